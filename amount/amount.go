@@ -0,0 +1,343 @@
+// Package amount is an exact decimal amount of LINO, IDA or USD, kept as an
+// integer of the smallest unit the chain uses: coins, MiniIDA or MiniDollar.
+package amount
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// Unit is the currency of an amount and its number of decimals.
+type Unit struct {
+	Name     string
+	Decimals int
+}
+
+// Units of the chain.
+var (
+	// LINO amounts are kept in coins, 1 LINO = 10^5 coins.
+	LINO = Unit{Name: "LINO", Decimals: 5}
+	// IDA amounts are kept in MiniIDA, 1 IDA = 10^5 MiniIDA.
+	IDA = Unit{Name: "IDA", Decimals: 5}
+	// USD amounts are kept in MiniDollar, 1 USD = 10^10 MiniDollar.
+	USD = Unit{Name: "USD", Decimals: 10}
+)
+
+var units = []Unit{LINO, IDA, USD}
+
+// Amount is an exact decimal amount of a unit. The zero value is zero of no
+// unit, e.g. of an unset field; arithmetic and comparisons between different
+// units panic.
+type Amount struct {
+	units sdk.Int
+	unit  Unit
+}
+
+// Parse parses a plain decimal, e.g. "1000.5" or "-0.1", with at most as
+// many decimals as @p unit. Thousands separators, exponents, a leading '+'
+// and surrounding spaces are rejected.
+func Parse(s string, unit Unit) (Amount, error) {
+	digits := strings.TrimPrefix(s, "-")
+	intPart, fracPart := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		intPart, fracPart = digits[:i], digits[i+1:]
+	}
+	if intPart == "" || !isDigits(intPart) || !isDigits(fracPart) || strings.HasSuffix(digits, ".") {
+		return Amount{}, errors.InvalidArgf("invalid %s amount %q", unit.Name, s)
+	}
+	if len(fracPart) > unit.Decimals {
+		return Amount{}, errors.InvalidArgf("%s amount %q has more than %v decimals", unit.Name, s, unit.Decimals)
+	}
+
+	v, ok := new(big.Int).SetString(intPart+fracPart+strings.Repeat("0", unit.Decimals-len(fracPart)), 10)
+	if !ok {
+		return Amount{}, errors.InvalidArgf("invalid %s amount %q", unit.Name, s)
+	}
+	if strings.HasPrefix(s, "-") {
+		v.Neg(v)
+	}
+	return Amount{units: sdk.NewIntFromBigInt(v), unit: unit}, nil
+}
+
+// ParseLNO parses a LINO amount.
+func ParseLNO(lno linotypes.LNO) (Amount, error) {
+	return Parse(lno, LINO)
+}
+
+// ParseIDA parses an IDA amount.
+func ParseIDA(ida linotypes.IDAStr) (Amount, error) {
+	return Parse(string(ida), IDA)
+}
+
+// ParseUSD parses a USD amount.
+func ParseUSD(usd string) (Amount, error) {
+	return Parse(usd, USD)
+}
+
+// MustParse is Parse panicking on error, for constants.
+func MustParse(s string, unit Unit) Amount {
+	a, err := Parse(s, unit)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// NewFromUnits returns the amount of @p units smallest units of @p unit.
+func NewFromUnits(units sdk.Int, unit Unit) Amount {
+	return Amount{units: units, unit: unit}
+}
+
+// NewFromCoin returns the LINO amount of @p coin.
+func NewFromCoin(coin linotypes.Coin) Amount {
+	return Amount{units: coin.Amount, unit: LINO}
+}
+
+// NewFromMiniIDA returns the IDA amount of @p miniIDA.
+func NewFromMiniIDA(miniIDA linotypes.MiniIDA) Amount {
+	return Amount{units: miniIDA, unit: IDA}
+}
+
+// NewFromMiniDollar returns the USD amount of @p miniDollar.
+func NewFromMiniDollar(miniDollar linotypes.MiniDollar) Amount {
+	return Amount{units: miniDollar.Int, unit: USD}
+}
+
+// Zero returns a zero amount of @p unit.
+func Zero(unit Unit) Amount {
+	return Amount{units: sdk.ZeroInt(), unit: unit}
+}
+
+// Unit returns the unit of the amount.
+func (a Amount) Unit() Unit {
+	return a.unit
+}
+
+// Units returns the amount in smallest units.
+func (a Amount) Units() sdk.Int {
+	return a.int()
+}
+
+// Coin returns a LINO amount in coins.
+func (a Amount) Coin() (linotypes.Coin, error) {
+	if a.unit != LINO {
+		return linotypes.NewCoinFromInt64(0), errors.InvalidArgf("%s amount is not LINO", a.unit.Name)
+	}
+	return linotypes.NewCoin(a.units), nil
+}
+
+// MiniIDA returns an IDA amount in MiniIDA.
+func (a Amount) MiniIDA() (linotypes.MiniIDA, error) {
+	if a.unit != IDA {
+		return sdk.ZeroInt(), errors.InvalidArgf("%s amount is not IDA", a.unit.Name)
+	}
+	return a.units, nil
+}
+
+// MiniDollar returns a USD amount in MiniDollar.
+func (a Amount) MiniDollar() (linotypes.MiniDollar, error) {
+	if a.unit != USD {
+		return linotypes.NewMiniDollar(0), errors.InvalidArgf("%s amount is not USD", a.unit.Name)
+	}
+	return linotypes.NewMiniDollarFromInt(a.units), nil
+}
+
+// ToUSD returns the USD value of a LINO amount at @p price, the MiniDollar
+// price of one coin as returned by GetCurrentPrice.
+func (a Amount) ToUSD(price linotypes.MiniDollar) (Amount, error) {
+	if a.unit != LINO {
+		return Amount{}, errors.InvalidArgf("%s amount is not LINO", a.unit.Name)
+	}
+	return Amount{units: a.units.Mul(price.Int), unit: USD}, nil
+}
+
+// ToLINO returns the LINO worth a USD amount at @p price, rounded down to a coin.
+func (a Amount) ToLINO(price linotypes.MiniDollar) (Amount, error) {
+	if a.unit != USD {
+		return Amount{}, errors.InvalidArgf("%s amount is not USD", a.unit.Name)
+	}
+	if !price.IsPositive() {
+		return Amount{}, errors.InvalidArgf("invalid price %v", price)
+	}
+	return Amount{units: a.units.Quo(price.Int), unit: LINO}, nil
+}
+
+// Add returns a + b.
+func (a Amount) Add(b Amount) Amount {
+	a.mustMatch(b)
+	return Amount{units: a.int().Add(b.int()), unit: a.unit}
+}
+
+// Sub returns a - b.
+func (a Amount) Sub(b Amount) Amount {
+	a.mustMatch(b)
+	return Amount{units: a.int().Sub(b.int()), unit: a.unit}
+}
+
+// Neg returns -a.
+func (a Amount) Neg() Amount {
+	return Amount{units: a.int().Neg(), unit: a.unit}
+}
+
+// MulInt64 returns a * n.
+func (a Amount) MulInt64(n int64) Amount {
+	return Amount{units: a.int().MulRaw(n), unit: a.unit}
+}
+
+// QuoInt64 returns a / n, rounded towards zero to the smallest unit.
+func (a Amount) QuoInt64(n int64) Amount {
+	return Amount{units: a.int().QuoRaw(n), unit: a.unit}
+}
+
+// Cmp returns -1, 0 or 1 if a is less than, equal to or greater than b.
+func (a Amount) Cmp(b Amount) int {
+	a.mustMatch(b)
+	return a.int().BigInt().Cmp(b.int().BigInt())
+}
+
+// Equal reports whether a == b.
+func (a Amount) Equal(b Amount) bool {
+	return a.Cmp(b) == 0
+}
+
+// LT reports whether a < b.
+func (a Amount) LT(b Amount) bool {
+	return a.Cmp(b) < 0
+}
+
+// GT reports whether a > b.
+func (a Amount) GT(b Amount) bool {
+	return a.Cmp(b) > 0
+}
+
+// IsZero reports whether the amount is zero.
+func (a Amount) IsZero() bool {
+	return a.int().IsZero()
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (a Amount) IsPositive() bool {
+	return a.int().IsPositive()
+}
+
+// IsNegative reports whether the amount is less than zero.
+func (a Amount) IsNegative() bool {
+	return a.int().IsNegative()
+}
+
+// String formats the amount as a plain decimal without trailing zeros, as
+// the chain parses LNO and IDA strings, e.g. "1000.5".
+func (a Amount) String() string {
+	intPart, fracPart, sign := a.parts()
+	if fracPart == "" {
+		return sign + intPart
+	}
+	return sign + intPart + "." + fracPart
+}
+
+// MarshalJSON marshals the amount as its String followed by its unit, e.g.
+// "1000.5 LINO", and the zero value as null.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if a == (Amount{}) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(a.String() + " " + a.unit.Name)
+}
+
+// UnmarshalJSON parses a decimal string followed by its unit, as marshaled
+// by MarshalJSON. A decimal without unit is parsed in the unit of the
+// amount, which must then be set beforehand, e.g. with Zero. null leaves
+// the amount unchanged.
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.UnmarshaFailed("amount must be a decimal string").AddCause(err)
+	}
+
+	decimal, unit := s, a.unit
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		decimal, unit = s[:i], Unit{}
+		for _, u := range units {
+			if u.Name == s[i+1:] {
+				unit = u
+			}
+		}
+		if unit == (Unit{}) {
+			return errors.InvalidArgf("amount %q has an unknown unit", s)
+		}
+		if a.unit != (Unit{}) && a.unit != unit {
+			return errors.InvalidArgf("amount %q is not %s", s, a.unit.Name)
+		}
+	}
+	if unit == (Unit{}) {
+		return errors.InvalidArgf("amount %q has no unit", s)
+	}
+	parsed, err := Parse(decimal, unit)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Display formats the amount with thousands separators and its unit, e.g.
+// "1,000.5 LINO".
+func (a Amount) Display() string {
+	intPart, fracPart, sign := a.parts()
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	if fracPart != "" {
+		b.WriteString("." + fracPart)
+	}
+	b.WriteString(" " + a.unit.Name)
+	return b.String()
+}
+
+func (a Amount) parts() (intPart, fracPart, sign string) {
+	digits := a.int().String()
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	decimals := a.unit.Decimals
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0"), sign
+}
+
+// int returns the units of the amount, zero for the zero value.
+func (a Amount) int() sdk.Int {
+	if a.units == (sdk.Int{}) {
+		return sdk.ZeroInt()
+	}
+	return a.units
+}
+
+func (a Amount) mustMatch(b Amount) {
+	if a.unit != b.unit {
+		panic("amount unit mismatch: " + a.unit.Name + " and " + b.unit.Name)
+	}
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
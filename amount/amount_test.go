@@ -0,0 +1,180 @@
+package amount
+
+import (
+	"encoding/json"
+	"testing"
+
+	linotypes "github.com/lino-network/lino/types"
+)
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		s           string
+		unit        Unit
+		expectUnits string
+		expectErr   bool
+	}{
+		"whole lino":          {s: "1000", unit: LINO, expectUnits: "100000000"},
+		"fractional lino":     {s: "0.00001", unit: LINO, expectUnits: "1"},
+		"negative lino":       {s: "-12.5", unit: LINO, expectUnits: "-1250000"},
+		"minidollar":          {s: "0.0000000001", unit: USD, expectUnits: "1"},
+		"ida":                 {s: "3.14159", unit: IDA, expectUnits: "314159"},
+		"too many decimals":   {s: "0.000001", unit: LINO, expectErr: true},
+		"thousands separator": {s: "1,000", unit: LINO, expectErr: true},
+		"exponent":            {s: "1e3", unit: LINO, expectErr: true},
+		"plus sign":           {s: "+1", unit: LINO, expectErr: true},
+		"spaces":              {s: " 1", unit: LINO, expectErr: true},
+		"missing int part":    {s: ".5", unit: LINO, expectErr: true},
+		"trailing dot":        {s: "1.", unit: LINO, expectErr: true},
+		"double minus":        {s: "--1", unit: LINO, expectErr: true},
+		"empty":               {s: "", unit: LINO, expectErr: true},
+	}
+
+	for testName, tc := range testCases {
+		a, err := Parse(tc.s, tc.unit)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err == nil && a.Units().String() != tc.expectUnits {
+			t.Errorf("%s: diff units, got %v, want %v", testName, a.Units(), tc.expectUnits)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testCases := map[string]struct {
+		a             Amount
+		expectString  string
+		expectDisplay string
+	}{
+		"zero":          {a: Zero(LINO), expectString: "0", expectDisplay: "0 LINO"},
+		"one coin":      {a: NewFromCoin(linotypes.NewCoinFromInt64(1)), expectString: "0.00001", expectDisplay: "0.00001 LINO"},
+		"thousands":     {a: MustParse("1234567.5", LINO), expectString: "1234567.5", expectDisplay: "1,234,567.5 LINO"},
+		"hundreds":      {a: MustParse("999", IDA), expectString: "999", expectDisplay: "999 IDA"},
+		"negative usd":  {a: MustParse("-1000.25", USD), expectString: "-1000.25", expectDisplay: "-1,000.25 USD"},
+		"trailing zero": {a: MustParse("10.50000", LINO), expectString: "10.5", expectDisplay: "10.5 LINO"},
+	}
+
+	for testName, tc := range testCases {
+		if s := tc.a.String(); s != tc.expectString {
+			t.Errorf("%s: diff string, got %v, want %v", testName, s, tc.expectString)
+		}
+		if s := tc.a.Display(); s != tc.expectDisplay {
+			t.Errorf("%s: diff display, got %v, want %v", testName, s, tc.expectDisplay)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	testCases := map[string]struct {
+		into        Amount
+		json        string
+		expectUnits string
+		expectUnit  Unit
+		expectJSON  string
+		expectErr   bool
+	}{
+		"lino":             {into: Amount{}, json: `"1000.5 LINO"`, expectUnits: "100050000", expectUnit: LINO},
+		"usd":              {into: Amount{}, json: `"-0.01 USD"`, expectUnits: "-100000000", expectUnit: USD},
+		"matching unit":    {into: Zero(IDA), json: `"2 IDA"`, expectUnits: "200000", expectUnit: IDA},
+		"preset unit":      {into: Zero(LINO), json: `"1000.5"`, expectUnits: "100050000", expectUnit: LINO, expectJSON: `"1000.5 LINO"`},
+		"null":             {into: Amount{}, json: `null`, expectUnits: "0", expectUnit: Unit{}},
+		"no unit":          {into: Amount{}, json: `"1"`, expectErr: true},
+		"unknown unit":     {into: Amount{}, json: `"1 BTC"`, expectErr: true},
+		"mismatching unit": {into: Zero(LINO), json: `"1 IDA"`, expectErr: true},
+		"invalid amount":   {into: Zero(IDA), json: `"1,000"`, expectErr: true},
+		"number":           {into: Zero(LINO), json: `1`, expectErr: true},
+	}
+
+	for testName, tc := range testCases {
+		a := tc.into
+		err := json.Unmarshal([]byte(tc.json), &a)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if a.Units().String() != tc.expectUnits || a.Unit() != tc.expectUnit {
+			t.Errorf("%s: diff amount, got %v %v, want %v %v", testName, a.Units(), a.Unit(), tc.expectUnits, tc.expectUnit)
+		}
+		expectJSON := tc.expectJSON
+		if expectJSON == "" {
+			expectJSON = tc.json
+		}
+		if b, err := json.Marshal(a); err != nil || string(b) != expectJSON {
+			t.Errorf("%s: diff json, got %s %v, want %s", testName, b, err, expectJSON)
+		}
+	}
+
+	// the zero value, e.g. of an unset field, is zero and round trips.
+	var zero Amount
+	if !zero.IsZero() || zero.IsPositive() || !zero.Units().IsZero() {
+		t.Errorf("zero value should be zero, got %v", zero.Units())
+	}
+	fields := struct {
+		Set   Amount `json:"set"`
+		Unset Amount `json:"unset"`
+	}{Set: MustParse("1", IDA)}
+	b, err := json.Marshal(fields)
+	if err != nil || string(b) != `{"set":"1 IDA","unset":null}` {
+		t.Errorf("diff json of fields, got %s %v", b, err)
+	}
+	parsed := fields
+	parsed.Set = Amount{}
+	err = json.Unmarshal(b, &parsed)
+	if err != nil || parsed.Set.Unit() != IDA || !parsed.Set.Equal(fields.Set) || parsed.Unset != (Amount{}) {
+		t.Errorf("diff fields after round trip, got %+v %v, want %+v", parsed, err, fields)
+	}
+}
+
+func TestConversions(t *testing.T) {
+	lino := MustParse("1000.5", LINO)
+	coin, err := lino.Coin()
+	if err != nil || !coin.IsEqual(linotypes.MustLinoToCoin("1000.5")) {
+		t.Errorf("diff coin, got %v %v", coin, err)
+	}
+	if _, err := lino.MiniIDA(); err == nil {
+		t.Errorf("lino amount should not convert to mini ida")
+	}
+
+	price := linotypes.NewMiniDollar(1200)
+	usd, err := lino.ToUSD(price)
+	if err != nil || usd.String() != "12.006" {
+		t.Errorf("diff usd, got %v %v, want 12.006", usd, err)
+	}
+	back, err := usd.ToLINO(price)
+	if err != nil || !back.Equal(lino) {
+		t.Errorf("diff lino, got %v %v, want %v", back, err, lino)
+	}
+	if _, err := lino.ToLINO(price); err == nil {
+		t.Errorf("lino amount should not convert from usd")
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a := MustParse("1.5", LINO)
+	b := MustParse("0.25", LINO)
+
+	if s := a.Add(b).String(); s != "1.75" {
+		t.Errorf("diff sum, got %v", s)
+	}
+	if s := b.Sub(a).String(); s != "-1.25" {
+		t.Errorf("diff difference, got %v", s)
+	}
+	if s := a.MulInt64(3).QuoInt64(2).String(); s != "2.25" {
+		t.Errorf("diff product, got %v", s)
+	}
+	if !b.LT(a) || !a.GT(b) || a.Equal(b) || a.Cmp(a) != 0 {
+		t.Errorf("diff comparisons of %v and %v", a, b)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("adding different units should panic")
+		}
+	}()
+	a.Add(MustParse("1", IDA))
+}
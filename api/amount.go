@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// The functions below are the variants of the functions taking amounts as
+// strings, taking amount.Amount instead. Amounts must be positive and of the
+// unit the msg expects.
+
+// RegisterV2Amount is RegisterV2 with a LINO register fee.
+func (api *API) RegisterV2Amount(ctx context.Context, referrer linotypes.AccOrAddr, registerFee amount.Amount,
+	username, newTxAddr, txPubKeyHex, signingPubKeyHex, referrerPrivKeyHex,
+	txPrivKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	fee, err := amountArg(registerFee, amount.LINO)
+	if err != nil {
+		return nil, err
+	}
+	return api.RegisterV2(ctx, referrer, fee, username, newTxAddr, txPubKeyHex,
+		signingPubKeyHex, referrerPrivKeyHex, txPrivKeyHex)
+}
+
+// TransferAmount is Transfer with a LINO amount.
+func (api *API) TransferAmount(
+	ctx context.Context, sender, receiver string, amt amount.Amount,
+	memo, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	lno, err := amountArg(amt, amount.LINO)
+	if err != nil {
+		return nil, err
+	}
+	return api.Transfer(ctx, sender, receiver, lno, memo, privKeyHex)
+}
+
+// TransferV2Amount is TransferV2 with a LINO amount.
+func (api *API) TransferV2Amount(
+	ctx context.Context, sender, receiver linotypes.AccOrAddr, amt amount.Amount,
+	memo, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	lno, err := amountArg(amt, amount.LINO)
+	if err != nil {
+		return nil, err
+	}
+	return api.TransferV2(ctx, sender, receiver, lno, memo, privKeyHex)
+}
+
+// DonateAmount is Donate with a LINO amount.
+func (api *API) DonateAmount(ctx context.Context, username, author string, amt amount.Amount,
+	postID, fromApp, memo string, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	lno, err := amountArg(amt, amount.LINO)
+	if err != nil {
+		return nil, err
+	}
+	return api.Donate(ctx, username, author, lno, postID, fromApp, memo, privKeyHex)
+}
+
+// StakeInAmount is StakeIn with a LINO deposit.
+func (api *API) StakeInAmount(
+	ctx context.Context, username string, deposit amount.Amount, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	lno, err := amountArg(deposit, amount.LINO)
+	if err != nil {
+		return nil, err
+	}
+	return api.StakeIn(ctx, username, lno, privKeyHex)
+}
+
+// StakeInForAmount is StakeInFor with a LINO deposit.
+func (api *API) StakeInForAmount(
+	ctx context.Context, sender, receiver string, deposit amount.Amount,
+	privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	lno, err := amountArg(deposit, amount.LINO)
+	if err != nil {
+		return nil, err
+	}
+	return api.StakeInFor(ctx, sender, receiver, lno, privKeyHex)
+}
+
+// StakeOutAmount is StakeOut with a LINO amount.
+func (api *API) StakeOutAmount(
+	ctx context.Context, username string, amt amount.Amount, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	lno, err := amountArg(amt, amount.LINO)
+	if err != nil {
+		return nil, err
+	}
+	return api.StakeOut(ctx, username, lno, privKeyHex)
+}
+
+// IDAMintAmount is IDAMint with the LINO amount spent on minting.
+func (api *API) IDAMintAmount(
+	ctx context.Context, username string, amt amount.Amount, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	lno, err := amountArg(amt, amount.LINO)
+	if err != nil {
+		return nil, err
+	}
+	return api.IDAMint(ctx, username, lno, privKeyHex)
+}
+
+// IDATransferAmount is IDATransfer with an IDA amount.
+func (api *API) IDATransferAmount(
+	ctx context.Context, app string, amt amount.Amount, from, to, signer, memo string,
+	privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	ida, err := amountArg(amt, amount.IDA)
+	if err != nil {
+		return nil, err
+	}
+	return api.IDATransfer(ctx, app, ida, from, to, signer, memo, privKeyHex)
+}
+
+// IDADonateAmount is IDADonate with an IDA amount.
+func (api *API) IDADonateAmount(
+	ctx context.Context, username, author, app string, amt amount.Amount, postID, signer, memo string,
+	privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	ida, err := amountArg(amt, amount.IDA)
+	if err != nil {
+		return nil, err
+	}
+	return api.IDADonate(ctx, username, author, app, ida, postID, signer, memo, privKeyHex)
+}
+
+// amountArg returns @p amt as the string a msg expects.
+func amountArg(amt amount.Amount, unit amount.Unit) (string, errors.Error) {
+	if amt.Unit() != unit {
+		return "", errors.InvalidArgf("expect %s amount, got %s", unit.Name, amt.Unit().Name)
+	}
+	if !amt.IsPositive() {
+		return "", errors.InvalidArgf("%s amount %s is not positive", unit.Name, amt)
+	}
+	return amt.String(), nil
+}
@@ -36,6 +36,13 @@ type API struct {
 	*broadcast.Broadcast
 	checkTxConfirmInterval time.Duration
 	timeout                time.Duration
+	admission              AdmissionControl
+}
+
+// AdmissionControl decides whether a transaction of signers can be broadcast
+// now. bandwidth.Gate implements it.
+type AdmissionControl interface {
+	Admit(ctx context.Context, signers []linotypes.AccOrAddr) errors.Error
 }
 
 // Options is a wrapper of init parameters
@@ -109,6 +116,15 @@ func NewLinoAPIFromArgs(opt *Options) *API {
 	}
 }
 
+// SetAdmissionControl makes GuaranteeBroadcast consult @p admission before
+// broadcasting. Nil removes it. Every tx of API, e.g. Transfer, Donate,
+// IDADonate and their Amount variants, is broadcast with GuaranteeBroadcast
+// and so is gated, once per call, before its first attempt. Tx bytes sent
+// with BroadcastRawMsgBytesSync or BroadcastToMempool are not.
+func (api *API) SetAdmissionControl(admission AdmissionControl) {
+	api.admission = admission
+}
+
 // MsgBuilderFunc is usually a closure that return messages bytes for a specific sequence.
 type MsgBuilderFunc func(seqs []uint64) ([]byte, errors.Error)
 
@@ -258,6 +274,8 @@ func (api *API) DeletePost(ctx context.Context, author,
 
 // UpdatePost updates post info with new data.
 // It composes UpdatePostMsg and then broadcasts the transaction to blockchain.
+// UpdatePostMsg has no links, so @p links are added to the envelope of the
+// content, see MakeUpdatePostMsg.
 func (api *API) UpdatePost(
 	ctx context.Context, author, title, postID, content string, links map[string]string,
 	privKeyHex string) (*model.BroadcastResponse, errors.Error) {
@@ -381,6 +399,13 @@ func (api *API) DeveloperRevoke(
 	return resp, err
 }
 
+// Permission grants are gone from lino v0.6.x: GrantPermissionMsg and
+// RevokePermissionMsg are no longer registered and the chain keeps no grant
+// records, so the functions below cannot be restored. Apps now sign for their
+// users with AppOrAffiliatedPermission: through affiliated accounts managed by
+// UpdateAffiliated, and for IDA through the authorization flag set by IDAAuthorize.
+// GetAppPermission reports both for a user and an app.
+
 // GrantPermission grants a certain (e.g. App) permission to
 // an authorized app with a certain period of time.
 // It composes GrantPermissionMsg and then broadcasts the transaction to blockchain.
@@ -636,6 +661,12 @@ func (api *API) GuaranteeBroadcast(ctx context.Context,
 	hashHistory := make([]string, 0)
 	var lastHash *string // init: nil
 
+	if api.admission != nil {
+		if err := api.admission.Admit(ctx, signers); err != nil {
+			return nil, hashHistory, err
+		}
+	}
+
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
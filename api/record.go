@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+
+	"github.com/lino-network/lino-go/broadcast"
+	"github.com/lino-network/lino-go/errors"
+	accmodel "github.com/lino-network/lino/x/account/model"
+)
+
+// TxSource finds txs of a signer with its sequence number. *API implements it.
+type TxSource interface {
+	GetTxAndSequenceNumberByUsername(ctx context.Context, username, hash string) (*accmodel.TxAndSequenceNumber, error)
+}
+
+// TxRecord is the txs built for a GuaranteeBroadcast, so that a broadcast
+// whose result is unknown, e.g. after a crash or a timeout, can be settled
+// later with SettleTxs.
+type TxRecord struct {
+	// Seq is the sequence number of the first signer of the last tx built,
+	// and Hashes the hashes of all of them.
+	Seq    uint64
+	Hashes []string
+}
+
+// Recorder returns a MsgBuilderFunc building txs with @p f which, before a
+// new tx may be broadcast, adds it to the record and calls @p save, e.g. to
+// journal the record. A failed save fails the broadcast.
+func (record *TxRecord) Recorder(f MsgBuilderFunc, save func() error) MsgBuilderFunc {
+	return func(seqs []uint64) ([]byte, errors.Error) {
+		txBytes, err := f(seqs)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := broadcast.CalcTxMsgHashHexString(txBytes)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(record.Hashes); n == 0 || record.Hashes[n-1] != hash {
+			record.Seq = seqs[0]
+			record.Hashes = append(record.Hashes, hash)
+			if err := save(); err != nil {
+				return nil, errors.GuaranteeBroadcastFail("failed to save tx record").AddCause(err)
+			}
+		}
+		return txBytes, nil
+	}
+}
+
+// SettleTxs looks up the txs of @p record signed by @p signer. It returns
+// the tx that committed successfully, if any, and otherwise whether one of
+// them may still commit: a tx not found may still commit until its sequence
+// number is used.
+func SettleTxs(ctx context.Context, source TxSource, signer string, record TxRecord) (*accmodel.Transaction, bool, error) {
+	open := false
+	var seq uint64
+	for _, hash := range record.Hashes {
+		txSeq, err := source.GetTxAndSequenceNumberByUsername(ctx, signer, hash)
+		if err != nil {
+			return nil, false, err
+		}
+		if txSeq.Tx == nil {
+			open, seq = true, txSeq.Sequence
+			continue
+		}
+		if txSeq.Tx.Code == 0 {
+			return txSeq.Tx, false, nil
+		}
+	}
+	return nil, open && seq <= record.Seq, nil
+}
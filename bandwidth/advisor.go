@@ -0,0 +1,213 @@
+// Package bandwidth interprets the bandwidth state of the chain: how many
+// messages an app can still sign, what a message costs at the current load
+// and when the credit of an app refills.
+//
+// Messages signed by an app or its affiliated accounts take bandwidth credit
+// of the app, refilled at its expected messages per second up to a cap set
+// by its stake. Messages of other users pay a fee in LINO instead. Both the
+// credit a message takes and the fee grow exponentially once the recent
+// message rate exceeds its quota.
+package bandwidth
+
+import (
+	"context"
+	"math"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino/param"
+	linotypes "github.com/lino-network/lino/types"
+	"github.com/lino-network/lino/x/bandwidth/model"
+)
+
+// Never is the wait of a credit that does not refill.
+const Never = time.Duration(math.MaxInt64)
+
+// Source provides the bandwidth state and the affiliated accounts of apps.
+// *query.Query implements it.
+type Source interface {
+	GetBandwidthInfo(ctx context.Context) (*model.BandwidthInfo, error)
+	GetBlockInfo(ctx context.Context) (*model.BlockInfo, error)
+	GetAppBandwidthInfo(ctx context.Context, username string) (*model.AppBandwidthInfo, error)
+	GetBandwidthParam(ctx context.Context) (*param.BandwidthParam, error)
+	GetAffiliated(ctx context.Context, developerName string) ([]string, error)
+}
+
+// Load is the message rate of the chain and what a message costs at it.
+type Load struct {
+	// GeneralMsgEMA and AppMsgEMA are the moving averages of messages per
+	// second signed by users and by apps.
+	GeneralMsgEMA sdk.Dec `json:"general_msg_ema"`
+	AppMsgEMA     sdk.Dec `json:"app_msg_ema"`
+	// GeneralMsgQuota and AppMsgQuota are the rates above which the fee and
+	// the cost per message grow.
+	GeneralMsgQuota sdk.Dec `json:"general_msg_quota"`
+	AppMsgQuota     sdk.Dec `json:"app_msg_quota"`
+	// MsgFee is the fee of a user message and CostPerMsg the credit of an
+	// app message in the last block.
+	MsgFee     linotypes.Coin `json:"msg_fee"`
+	CostPerMsg sdk.Dec        `json:"cost_per_msg"`
+	// ExpectedMsgFee and ExpectedCostPerMsg are those of the next block,
+	// computed from the current averages.
+	ExpectedMsgFee     linotypes.Coin `json:"expected_msg_fee"`
+	ExpectedCostPerMsg sdk.Dec        `json:"expected_cost_per_msg"`
+}
+
+// NewLoad returns the load of @p info and @p block under @p bandwidthParam.
+func NewLoad(info *model.BandwidthInfo, block *model.BlockInfo, bandwidthParam *param.BandwidthParam) (*Load, error) {
+	maxMPS := bandwidthParam.ExpectedMaxMPS
+	if info.MaxMPS.GT(maxMPS) {
+		maxMPS = info.MaxMPS
+	}
+	generalQuota := bandwidthParam.GeneralMsgQuotaRatio.Mul(maxMPS)
+	appQuota := bandwidthParam.AppMsgQuotaRatio.Mul(maxMPS)
+	if !generalQuota.IsPositive() || !appQuota.IsPositive() {
+		return nil, errors.InvalidArgf("invalid msg quota %v, %v", generalQuota, appQuota)
+	}
+
+	fee := approximateExp(info.GeneralMsgEMA.Sub(generalQuota).Quo(generalQuota).Mul(bandwidthParam.MsgFeeFactorA)).
+		Mul(bandwidthParam.MsgFeeFactorB)
+	return &Load{
+		GeneralMsgEMA:      info.GeneralMsgEMA,
+		AppMsgEMA:          info.AppMsgEMA,
+		GeneralMsgQuota:    generalQuota,
+		AppMsgQuota:        appQuota,
+		MsgFee:             block.CurMsgFee,
+		CostPerMsg:         block.CurU,
+		ExpectedMsgFee:     linotypes.NewCoinFromInt64(fee.MulInt64(linotypes.Decimals).RoundInt64()),
+		ExpectedCostPerMsg: approximateExp(info.AppMsgEMA.Sub(appQuota).Quo(appQuota).Mul(bandwidthParam.AppVacancyFactor)),
+	}, nil
+}
+
+// Capacity is the bandwidth credit of an app at a time.
+type Capacity struct {
+	App string    `json:"app"`
+	At  time.Time `json:"at"`
+	// Credit is the credit at At, refilled since the last message, and
+	// MaxCredit the cap of refills.
+	Credit    sdk.Dec `json:"credit"`
+	MaxCredit sdk.Dec `json:"max_credit"`
+	// RefillRate is the credit refilled per second.
+	RefillRate sdk.Dec `json:"refill_rate"`
+	// CostPerMsg is the credit a message takes.
+	CostPerMsg sdk.Dec `json:"cost_per_msg"`
+	// Remaining is the number of messages the credit admits at At.
+	Remaining int64 `json:"remaining"`
+	// NextIn is the wait until a message is admitted, zero if Remaining is
+	// positive, and FullIn the wait until the credit is full. Both are Never
+	// if the credit does not refill.
+	NextIn time.Duration `json:"next_in"`
+	FullIn time.Duration `json:"full_in"`
+}
+
+// NewCapacity returns the capacity of @p info at @p now when a message takes
+// @p costPerMsg credit. The chain admits a message if the credit covers its
+// cost, and charges apps signing more than their expected rate in a block
+// extra credit when the block ends, which is not included.
+func NewCapacity(info *model.AppBandwidthInfo, costPerMsg sdk.Dec, now time.Time) *Capacity {
+	credit := Refill(info, now.Unix())
+	capacity := &Capacity{
+		App:        string(info.Username),
+		At:         now,
+		Credit:     credit,
+		MaxCredit:  info.MaxBandwidthCredit,
+		RefillRate: info.ExpectedMPS,
+		CostPerMsg: costPerMsg,
+		FullIn:     refillIn(info.MaxBandwidthCredit.Sub(credit), info.ExpectedMPS),
+	}
+	if costPerMsg.IsPositive() && credit.GTE(costPerMsg) {
+		capacity.Remaining = credit.Quo(costPerMsg).TruncateInt64()
+	}
+	if capacity.Remaining == 0 {
+		capacity.NextIn = refillIn(costPerMsg.Sub(credit), info.ExpectedMPS)
+	}
+	return capacity
+}
+
+// Refill returns the credit of @p info at unix time @p now, as the chain
+// refills it before checking a message.
+func Refill(info *model.AppBandwidthInfo, now int64) sdk.Dec {
+	if now <= info.LastRefilledAt || info.CurBandwidthCredit.GTE(info.MaxBandwidthCredit) {
+		return info.CurBandwidthCredit
+	}
+	credit := info.ExpectedMPS.MulInt64(now - info.LastRefilledAt).Add(info.CurBandwidthCredit)
+	if credit.GT(info.MaxBandwidthCredit) {
+		return info.MaxBandwidthCredit
+	}
+	return credit
+}
+
+// refillIn returns the wait until @p missing credit is refilled at @p rate.
+func refillIn(missing, rate sdk.Dec) time.Duration {
+	if !missing.IsPositive() {
+		return 0
+	}
+	if !rate.IsPositive() {
+		return Never
+	}
+	return time.Duration(missing.Quo(rate).Ceil().TruncateInt64()) * time.Second
+}
+
+// Advisor answers bandwidth questions against the chain.
+type Advisor struct {
+	source Source
+	now    func() time.Time
+}
+
+// NewAdvisor returns an instance of Advisor reading from @p source.
+func NewAdvisor(source Source) *Advisor {
+	return &Advisor{source: source, now: time.Now}
+}
+
+// Load returns the current load of the chain.
+func (a *Advisor) Load(ctx context.Context) (*Load, error) {
+	info, err := a.source.GetBandwidthInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	block, err := a.source.GetBlockInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bandwidthParam, err := a.source.GetBandwidthParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewLoad(info, block, bandwidthParam)
+}
+
+// Capacity returns the capacity of @p app now. A message is costed at the
+// higher of the last and the expected cost, as it may be checked in either
+// block.
+func (a *Advisor) Capacity(ctx context.Context, app string) (*Capacity, error) {
+	capacity, _, err := a.capacity(ctx, app)
+	return capacity, err
+}
+
+// capacity returns the capacity of @p app now with the bandwidth info it is
+// computed from.
+func (a *Advisor) capacity(ctx context.Context, app string) (*Capacity, *model.AppBandwidthInfo, error) {
+	load, err := a.Load(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := a.source.GetAppBandwidthInfo(ctx, app)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewCapacity(info, sdk.MaxDec(load.CostPerMsg, load.ExpectedCostPerMsg), a.now()), info, nil
+}
+
+// approximateExp approximates e^x as (1 + |x|/1024)^1024 the way the chain
+// does, inverted for negative x.
+func approximateExp(x sdk.Dec) sdk.Dec {
+	y := sdk.OneDec().Add(x.Abs().Quo(sdk.NewDec(1024)))
+	for i := 0; i < 10; i++ {
+		y = y.Mul(y)
+	}
+	if x.IsNegative() {
+		return sdk.OneDec().Quo(y)
+	}
+	return y
+}
@@ -0,0 +1,286 @@
+package bandwidth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino/param"
+	linotypes "github.com/lino-network/lino/types"
+	"github.com/lino-network/lino/x/bandwidth/model"
+)
+
+type fakeSource struct {
+	info       *model.BandwidthInfo
+	block      *model.BlockInfo
+	apps       map[string]*model.AppBandwidthInfo
+	param      *param.BandwidthParam
+	affiliated map[string][]string
+}
+
+func (s *fakeSource) GetBandwidthInfo(ctx context.Context) (*model.BandwidthInfo, error) {
+	return s.info, nil
+}
+
+func (s *fakeSource) GetBlockInfo(ctx context.Context) (*model.BlockInfo, error) {
+	return s.block, nil
+}
+
+func (s *fakeSource) GetAppBandwidthInfo(ctx context.Context, username string) (*model.AppBandwidthInfo, error) {
+	info, ok := s.apps[username]
+	if !ok {
+		return nil, errors.EmptyResponse("app bandwidth info is not found")
+	}
+	return info, nil
+}
+
+func (s *fakeSource) GetBandwidthParam(ctx context.Context) (*param.BandwidthParam, error) {
+	return s.param, nil
+}
+
+func (s *fakeSource) GetAffiliated(ctx context.Context, developerName string) ([]string, error) {
+	return s.affiliated[developerName], nil
+}
+
+func dec(s string) sdk.Dec {
+	return sdk.MustNewDecFromStr(s)
+}
+
+// newSource returns a source whose load is at the quotas, so a message costs
+// one credit and the fee is MsgFeeFactorB, and whose app refills one credit
+// a second up to ten.
+func newSource(credit string) *fakeSource {
+	return &fakeSource{
+		info: &model.BandwidthInfo{GeneralMsgEMA: dec("3"), AppMsgEMA: dec("10"), MaxMPS: dec("0")},
+		block: &model.BlockInfo{
+			CurMsgFee: linotypes.NewCoinFromInt64(100000),
+			CurU:      dec("1"),
+		},
+		apps: map[string]*model.AppBandwidthInfo{
+			"app": {
+				Username:           "app",
+				MaxBandwidthCredit: dec("10"),
+				CurBandwidthCredit: dec(credit),
+				ExpectedMPS:        dec("1"),
+				LastRefilledAt:     1000,
+			},
+		},
+		param: &param.BandwidthParam{
+			GeneralMsgQuotaRatio: dec("0.3"),
+			AppMsgQuotaRatio:     dec("1"),
+			ExpectedMaxMPS:       dec("10"),
+			MsgFeeFactorA:        dec("6"),
+			MsgFeeFactorB:        dec("1"),
+			AppVacancyFactor:     dec("69.49"),
+		},
+		affiliated: map[string][]string{"app": {"user"}},
+	}
+}
+
+func TestNewLoad(t *testing.T) {
+	testCases := map[string]struct {
+		generalMsgEMA  string
+		appMsgEMA      string
+		expectFee      linotypes.Coin
+		expectCostMore bool
+	}{
+		"at quotas": {
+			generalMsgEMA: "3",
+			appMsgEMA:     "10",
+			expectFee:     linotypes.NewCoinFromInt64(100000),
+		},
+		"idle": {
+			generalMsgEMA: "0",
+			appMsgEMA:     "0",
+			expectFee:     linotypes.NewCoinFromInt64(252),
+		},
+		"busy apps": {
+			generalMsgEMA:  "3",
+			appMsgEMA:      "11",
+			expectFee:      linotypes.NewCoinFromInt64(100000),
+			expectCostMore: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		source := newSource("10")
+		source.info.GeneralMsgEMA = dec(tc.generalMsgEMA)
+		source.info.AppMsgEMA = dec(tc.appMsgEMA)
+		load, err := NewLoad(source.info, source.block, source.param)
+		if err != nil {
+			t.Errorf("%s: failed to get load, got err %v", testName, err)
+			continue
+		}
+		if !load.ExpectedMsgFee.IsEqual(tc.expectFee) {
+			t.Errorf("%s: diff fee, got %v, want %v", testName, load.ExpectedMsgFee, tc.expectFee)
+		}
+		if load.ExpectedCostPerMsg.GT(sdk.OneDec()) != tc.expectCostMore {
+			t.Errorf("%s: diff cost, got %v, want more than one %v", testName, load.ExpectedCostPerMsg, tc.expectCostMore)
+		}
+	}
+
+	source := newSource("10")
+	source.param.ExpectedMaxMPS = dec("0")
+	if _, err := NewLoad(source.info, source.block, source.param); err == nil {
+		t.Errorf("zero quota: expect err")
+	}
+}
+
+func TestNewCapacity(t *testing.T) {
+	testCases := map[string]struct {
+		credit          string
+		costPerMsg      string
+		now             int64
+		expectCredit    sdk.Dec
+		expectRemaining int64
+		expectNextIn    time.Duration
+		expectFullIn    time.Duration
+	}{
+		"full": {
+			credit:          "10",
+			costPerMsg:      "1",
+			now:             1005,
+			expectCredit:    dec("10"),
+			expectRemaining: 10,
+		},
+		"refilled": {
+			credit:          "2.5",
+			costPerMsg:      "1",
+			now:             1003,
+			expectCredit:    dec("5.5"),
+			expectRemaining: 5,
+			expectFullIn:    5 * time.Second,
+		},
+		"refill capped": {
+			credit:          "2.5",
+			costPerMsg:      "1",
+			now:             1100,
+			expectCredit:    dec("10"),
+			expectRemaining: 10,
+		},
+		"exhausted": {
+			credit:          "-1.5",
+			costPerMsg:      "2",
+			now:             1000,
+			expectCredit:    dec("-1.5"),
+			expectRemaining: 0,
+			expectNextIn:    4 * time.Second,
+			expectFullIn:    12 * time.Second,
+		},
+	}
+
+	for testName, tc := range testCases {
+		info := newSource(tc.credit).apps["app"]
+		capacity := NewCapacity(info, dec(tc.costPerMsg), time.Unix(tc.now, 0))
+		if !capacity.Credit.Equal(tc.expectCredit) {
+			t.Errorf("%s: diff credit, got %v, want %v", testName, capacity.Credit, tc.expectCredit)
+		}
+		if capacity.Remaining != tc.expectRemaining {
+			t.Errorf("%s: diff remaining, got %v, want %v", testName, capacity.Remaining, tc.expectRemaining)
+		}
+		if capacity.NextIn != tc.expectNextIn {
+			t.Errorf("%s: diff next in, got %v, want %v", testName, capacity.NextIn, tc.expectNextIn)
+		}
+		if capacity.FullIn != tc.expectFullIn {
+			t.Errorf("%s: diff full in, got %v, want %v", testName, capacity.FullIn, tc.expectFullIn)
+		}
+	}
+
+	info := newSource("0").apps["app"]
+	info.ExpectedMPS = dec("0")
+	if capacity := NewCapacity(info, dec("1"), time.Unix(1000, 0)); capacity.NextIn != Never || capacity.FullIn != Never {
+		t.Errorf("no refill: diff waits, got %v, %v", capacity.NextIn, capacity.FullIn)
+	}
+}
+
+func TestGate(t *testing.T) {
+	signers := func(username string) []linotypes.AccOrAddr {
+		return []linotypes.AccOrAddr{linotypes.NewAccOrAddrFromAcc(linotypes.AccountKey(username))}
+	}
+	testCases := map[string]struct {
+		credit       string
+		opts         GateOptions
+		signer       string
+		times        int
+		expectAdmits int
+	}{
+		"app within credit": {
+			credit:       "3",
+			opts:         GateOptions{Apps: []string{"app"}},
+			signer:       "app",
+			times:        5,
+			expectAdmits: 3,
+		},
+		"affiliated with reserve": {
+			credit:       "3",
+			opts:         GateOptions{Apps: []string{"app"}, Reserve: 1},
+			signer:       "user",
+			times:        5,
+			expectAdmits: 2,
+		},
+		"fee within max": {
+			credit:       "0",
+			opts:         GateOptions{Apps: []string{"app"}, MaxFee: linotypes.NewCoinFromInt64(100000)},
+			signer:       "other",
+			times:        5,
+			expectAdmits: 5,
+		},
+		"fee above max": {
+			credit:       "0",
+			opts:         GateOptions{MaxFee: linotypes.NewCoinFromInt64(99999)},
+			signer:       "app",
+			times:        5,
+			expectAdmits: 0,
+		},
+	}
+
+	for testName, tc := range testCases {
+		gate, err := NewGate(newSource(tc.credit), tc.opts)
+		if err != nil {
+			t.Errorf("%s: failed to create gate, got err %v", testName, err)
+			continue
+		}
+		gate.advisor.now = func() time.Time { return time.Unix(1000, 0) }
+		admits := 0
+		for i := 0; i < tc.times; i++ {
+			err := gate.Admit(context.Background(), signers(tc.signer))
+			if err == nil {
+				admits++
+			} else if err.CodeType() != errors.CodeBandwidthNotEnough {
+				t.Errorf("%s: diff err, got %v", testName, err)
+			}
+		}
+		if admits != tc.expectAdmits {
+			t.Errorf("%s: diff admits, got %v, want %v", testName, admits, tc.expectAdmits)
+		}
+	}
+}
+
+func TestGateResetsOnChainChange(t *testing.T) {
+	source := newSource("1")
+	gate, _ := NewGate(source, GateOptions{Apps: []string{"app"}, Wait: true, PollInterval: time.Millisecond})
+	gate.advisor.now = func() time.Time { return time.Unix(1000, 0) }
+	signers := []linotypes.AccOrAddr{linotypes.NewAccOrAddrFromAcc("app")}
+	if err := gate.Admit(context.Background(), signers); err != nil {
+		t.Errorf("first admit: got err %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := gate.Admit(ctx, signers); err == nil || err.CodeType() != errors.CodeTimeout {
+		t.Errorf("exhausted: expect timeout, got %v", err)
+	}
+
+	source.apps["app"] = &model.AppBandwidthInfo{
+		Username:           "app",
+		MaxBandwidthCredit: dec("10"),
+		CurBandwidthCredit: dec("1"),
+		ExpectedMPS:        dec("1"),
+		LastRefilledAt:     1001,
+	}
+	if err := gate.Admit(context.Background(), signers); err != nil {
+		t.Errorf("after commit: got err %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package bandwidth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lino-network/lino-go/errors"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// GateOptions configures a Gate.
+type GateOptions struct {
+	// Apps are the apps whose credit is checked for messages signed by them
+	// or their affiliated accounts.
+	Apps []string
+	// Reserve is the number of messages of credit the gate leaves to other
+	// senders of the apps.
+	Reserve int64
+	// MaxFee is the highest fee a message of another user may pay. Zero
+	// admits any fee.
+	MaxFee linotypes.Coin
+	// Wait makes Admit wait until the message is admitted instead of
+	// rejecting it.
+	Wait bool
+	// PollInterval is the time between checks while waiting. Defaults to
+	// three seconds.
+	PollInterval time.Duration
+	// RefreshInterval is the time the affiliated accounts of the apps are
+	// cached. Defaults to ten minutes.
+	RefreshInterval time.Duration
+}
+
+// Gate admits messages before they are broadcast, so that a sender paces
+// itself by the bandwidth of its app instead of having messages rejected by
+// the chain. It implements api.AdmissionControl and is safe for concurrent
+// use.
+//
+// Only the first signer of a transaction pays for bandwidth, so only it is
+// checked. Address signers are treated as users paying the message fee.
+type Gate struct {
+	advisor *Advisor
+	opts    GateOptions
+
+	mu          sync.Mutex
+	affiliating map[string]string
+	refreshedAt time.Time
+	apps        map[string]*appState
+}
+
+// appState counts the messages admitted for an app since its credit on
+// chain last changed, as they are not reflected before they are committed.
+type appState struct {
+	seen     string
+	admitted int64
+}
+
+// NewGate returns an instance of Gate reading from @p source.
+func NewGate(source Source, opts GateOptions) (*Gate, error) {
+	if opts.Reserve < 0 {
+		return nil, errors.InvalidArgf("invalid reserve %v", opts.Reserve)
+	}
+	if opts.MaxFee == (linotypes.Coin{}) {
+		opts.MaxFee = linotypes.NewCoinFromInt64(0)
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 3 * time.Second
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = 10 * time.Minute
+	}
+	return &Gate{advisor: NewAdvisor(source), opts: opts, apps: map[string]*appState{}}, nil
+}
+
+// Admit returns nil if a transaction of @p signers can be broadcast now. If
+// Wait is set it waits until then or until the context is done.
+func (g *Gate) Admit(ctx context.Context, signers []linotypes.AccOrAddr) errors.Error {
+	if len(signers) == 0 {
+		return nil
+	}
+	for {
+		wait, err := g.admit(ctx, signers[0])
+		if err != nil {
+			if linoe, ok := err.(errors.Error); ok {
+				return linoe
+			}
+			return errors.QueryFail("admission control failed").AddCause(err)
+		}
+		if wait == 0 {
+			return nil
+		}
+		if !g.opts.Wait {
+			return errors.BandwidthNotEnoughf("%v not admitted, retry in %v", signers[0], wait)
+		}
+		if wait > g.opts.PollInterval {
+			wait = g.opts.PollInterval
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Timeoutf("%v not admitted before the context is done", signers[0])
+		case <-time.After(wait):
+		}
+	}
+}
+
+// admit admits a message of @p signer and returns zero, or returns the wait
+// until it may be.
+func (g *Gate) admit(ctx context.Context, signer linotypes.AccOrAddr) (time.Duration, error) {
+	app := ""
+	if !signer.IsAddr {
+		var err error
+		app, err = g.affiliatingApp(ctx, string(signer.AccountKey))
+		if err != nil {
+			return 0, err
+		}
+	}
+	if app == "" {
+		return g.admitFee(ctx)
+	}
+
+	capacity, info, err := g.advisor.capacity(ctx, app)
+	if err != nil {
+		return 0, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state, ok := g.apps[app]
+	seen := fmt.Sprintf("%v@%v", info.CurBandwidthCredit, info.LastRefilledAt)
+	if !ok || state.seen != seen {
+		state = &appState{seen: seen}
+		g.apps[app] = state
+	}
+	if capacity.Remaining-state.admitted-g.opts.Reserve >= 1 {
+		state.admitted++
+		return 0, nil
+	}
+	if capacity.NextIn > 0 {
+		return capacity.NextIn, nil
+	}
+	return g.opts.PollInterval, nil
+}
+
+// admitFee admits a message paying the fee if the expected fee is within
+// MaxFee.
+func (g *Gate) admitFee(ctx context.Context) (time.Duration, error) {
+	if g.opts.MaxFee.IsZero() {
+		return 0, nil
+	}
+	load, err := g.advisor.Load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if load.ExpectedMsgFee.IsGT(g.opts.MaxFee) || load.MsgFee.IsGT(g.opts.MaxFee) {
+		return g.opts.PollInterval, nil
+	}
+	return 0, nil
+}
+
+// affiliatingApp returns the app of @p username among Apps, empty if none.
+func (g *Gate) affiliatingApp(ctx context.Context, username string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.affiliating == nil || g.advisor.now().Sub(g.refreshedAt) >= g.opts.RefreshInterval {
+		affiliating := map[string]string{}
+		for _, app := range g.opts.Apps {
+			affiliated, err := g.advisor.source.GetAffiliated(ctx, app)
+			if err != nil {
+				return "", err
+			}
+			for _, username := range affiliated {
+				affiliating[username] = app
+			}
+			affiliating[app] = app
+		}
+		g.affiliating = affiliating
+		g.refreshedAt = g.advisor.now()
+	}
+	return g.affiliating[username], nil
+}
@@ -16,6 +16,7 @@ import (
 	auth "github.com/cosmos/cosmos-sdk/x/auth"
 	"github.com/lino-network/lino-go/errors"
 	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/post"
 	"github.com/lino-network/lino-go/transport"
 	// "github.com/lino-network/lino/param"
 	linotypes "github.com/lino-network/lino/types"
@@ -202,6 +203,40 @@ func (broadcast *Broadcast) MakeTransferV2Msg(sender, receiver linotypes.AccOrAd
 	return txByte, nil
 }
 
+// MakeBatchMsg return the signed tx bytes of msgs which are all signed by
+// the account of privKeyHex only, e.g. transfers from one sender. The
+// signatures take seq, seq+1 and so on, and the chain accepts at most
+// linotypes.TxSigLimit of them in a tx.
+func (broadcast *Broadcast) MakeBatchMsg(msgs []sdk.Msg, privKeyHex string, seq uint64) ([]byte, errors.Error) {
+	if len(msgs) == 0 || len(msgs) > linotypes.TxSigLimit {
+		return nil, errors.InvalidArgf("a batch has 1 to %d msgs, got %d", linotypes.TxSigLimit, len(msgs))
+	}
+	privKeyHexs := make([]string, len(msgs))
+	seqs := make([]uint64, len(msgs))
+	for i := range msgs {
+		privKeyHexs[i] = privKeyHex
+		seqs[i] = seq + uint64(i)
+	}
+	return broadcast.MakeSignedMsgs(msgs, privKeyHexs, seqs, "")
+}
+
+// MakeSignedMsgs return the signed tx bytes of msgs, signed with
+// privKeyHexs[i] and seqs[i] for the i-th signer of the msgs in order. The
+// chain accepts at most linotypes.TxSigLimit signatures in a tx.
+func (broadcast *Broadcast) MakeSignedMsgs(msgs []sdk.Msg, privKeyHexs []string, seqs []uint64, memo string) ([]byte, errors.Error) {
+	if len(msgs) == 0 || len(privKeyHexs) == 0 || len(privKeyHexs) > linotypes.TxSigLimit {
+		return nil, errors.InvalidArgf("a tx has msgs and 1 to %d signatures, got %d", linotypes.TxSigLimit, len(privKeyHexs))
+	}
+	if len(seqs) != len(privKeyHexs) {
+		return nil, errors.InvalidArgf("got %d sequence numbers for %d signatures", len(seqs), len(privKeyHexs))
+	}
+	txByte, buildErr := broadcast.transport.SignAndBuildMsgs(msgs, privKeyHexs, seqs, memo)
+	if buildErr != nil {
+		return nil, buildErr
+	}
+	return txByte, nil
+}
+
 func (broadcast *Broadcast) DecodeTxBytes(txbytes []byte) (*auth.StdTx, errors.Error) {
 	tx := &auth.StdTx{}
 	if err := broadcast.transport.Cdc.UnmarshalJSON(txbytes, tx); err != nil {
@@ -562,8 +597,17 @@ func (broadcast *Broadcast) MakeDeleteMsg(author, postID,
 // }
 
 // MakeUpdatePostMsg return the signed msg bytes.
+// UpdatePostMsg has no links, so @p links are added to the envelope of the
+// content, see post.WithLinks, which must stay within post.DefaultLimits.
 func (broadcast *Broadcast) MakeUpdatePostMsg(author, title, postID, content string,
 	links map[string]string, privKeyHex string, seq uint64) ([]byte, errors.Error) {
+	content, err := post.WithLinks(content, links)
+	if err == nil {
+		err = post.Validate(post.DefaultLimits, author, postID, title, content)
+	}
+	if err != nil {
+		return nil, errors.InvalidArg("MakeUpdatePostMsg: invalid post").AddCause(err)
+	}
 	msg := posttypes.UpdatePostMsg{
 		Author:  linotypes.AccountKey(author),
 		PostID:  postID,
@@ -769,6 +813,10 @@ func (broadcast *Broadcast) MakeStakeOutMsg(username, amount, privKeyHex string,
 	return txByte, nil
 }
 
+// DelegateMsg and DelegatorWithdrawMsg were removed from the vote module in
+// lino v0.6.x and cannot be built anymore, use MakeStakeInForMsg to stake
+// for another user instead.
+
 // Delegate delegates a certain amount of LINO token of delegator to a voter, so
 // the voter will have more voting power.
 // It composes DelegateMsg and then broadcasts the transaction to blockchain.
@@ -1003,6 +1051,9 @@ func (broadcast *Broadcast) MakeUpdateAffiliatedMsg(username, app string, activa
 	return txByte, nil
 }
 
+// Permission grants are gone from lino v0.6.x, see the note above
+// api.GrantPermission.
+
 // GrantPermission grants a certain (e.g. App) permission to
 // an authorized app with a certain period of time.
 // It composes GrantPermissionMsg and then broadcasts the transaction to blockchain.
@@ -0,0 +1,70 @@
+package broadcast
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lino-network/lino-go/post"
+	"github.com/lino-network/lino-go/transport"
+	posttypes "github.com/lino-network/lino/x/post/types"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+)
+
+func TestMakeUpdatePostMsg(t *testing.T) {
+	broadcast := NewBroadcast(transport.NewTransportFromArgs("test", "", 0), 1, 0, 0, false, false)
+	privKeyHex := hex.EncodeToString(secp256k1.GenPrivKey().Bytes())
+	testCases := map[string]struct {
+		content     string
+		links       map[string]string
+		expectBody  string
+		expectLinks map[string]string
+		expectErr   bool
+	}{
+		"no links": {
+			content:    "hello",
+			expectBody: "hello",
+		},
+		"links": {
+			content:     "hello",
+			links:       map[string]string{"source": "https://example.com"},
+			expectBody:  "hello",
+			expectLinks: map[string]string{"source": "https://example.com"},
+		},
+		"too long with links": {
+			content:   strings.Repeat("a", post.DefaultLimits.MaxContentLength),
+			links:     map[string]string{"source": "https://example.com"},
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		txBytes, err := broadcast.MakeUpdatePostMsg("alice", "title", "post", tc.content, tc.links, privKeyHex, 0)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		tx, err := broadcast.DecodeTxBytes(txBytes)
+		if err != nil {
+			t.Errorf("%s: failed to decode tx, got err %v", testName, err)
+			continue
+		}
+		msg, ok := tx.Msgs[0].(posttypes.UpdatePostMsg)
+		if !ok {
+			t.Errorf("%s: diff msg, got %+v", testName, tx.Msgs[0])
+			continue
+		}
+		envelope, parseErr := post.Parse(msg.Content)
+		if parseErr != nil {
+			t.Errorf("%s: failed to parse content %q, got err %v", testName, msg.Content, parseErr)
+			continue
+		}
+		if envelope.Body != tc.expectBody || !reflect.DeepEqual(envelope.Links, tc.expectLinks) {
+			t.Errorf("%s: diff envelope, got %+v", testName, envelope)
+		}
+	}
+}
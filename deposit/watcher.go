@@ -0,0 +1,251 @@
+// Package deposit watches the blockchain for transfers to a set of
+// deposit accounts, as exchanges and custodians need to credit incoming
+// LINO to their customers.
+package deposit
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/scanner"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	acctypes "github.com/lino-network/lino/x/account/types"
+)
+
+var memoTagReCheck = regexp.MustCompile("^[0-9A-Za-z_-]{1,64}$")
+
+// Deposit is a successful transfer to a watched account. ID is stable
+// across re-scans of the same block, so handlers can use it to deduplicate.
+// To is the receiver of the transfer and Account the watched account it
+// credits, which is the username when a watched username is paid at its
+// address.
+type Deposit struct {
+	ID       string         `json:"id"`
+	Height   int64          `json:"height"`
+	Time     time.Time      `json:"time"`
+	TxHash   string         `json:"tx_hash"`
+	MsgIndex int            `json:"msg_index"`
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	Account  string         `json:"account"`
+	Amount   linotypes.Coin `json:"amount"`
+	Memo     string         `json:"memo"`
+	Tag      string         `json:"tag"`
+}
+
+// Handler receives deposits in chain order. A block's checkpoint is saved
+// only after all its deposits are handled, so after a restart or an error
+// deposits of that block are delivered again with the same ID.
+type Handler interface {
+	HandleDeposit(ctx context.Context, deposit *Deposit) error
+}
+
+// HandlerFunc adapts a function to Handler.
+type HandlerFunc func(ctx context.Context, deposit *Deposit) error
+
+// HandleDeposit implements Handler.
+func (f HandlerFunc) HandleDeposit(ctx context.Context, deposit *Deposit) error {
+	return f(ctx, deposit)
+}
+
+// AccountSource returns the info of an account. *api.API satisfies it.
+type AccountSource interface {
+	GetAccountInfo(ctx context.Context, username string) (*accmodel.AccountInfo, error)
+}
+
+// Options configures a Watcher.
+type Options struct {
+	// Accounts are the watched usernames or bech32 addresses. A TransferV2
+	// can pay a username at its address; those transfers are only watched
+	// once Resolve has looked up the addresses of the usernames, which Run
+	// does if the block source is also an AccountSource. Otherwise list the
+	// addresses as well.
+	Accounts []string
+	// StartHeight is the first height scanned when the checkpoint is empty.
+	StartHeight int64
+	// Confirmations is how many blocks a deposit waits before it is emitted.
+	Confirmations int64
+	// PollInterval is how long to wait for new blocks when at the tip.
+	PollInterval time.Duration
+	// RetryInterval is how long to wait before re-scanning from the
+	// checkpoint after an error. Defaults to five seconds.
+	RetryInterval time.Duration
+	// MemoParser extracts the customer tag from a memo. Defaults to ParseMemoTag.
+	MemoParser func(memo string) string
+	// OnError is called with every error before the watcher retries.
+	OnError func(err error)
+}
+
+// Watcher follows blocks and emits deposits to watched accounts.
+type Watcher struct {
+	source     scanner.BlockSource
+	checkpoint scanner.Checkpoint
+	handler    Handler
+	watched    map[string]string
+	resolved   bool
+	opts       Options
+}
+
+// NewWatcher returns an instance of Watcher reading blocks from @p source,
+// resuming from @p checkpoint and emitting deposits to @p handler.
+func NewWatcher(source scanner.BlockSource, checkpoint scanner.Checkpoint, handler Handler, opts Options) *Watcher {
+	if opts.RetryInterval == 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	if opts.MemoParser == nil {
+		opts.MemoParser = ParseMemoTag
+	}
+	if checkpoint == nil {
+		checkpoint = scanner.NewMemCheckpoint()
+	}
+	watched := map[string]string{}
+	for _, acc := range opts.Accounts {
+		watched[acc] = acc
+	}
+	return &Watcher{
+		source:     source,
+		checkpoint: checkpoint,
+		handler:    handler,
+		watched:    watched,
+		opts:       opts,
+	}
+}
+
+// Run follows the chain until the context is done. On any error it reports
+// it to OnError, waits RetryInterval and re-scans from the saved height, so
+// blocks missed because of node or handler failures are not skipped.
+func (w *Watcher) Run(ctx context.Context) error {
+	if len(w.watched) == 0 {
+		return errors.InvalidArg("no watched accounts")
+	}
+
+	s := scanner.NewScanner(w.source, w.checkpoint, scanner.Options{
+		StartHeight:   w.opts.StartHeight,
+		Confirmations: w.opts.Confirmations,
+		PollInterval:  w.opts.PollInterval,
+	})
+	accounts, _ := w.source.(AccountSource)
+	for {
+		var err error
+		if accounts != nil {
+			err = w.Resolve(ctx, accounts)
+		}
+		if err == nil {
+			err = s.Run(ctx, w.HandleBlock)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if w.opts.OnError != nil && err != nil {
+			w.opts.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.opts.RetryInterval):
+		}
+	}
+}
+
+// Resolve looks up the addresses of the watched usernames in @p accounts,
+// so transfers to them are credited to the usernames. Usernames that are not
+// registered are skipped. It only queries once it succeeded.
+func (w *Watcher) Resolve(ctx context.Context, accounts AccountSource) error {
+	if w.resolved {
+		return nil
+	}
+	addrs := map[string]string{}
+	for _, acc := range w.opts.Accounts {
+		if _, err := sdk.AccAddressFromBech32(acc); err == nil {
+			continue
+		}
+		info, err := accounts.GetAccountInfo(ctx, acc)
+		if err != nil {
+			if errors.IsEmptyResponse(err) {
+				continue
+			}
+			return errors.QueryFailf("failed to resolve address of %s", acc).AddCause(err)
+		}
+		addrs[info.Address.String()] = acc
+	}
+	for addr, username := range addrs {
+		w.watched[addr] = username
+	}
+	w.resolved = true
+	return nil
+}
+
+// HandleBlock emits the deposits in @p block. It is the scanner.Handler used by Run.
+func (w *Watcher) HandleBlock(ctx context.Context, block *scanner.Block) error {
+	for _, tx := range block.Txs {
+		if tx.DecodeErr != nil || tx.Code != 0 {
+			continue
+		}
+		for i, msg := range tx.Tx.Msgs {
+			deposit, err := w.toDeposit(msg)
+			if err != nil {
+				return err
+			}
+			if deposit == nil {
+				continue
+			}
+			deposit.ID = fmt.Sprintf("%s:%d", tx.Hash, i)
+			deposit.Height = block.Height
+			deposit.Time = block.Time
+			deposit.TxHash = tx.Hash
+			deposit.MsgIndex = i
+			if err := w.handler.HandleDeposit(ctx, deposit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) toDeposit(msg sdk.Msg) (*Deposit, error) {
+	var from, to, memo string
+	var amount linotypes.LNO
+	switch msg := msg.(type) {
+	case acctypes.TransferMsg:
+		from, to, amount, memo = string(msg.Sender), string(msg.Receiver), msg.Amount, msg.Memo
+	case acctypes.TransferV2Msg:
+		from, to, amount, memo = msg.Sender.String(), msg.Receiver.String(), msg.Amount, msg.Memo
+	default:
+		return nil, nil
+	}
+	account, ok := w.watched[to]
+	if !ok {
+		return nil, nil
+	}
+
+	coin, err := linotypes.LinoToCoin(amount)
+	if err != nil {
+		return nil, errors.InvalidArgf("invalid transfer amount %s", amount).AddCause(err)
+	}
+	return &Deposit{
+		From:    from,
+		To:      to,
+		Account: account,
+		Amount:  coin,
+		Memo:    memo,
+		Tag:     w.opts.MemoParser(memo),
+	}, nil
+}
+
+// ParseMemoTag returns the trimmed memo if it is a single token of at most
+// 64 letters, digits, '_' or '-', which is how deposit memos are usually
+// assigned to customers, or an empty string otherwise.
+func ParseMemoTag(memo string) string {
+	tag := strings.TrimSpace(memo)
+	if !memoTagReCheck.MatchString(tag) {
+		return ""
+	}
+	return tag
+}
@@ -0,0 +1,186 @@
+package deposit
+
+import (
+	"context"
+	goerrors "errors"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/scanner"
+	linoapp "github.com/lino-network/lino/app"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/state"
+	ttypes "github.com/tendermint/tendermint/types"
+)
+
+var depositAddr = sdk.AccAddress(secp256k1.GenPrivKeySecp256k1([]byte("deposit")).PubKey().Address())
+
+func newFixture(t *testing.T) *scanner.Fixture {
+	cdc := linoapp.MakeCodec()
+	encode := func(msg sdk.Msg) ttypes.Tx {
+		bz, err := cdc.MarshalJSON(auth.NewStdTx([]sdk.Msg{msg}, auth.StdFee{}, nil, ""))
+		if err != nil {
+			t.Fatalf("failed to encode tx: %v", err)
+		}
+		return bz
+	}
+	add := func(fixture *scanner.Fixture, height int64, txs []ttypes.Tx, codes []uint32) {
+		results := &state.ABCIResponses{}
+		for _, code := range codes {
+			results.DeliverTx = append(results.DeliverTx, &abci.ResponseDeliverTx{Code: code})
+		}
+		fixture.Add(ttypes.MakeBlock(height, txs, nil, nil), &ctypes.ResultBlockResults{Height: height, Results: results})
+	}
+
+	fixture := &scanner.Fixture{}
+	add(fixture, 1, []ttypes.Tx{
+		encode(acctypes.NewTransferMsg("alice", "exchange", "10", " uid-42 ")),
+		encode(acctypes.NewTransferMsg("alice", "carol", "10", "uid-42")),
+	}, []uint32{0, 0})
+	add(fixture, 2, []ttypes.Tx{
+		encode(acctypes.NewTransferMsg("alice", "exchange", "1000", "uid-42")),
+		encode(acctypes.NewTransferV2Msg(
+			linotypes.NewAccOrAddrFromAcc("bob"), linotypes.NewAccOrAddrFromAddr(depositAddr), "2.5", "thanks!")),
+	}, []uint32{1, 0})
+	return fixture
+}
+
+func TestParseMemoTag(t *testing.T) {
+	testCases := map[string]struct {
+		memo      string
+		expectTag string
+	}{
+		"plain id":       {memo: "uid-42", expectTag: "uid-42"},
+		"id with spaces": {memo: "  123456 ", expectTag: "123456"},
+		"free text":      {memo: "thanks for the stream", expectTag: ""},
+		"punctuation":    {memo: "thanks!", expectTag: ""},
+		"empty memo":     {memo: "", expectTag: ""},
+	}
+
+	for testName, tc := range testCases {
+		if tag := ParseMemoTag(tc.memo); tag != tc.expectTag {
+			t.Errorf("%s: diff tag, got %q, want %q", testName, tag, tc.expectTag)
+		}
+	}
+}
+
+func TestWatcherRetriesFromCheckpoint(t *testing.T) {
+	checkpoint := scanner.NewMemCheckpoint()
+	deposits := map[string]*Deposit{}
+	calls := 0
+	handler := HandlerFunc(func(ctx context.Context, deposit *Deposit) error {
+		calls++
+		if calls == 2 {
+			return goerrors.New("database unavailable")
+		}
+		deposits[deposit.ID] = deposit
+		return nil
+	})
+	errs := []error{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := NewWatcher(newFixture(t), checkpoint, handler, Options{
+		Accounts:      []string{"exchange", depositAddr.String()},
+		PollInterval:  time.Millisecond,
+		RetryInterval: time.Millisecond,
+		OnError:       func(err error) { errs = append(errs, err) },
+	}).Run(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("watcher should stop with the context, got %v", err)
+	}
+
+	if len(errs) != 1 {
+		t.Errorf("diff errors, got %v", errs)
+	}
+	if len(deposits) != 2 {
+		t.Fatalf("diff deposits, got %+v", deposits)
+	}
+	for _, deposit := range deposits {
+		switch deposit.Height {
+		case 1:
+			if deposit.To != "exchange" || deposit.Tag != "uid-42" ||
+				!deposit.Amount.IsEqual(linotypes.NewCoinFromInt64(10*linotypes.Decimals)) {
+				t.Errorf("diff deposit to exchange, got %+v", deposit)
+			}
+		case 2:
+			if deposit.To != depositAddr.String() || deposit.From != "bob" || deposit.Tag != "" {
+				t.Errorf("diff deposit to address, got %+v", deposit)
+			}
+		default:
+			t.Errorf("unexpected deposit %+v", deposit)
+		}
+	}
+	if height, _ := checkpoint.Load(); height != 3 {
+		t.Errorf("diff checkpoint, got %d, want 3", height)
+	}
+}
+
+// fakeChain is a block source that also returns account info, failing the
+// first @p failures queries.
+type fakeChain struct {
+	*scanner.Fixture
+	addrs    map[string]sdk.AccAddress
+	failures int
+	queries  int
+}
+
+func (c *fakeChain) GetAccountInfo(ctx context.Context, username string) (*accmodel.AccountInfo, error) {
+	c.queries++
+	if c.queries <= c.failures {
+		return nil, goerrors.New("node unavailable")
+	}
+	addr, ok := c.addrs[username]
+	if !ok {
+		return nil, errors.EmptyResponse("account info is not found")
+	}
+	return &accmodel.AccountInfo{Username: linotypes.AccountKey(username), Address: addr}, nil
+}
+
+func TestWatcherResolvesAddresses(t *testing.T) {
+	otherAddr := sdk.AccAddress(secp256k1.GenPrivKeySecp256k1([]byte("other")).PubKey().Address())
+	chain := &fakeChain{
+		Fixture:  newFixture(t),
+		addrs:    map[string]sdk.AccAddress{"depositor": depositAddr, "exchange": otherAddr},
+		failures: 1,
+	}
+	deposits := []*Deposit{}
+	handler := HandlerFunc(func(ctx context.Context, deposit *Deposit) error {
+		deposits = append(deposits, deposit)
+		return nil
+	})
+	errs := []error{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	NewWatcher(chain, nil, handler, Options{
+		Accounts:      []string{"exchange", "depositor", "unregistered"},
+		PollInterval:  time.Millisecond,
+		RetryInterval: time.Millisecond,
+		OnError:       func(err error) { errs = append(errs, err) },
+	}).Run(ctx)
+
+	if len(errs) != 1 {
+		t.Errorf("diff errors, got %v", errs)
+	}
+	if chain.queries != 4 {
+		t.Errorf("addresses should be resolved once after the failure, got %v queries", chain.queries)
+	}
+	if len(deposits) != 2 {
+		t.Fatalf("diff deposits, got %+v", deposits)
+	}
+	if deposits[0].To != "exchange" || deposits[0].Account != "exchange" {
+		t.Errorf("diff deposit to username, got %+v", deposits[0])
+	}
+	if deposits[1].To != depositAddr.String() || deposits[1].Account != "depositor" {
+		t.Errorf("diff deposit to address of username, got %+v", deposits[1])
+	}
+}
@@ -0,0 +1,124 @@
+package election
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lino-network/lino/param"
+	linotypes "github.com/lino-network/lino/types"
+	valmodel "github.com/lino-network/lino/x/validator/model"
+)
+
+func coin(n int64) linotypes.Coin {
+	return linotypes.NewCoinFromInt64(n)
+}
+
+// newState returns oncall a and b, standby c, candidate d and jailed e.
+func newState() *State {
+	validator := func(username string, votes int64) *valmodel.Validator {
+		return &valmodel.Validator{Username: linotypes.AccountKey(username), ReceivedVotes: coin(votes)}
+	}
+	return &State{
+		List: &valmodel.ValidatorList{
+			Oncall:             []linotypes.AccountKey{"a", "b"},
+			Standby:            []linotypes.AccountKey{"c"},
+			Candidates:         []linotypes.AccountKey{"d"},
+			Jail:               []linotypes.AccountKey{"e"},
+			LowestOncall:       "b",
+			LowestOncallVotes:  coin(800),
+			LowestStandby:      "c",
+			LowestStandbyVotes: coin(500),
+		},
+		Validators: []*valmodel.Validator{
+			validator("a", 1000), validator("b", 800), validator("c", 500), validator("d", 100), validator("e", 0),
+		},
+		Param: &param.ValidatorParam{OncallSize: 2, StandbySize: 1, MaxVotedValidators: 3},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	testCases := map[string]struct {
+		votes         []Vote
+		expectChanges []Change
+		expectVotes   map[string]int64
+		expectErr     bool
+	}{
+		"candidate voted into oncall": {
+			votes: []Vote{{Voter: "x", Stake: coin(900), Validators: []string{"d"}}},
+			expectChanges: []Change{
+				{Username: "b", From: ListOncall, To: ListStandby},
+				{Username: "c", From: ListStandby, To: ListCandidate},
+				{Username: "d", From: ListCandidate, To: ListOncall},
+			},
+			expectVotes: map[string]int64{"d": 1000},
+		},
+		"moved votes refill standby": {
+			votes: []Vote{{
+				Voter: "y", Stake: coin(600), Validators: []string{"a", "b"},
+				Prev: []valmodel.ElectionVote{{ValidatorName: "c", Vote: coin(300)}},
+			}},
+			expectChanges: []Change{},
+			expectVotes:   map[string]int64{"a": 1300, "b": 1100, "c": 200},
+		},
+		"jailed validator": {
+			votes:         []Vote{{Voter: "x", Stake: coin(2000), Validators: []string{"e"}}},
+			expectChanges: []Change{},
+			expectVotes:   map[string]int64{"e": 2000},
+		},
+		"votes in order": {
+			votes: []Vote{
+				{Voter: "x", Stake: coin(900), Validators: []string{"d"}},
+				{Voter: "x", Stake: coin(900), Validators: []string{"c"},
+					Prev: []valmodel.ElectionVote{{ValidatorName: "d", Vote: coin(900)}}},
+			},
+			expectChanges: []Change{
+				{Username: "b", From: ListOncall, To: ListStandby},
+				{Username: "c", From: ListStandby, To: ListOncall},
+			},
+			expectVotes: map[string]int64{"c": 1400, "d": 100},
+		},
+		"too many validators": {
+			votes:     []Vote{{Voter: "x", Stake: coin(900), Validators: []string{"a", "b", "c", "d"}}},
+			expectErr: true,
+		},
+		"unknown validator": {
+			votes:     []Vote{{Voter: "x", Stake: coin(900), Validators: []string{"z"}}},
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		state := newState()
+		res, err := Simulate(state, tc.votes...)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(res.Changes, tc.expectChanges) {
+			t.Errorf("%s: diff changes, got %+v, want %+v", testName, res.Changes, tc.expectChanges)
+		}
+		for _, validator := range res.State.Validators {
+			if votes, ok := tc.expectVotes[string(validator.Username)]; ok && !validator.ReceivedVotes.IsEqual(coin(votes)) {
+				t.Errorf("%s: diff votes of %s, got %v, want %v", testName, validator.Username, validator.ReceivedVotes, votes)
+			}
+		}
+		if !reflect.DeepEqual(state, newState()) {
+			t.Errorf("%s: state is modified", testName)
+		}
+	}
+}
+
+func TestStandings(t *testing.T) {
+	expect := []Standing{
+		{Username: "a", List: ListOncall, ReceivedVotes: coin(1000), ToOncall: coin(0), ToStandby: coin(0)},
+		{Username: "b", List: ListOncall, ReceivedVotes: coin(800), ToOncall: coin(0), ToStandby: coin(0)},
+		{Username: "c", List: ListStandby, ReceivedVotes: coin(500), ToOncall: coin(301), ToStandby: coin(0)},
+		{Username: "d", List: ListCandidate, ReceivedVotes: coin(100), ToOncall: coin(701), ToStandby: coin(401)},
+	}
+	if standings := Standings(newState()); !reflect.DeepEqual(standings, expect) {
+		t.Errorf("diff standings, got %+v, want %+v", standings, expect)
+	}
+}
@@ -0,0 +1,376 @@
+// Package election simulates the validator election of the chain: how the
+// votes of VoteValidator msgs move validators between the oncall, standby
+// and candidate lists. Simulate is a pure function over a State, which a
+// Simulator fetches from the chain.
+package election
+
+import (
+	"math"
+	"sort"
+
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino/param"
+	linotypes "github.com/lino-network/lino/types"
+	valmodel "github.com/lino-network/lino/x/validator/model"
+)
+
+// Validator lists.
+const (
+	ListOncall    = "oncall"
+	ListStandby   = "standby"
+	ListCandidate = "candidate"
+	ListJail      = "jail"
+)
+
+// State is the election state of the chain. Validators holds every
+// validator of the lists.
+type State struct {
+	List       *valmodel.ValidatorList `json:"list"`
+	Validators []*valmodel.Validator   `json:"validators"`
+	Param      *param.ValidatorParam   `json:"param"`
+}
+
+// Vote is a VoteValidator msg of Voter, with the stake of the voter and its
+// current election votes.
+type Vote struct {
+	Voter      string                  `json:"voter"`
+	Stake      linotypes.Coin          `json:"stake"`
+	Prev       []valmodel.ElectionVote `json:"prev"`
+	Validators []string                `json:"validators"`
+}
+
+// Change is a validator moving from a list to another.
+type Change struct {
+	Username string `json:"username"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+// Result is the state after votes and the validators that changed lists,
+// ordered by username.
+type Result struct {
+	State   *State   `json:"state"`
+	Changes []Change `json:"changes"`
+}
+
+// Standing is the place of a validator in the election. ToOncall and
+// ToStandby are the votes it needs to receive to join the oncall or standby
+// list, zero if it is already in or above it.
+type Standing struct {
+	Username      string         `json:"username"`
+	List          string         `json:"list"`
+	ReceivedVotes linotypes.Coin `json:"received_votes"`
+	ToOncall      linotypes.Coin `json:"to_oncall"`
+	ToStandby     linotypes.Coin `json:"to_standby"`
+}
+
+// Simulate applies @p votes to @p state in order, following the rules of the
+// chain, and returns the resulting state. @p state is not modified. Votes
+// withdrawn from validators missing from the state, e.g. revoked ones, only
+// lower their votes and are ignored.
+func Simulate(state *State, votes ...Vote) (*Result, error) {
+	e, err := newElection(state)
+	if err != nil {
+		return nil, err
+	}
+	for _, vote := range votes {
+		if err := e.vote(vote); err != nil {
+			return nil, err
+		}
+	}
+
+	after := e.state(state)
+	changes := []Change{}
+	for _, validator := range state.Validators {
+		from, to := listOf(state.List, validator.Username), listOf(after.List, validator.Username)
+		if from != to {
+			changes = append(changes, Change{Username: string(validator.Username), From: from, To: to})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Username < changes[j].Username })
+	return &Result{State: after, Changes: changes}, nil
+}
+
+// Standings returns the standing of every oncall, standby and candidate
+// validator of @p state, most voted first.
+func Standings(state *State) []Standing {
+	one := linotypes.NewCoinFromInt64(1)
+	needs := func(votes, lowest linotypes.Coin) linotypes.Coin {
+		if votes.IsGT(lowest) {
+			return linotypes.NewCoinFromInt64(0)
+		}
+		return lowest.Minus(votes).Plus(one)
+	}
+
+	standings := []Standing{}
+	for _, validator := range state.Validators {
+		list := listOf(state.List, validator.Username)
+		if list == "" || list == ListJail {
+			continue
+		}
+		standing := Standing{
+			Username:      string(validator.Username),
+			List:          list,
+			ReceivedVotes: validator.ReceivedVotes,
+			ToOncall:      linotypes.NewCoinFromInt64(0),
+			ToStandby:     linotypes.NewCoinFromInt64(0),
+		}
+		if list != ListOncall {
+			standing.ToOncall = needs(validator.ReceivedVotes, state.List.LowestOncallVotes)
+		}
+		if list == ListCandidate {
+			standing.ToStandby = needs(validator.ReceivedVotes, state.List.LowestStandbyVotes)
+		}
+		standings = append(standings, standing)
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		if !standings[i].ReceivedVotes.IsEqual(standings[j].ReceivedVotes) {
+			return standings[i].ReceivedVotes.IsGT(standings[j].ReceivedVotes)
+		}
+		return standings[i].Username < standings[j].Username
+	})
+	return standings
+}
+
+// listOf returns the list @p username is in, empty if none.
+func listOf(list *valmodel.ValidatorList, username linotypes.AccountKey) string {
+	switch {
+	case linotypes.FindAccountInList(username, list.Oncall) != -1:
+		return ListOncall
+	case linotypes.FindAccountInList(username, list.Standby) != -1:
+		return ListStandby
+	case linotypes.FindAccountInList(username, list.Candidates) != -1:
+		return ListCandidate
+	case linotypes.FindAccountInList(username, list.Jail) != -1:
+		return ListJail
+	}
+	return ""
+}
+
+// election is a mutable copy of a State. Its methods mirror the validator
+// manager of the chain.
+type election struct {
+	list       valmodel.ValidatorList
+	validators map[linotypes.AccountKey]*valmodel.Validator
+	param      *param.ValidatorParam
+}
+
+func newElection(state *State) (*election, error) {
+	if state.List == nil || state.Param == nil {
+		return nil, errors.InvalidArg("validator list and param are required")
+	}
+	e := &election{
+		list:       *state.List,
+		validators: make(map[linotypes.AccountKey]*valmodel.Validator, len(state.Validators)),
+		param:      state.Param,
+	}
+	e.list.Oncall = append([]linotypes.AccountKey{}, state.List.Oncall...)
+	e.list.Standby = append([]linotypes.AccountKey{}, state.List.Standby...)
+	e.list.Candidates = append([]linotypes.AccountKey{}, state.List.Candidates...)
+	e.list.Jail = append([]linotypes.AccountKey{}, state.List.Jail...)
+	for _, validator := range state.Validators {
+		copied := *validator
+		e.validators[validator.Username] = &copied
+	}
+	for _, lst := range [][]linotypes.AccountKey{e.list.Oncall, e.list.Standby, e.list.Candidates, e.list.Jail} {
+		for _, username := range lst {
+			if _, ok := e.validators[username]; !ok {
+				return nil, errors.InvalidArgf("validator %s of the lists is missing", username)
+			}
+		}
+	}
+	return e, nil
+}
+
+// state returns the current state, with validators in the order of @p prev.
+func (e *election) state(prev *State) *State {
+	list := e.list
+	validators := make([]*valmodel.Validator, len(prev.Validators))
+	for i, validator := range prev.Validators {
+		validators[i] = e.validators[validator.Username]
+	}
+	return &State{List: &list, Validators: validators, Param: e.param}
+}
+
+func (e *election) vote(vote Vote) error {
+	if int64(len(vote.Validators)) > e.param.MaxVotedValidators {
+		return errors.InvalidArgf("%v voted validators, at most %v", len(vote.Validators), e.param.MaxVotedValidators)
+	}
+	for _, username := range vote.Validators {
+		validator, ok := e.validators[linotypes.AccountKey(username)]
+		if !ok || validator.HasRevoked {
+			return errors.InvalidArgf("validator %s is not found", username)
+		}
+	}
+	if len(vote.Prev) == 0 && len(vote.Validators) == 0 {
+		return nil
+	}
+	if len(vote.Validators) == 0 {
+		return errors.InvalidArg("no validator voted")
+	}
+
+	// as getElectionVoteListUpdates: withdraw all previous votes and split
+	// the stake among the voted validators.
+	updates := []*valmodel.ElectionVote{}
+	for _, prev := range vote.Prev {
+		updates = append(updates, &valmodel.ElectionVote{ValidatorName: prev.ValidatorName, Vote: prev.Vote.Neg()})
+	}
+	voteStake := linotypes.DecToCoin(vote.Stake.ToDec().QuoInt64(int64(len(vote.Validators))))
+	for _, username := range vote.Validators {
+		found := false
+		for _, update := range updates {
+			if update.ValidatorName == linotypes.AccountKey(username) {
+				update.Vote = update.Vote.Plus(voteStake)
+				found = true
+				break
+			}
+		}
+		if !found {
+			updates = append(updates, &valmodel.ElectionVote{ValidatorName: linotypes.AccountKey(username), Vote: voteStake})
+		}
+	}
+
+	for _, update := range updates {
+		if update.Vote.IsZero() {
+			continue
+		}
+		validator, ok := e.validators[update.ValidatorName]
+		if !ok {
+			continue
+		}
+		validator.ReceivedVotes = validator.ReceivedVotes.Plus(update.Vote)
+
+		switch list := listOf(&e.list, update.ValidatorName); {
+		case update.Vote.IsPositive() && list == ListStandby:
+			e.onStandbyVotesInc(validator)
+		case update.Vote.IsPositive() && list == ListCandidate:
+			e.onCandidateVotesInc(validator)
+		case !update.Vote.IsPositive() && list == ListStandby:
+			e.onStandbyVotesDec(validator)
+		case !update.Vote.IsPositive() && list == ListOncall:
+			e.onOncallVotesDec(validator)
+		case list == ListOncall:
+			e.balance()
+		}
+	}
+	return nil
+}
+
+func (e *election) onCandidateVotesInc(me *valmodel.Validator) {
+	if me.ReceivedVotes.IsGT(e.list.LowestOncallVotes) {
+		e.list.Candidates = remove(me.Username, e.list.Candidates)
+		e.list.Oncall = append(e.list.Oncall, me.Username)
+	} else if me.ReceivedVotes.IsGT(e.list.LowestStandbyVotes) {
+		e.list.Candidates = remove(me.Username, e.list.Candidates)
+		e.list.Standby = append(e.list.Standby, me.Username)
+	}
+	e.balance()
+}
+
+func (e *election) onStandbyVotesInc(me *valmodel.Validator) {
+	if me.ReceivedVotes.IsGT(e.list.LowestOncallVotes) {
+		e.list.Standby = remove(me.Username, e.list.Standby)
+		e.list.Oncall = append(e.list.Oncall, me.Username)
+	}
+	e.balance()
+}
+
+func (e *election) onStandbyVotesDec(me *valmodel.Validator) {
+	if !me.ReceivedVotes.IsGTE(e.list.LowestStandbyVotes) {
+		e.list.Standby = remove(me.Username, e.list.Standby)
+		e.list.Candidates = append(e.list.Candidates, me.Username)
+	}
+	e.balance()
+}
+
+func (e *election) onOncallVotesDec(me *valmodel.Validator) {
+	if !me.ReceivedVotes.IsGTE(e.list.LowestStandbyVotes) {
+		e.list.Oncall = remove(me.Username, e.list.Oncall)
+		e.list.Candidates = append(e.list.Candidates, me.Username)
+	} else if !me.ReceivedVotes.IsGTE(e.list.LowestOncallVotes) {
+		e.list.Oncall = remove(me.Username, e.list.Oncall)
+		e.list.Standby = append(e.list.Standby, me.Username)
+	}
+	e.balance()
+}
+
+// balance is balanceValidatorList: it moves the lowest validators down from
+// full lists, fills empty seats with the highest validators below and
+// updates the lowest votes.
+func (e *election) balance() {
+	for int64(len(e.list.Oncall)) > e.param.OncallSize {
+		lowest := e.lowest(e.list.Oncall)
+		e.list.Oncall = remove(lowest, e.list.Oncall)
+		e.list.Standby = append(e.list.Standby, lowest)
+	}
+	for int64(len(e.list.Standby)) > e.param.StandbySize {
+		lowest := e.lowest(e.list.Standby)
+		e.list.Standby = remove(lowest, e.list.Standby)
+		e.list.Candidates = append(e.list.Candidates, lowest)
+	}
+	for int64(len(e.list.Oncall)) < e.param.OncallSize && len(e.list.Standby) > 0 {
+		highest := e.highest(e.list.Standby)
+		e.list.Standby = remove(highest, e.list.Standby)
+		e.list.Oncall = append(e.list.Oncall, highest)
+	}
+	for int64(len(e.list.Oncall)) < e.param.OncallSize && len(e.list.Candidates) > 0 {
+		highest := e.highest(e.list.Candidates)
+		e.list.Candidates = remove(highest, e.list.Candidates)
+		e.list.Oncall = append(e.list.Oncall, highest)
+	}
+	for int64(len(e.list.Standby)) < e.param.StandbySize && len(e.list.Candidates) > 0 {
+		highest := e.highest(e.list.Candidates)
+		e.list.Candidates = remove(highest, e.list.Candidates)
+		e.list.Standby = append(e.list.Standby, highest)
+	}
+	e.list.LowestOncall, e.list.LowestOncallVotes = e.strictLowest(e.list.Oncall)
+	e.list.LowestStandby, e.list.LowestStandbyVotes = e.strictLowest(e.list.Standby)
+}
+
+// highest returns the validator of @p lst with the most votes, the last one
+// on ties, as getHighestVotesAndValidator.
+func (e *election) highest(lst []linotypes.AccountKey) linotypes.AccountKey {
+	highest, votes := linotypes.AccountKey(""), linotypes.NewCoinFromInt64(0)
+	for _, username := range lst {
+		if validator := e.validators[username]; validator.ReceivedVotes.IsGTE(votes) {
+			highest, votes = username, validator.ReceivedVotes
+		}
+	}
+	return highest
+}
+
+// lowest returns the validator of @p lst with the least votes, the last one
+// on ties, as getLowestVotesAndValidator.
+func (e *election) lowest(lst []linotypes.AccountKey) linotypes.AccountKey {
+	lowest, votes := linotypes.AccountKey(""), linotypes.NewCoinFromInt64(math.MaxInt64)
+	for _, username := range lst {
+		if validator := e.validators[username]; votes.IsGTE(validator.ReceivedVotes) {
+			lowest, votes = username, validator.ReceivedVotes
+		}
+	}
+	return lowest
+}
+
+// strictLowest returns the validator of @p lst with the least votes, the
+// first one on ties, and its votes, as updateLowestOncall.
+func (e *election) strictLowest(lst []linotypes.AccountKey) (linotypes.AccountKey, linotypes.Coin) {
+	if len(lst) == 0 {
+		return "", linotypes.NewCoinFromInt64(0)
+	}
+	lowest, votes := linotypes.AccountKey(""), linotypes.NewCoinFromInt64(math.MaxInt64)
+	for _, username := range lst {
+		if validator := e.validators[username]; votes.IsGT(validator.ReceivedVotes) {
+			lowest, votes = username, validator.ReceivedVotes
+		}
+	}
+	return lowest, votes
+}
+
+func remove(username linotypes.AccountKey, lst []linotypes.AccountKey) []linotypes.AccountKey {
+	for i := range lst {
+		if lst[i] == username {
+			return append(lst[:i:i], lst[i+1:]...)
+		}
+	}
+	return lst
+}
@@ -0,0 +1,94 @@
+package election
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lino-network/lino/param"
+	linotypes "github.com/lino-network/lino/types"
+	valmodel "github.com/lino-network/lino/x/validator/model"
+	votemodel "github.com/lino-network/lino/x/vote/model"
+)
+
+// Source provides the election state and the votes of voters. *query.Query
+// implements it.
+type Source interface {
+	GetAllValidators(ctx context.Context) (*valmodel.ValidatorList, error)
+	GetValidator(ctx context.Context, username string) (*valmodel.Validator, error)
+	GetValidatorParam(ctx context.Context) (*param.ValidatorParam, error)
+	GetElectionVoteList(ctx context.Context, username string) (*valmodel.ElectionVoteList, error)
+	GetVoter(ctx context.Context, username string) (*votemodel.Voter, error)
+}
+
+// Simulator fetches election states and votes to simulate.
+type Simulator struct {
+	source Source
+}
+
+// NewSimulator returns an instance of Simulator reading from @p source.
+func NewSimulator(source Source) *Simulator {
+	return &Simulator{source: source}
+}
+
+// State returns the current election state.
+func (s *Simulator) State(ctx context.Context) (*State, error) {
+	list, err := s.source.GetAllValidators(ctx)
+	if err != nil {
+		return nil, err
+	}
+	validatorParam, err := s.source.GetValidatorParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usernames := []string{}
+	for _, lst := range [][]linotypes.AccountKey{list.Oncall, list.Standby, list.Candidates, list.Jail} {
+		for _, username := range lst {
+			usernames = append(usernames, string(username))
+		}
+	}
+	validators := make([]*valmodel.Validator, len(usernames))
+	errs := make([]error, len(usernames))
+	var wg sync.WaitGroup
+	for i, username := range usernames {
+		wg.Add(1)
+		go func(i int, username string) {
+			defer wg.Done()
+			validators[i], errs[i] = s.source.GetValidator(ctx, username)
+		}(i, username)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &State{List: list, Validators: validators, Param: validatorParam}, nil
+}
+
+// Vote returns the vote of @p voter for @p validators, with its current
+// stake and election votes.
+func (s *Simulator) Vote(ctx context.Context, voter string, validators []string) (*Vote, error) {
+	v, err := s.source.GetVoter(ctx, voter)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := s.source.GetElectionVoteList(ctx, voter)
+	if err != nil {
+		return nil, err
+	}
+	return &Vote{Voter: voter, Stake: v.LinoStake, Prev: prev.ElectionVotes, Validators: validators}, nil
+}
+
+// WhatIf returns the election state if @p voter voted for @p validators now.
+func (s *Simulator) WhatIf(ctx context.Context, voter string, validators []string) (*Result, error) {
+	state, err := s.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vote, err := s.Vote(ctx, voter, validators)
+	if err != nil {
+		return nil, err
+	}
+	return Simulate(state, *vote)
+}
@@ -20,4 +20,5 @@ const (
 	CodeGuaranteeBroadcastFail // guarantee broadcast fail
 	CodeUnmarshalFailed
 	CodeSequenceNumberNotEnough // for multisig msg return error if sequence number is not enough
+	CodeBandwidthNotEnough      // rejected by admission control before broadcast
 )
@@ -30,6 +30,13 @@ func NewError(code CodeType, msg string) Error {
 	return newError(code, msg)
 }
 
+// IsEmptyResponse returns true if @p err is an Error with CodeEmptyResponse,
+// e.g. of a query of something not on chain.
+func IsEmptyResponse(err error) bool {
+	linoe, ok := err.(Error)
+	return ok && linoe.CodeType() == CodeEmptyResponse
+}
+
 type serverError struct {
 	code           CodeType
 	msg            string
@@ -31,6 +31,8 @@ func CodeToDefaultMsg(code CodeType) string {
 		return "Tx Not Found"
 	case CodeSequenceNumberNotEnough:
 		return "sequence number not enough"
+	case CodeBandwidthNotEnough:
+		return "bandwidth not enough"
 	default:
 		return fmt.Sprintf("Unknown code %d", code)
 	}
@@ -136,6 +138,11 @@ func SequenceNumberNotEnoughf(format string, args ...interface{}) Error {
 	return newError(CodeSequenceNumberNotEnough, fmt.Sprintf(format, args...))
 }
 
+//BandwidthNotEnoughf creates an error with CodeBandwidthNotEnough and formatted message
+func BandwidthNotEnoughf(format string, args ...interface{}) Error {
+	return newError(CodeBandwidthNotEnough, fmt.Sprintf(format, args...))
+}
+
 //EmptyResponse creates an error with CodeEmptyResponse
 func EmptyResponse(msg string) Error {
 	return newError(CodeEmptyResponse, msg)
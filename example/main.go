@@ -2,11 +2,10 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
 
 	"github.com/lino-network/lino-go/api"
-	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/lino-network/lino-go/onboarding"
 )
 
 //"encoding/hex"
@@ -126,21 +125,28 @@ func main() {
 	// resetPub := resetPriv.PubKey()
 	// txPub := txPriv.PubKey()
 	// appPub := appPriv.PubKey()
-	newUserResetKey := secp256k1.GenPrivKey()
-	newUserTxKey := secp256k1.GenPrivKey()
-	newUserAppKey := secp256k1.GenPrivKey()
 	newUser := "ffsffssds"
 
 	api := api.NewLinoAPIFromArgs(&api.Options{
 		ChainID: "lino-staging",
 		NodeURL: "http://localhost:26657",
 	})
-	resp, err := api.Register(context.Background(), "lino", "100", newUser, hex.EncodeToString(newUserResetKey.PubKey().Bytes()), hex.EncodeToString(newUserTxKey.PubKey().Bytes()), hex.EncodeToString(newUserAppKey.PubKey().Bytes()), "E1B0F79B202FDC4DB4ED428384A06E9A6562527A0A0E85203508700E1BFA96CAB458D899B1")
+	onboarder, err := onboarding.NewOnboarder(api, onboarding.Options{
+		Referrer:           "lino",
+		ReferrerPrivKeyHex: "E1B0F79B202FDC4DB4ED428384A06E9A6562527A0A0E85203508700E1BFA96CAB458D899B1",
+	})
 	if err != nil {
 		panic(err)
 	}
-
-	fmt.Println(">>resp: ", resp.CommitHash)
+	account, err := onboarder.Register(context.Background(), onboarding.Request{Username: newUser})
+	if err != nil {
+		panic(err)
+	}
+	if resp := account.RegisterTx; resp != nil {
+		fmt.Println(">>resp: ", resp.CommitHash)
+	} else {
+		fmt.Println(">>already registered: ", account.Username)
+	}
 
 	// _, err = api.GrantPermission(newUser, "lino", 7*24*60*60, model.AppPermission, hex.EncodeToString(newUserTxKey.Bytes()), 0)
 	// if err != nil {
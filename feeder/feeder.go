@@ -0,0 +1,328 @@
+// Package feeder fetches the LINO price from a set of sources and feeds
+// their median to the blockchain on behalf of a validator, whenever it moved
+// enough or the last feed is getting old.
+package feeder
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/price"
+	linotypes "github.com/lino-network/lino/types"
+	pricemodel "github.com/lino-network/lino/x/price/model"
+)
+
+// Feed reasons.
+const (
+	ReasonFirstFeed = "first_feed"
+	ReasonDeviation = "deviation"
+	ReasonHeartbeat = "heartbeat"
+)
+
+// Chain queries prices and feeds them. *api.API implements it.
+type Chain interface {
+	GetCurrentPrice(ctx context.Context) (linotypes.MiniDollar, error)
+	GetHistoryPrice(ctx context.Context) ([]pricemodel.FeedHistory, error)
+	GetLastFeed(ctx context.Context, validator string) (*pricemodel.FedPrice, error)
+	FeedPrice(ctx context.Context, username string, price linotypes.MiniDollar,
+		privKeyHex string) (*model.BroadcastResponse, errors.Error)
+}
+
+// Options configures a Feeder. Deviations are in basis points.
+type Options struct {
+	// Username and PrivKeyHex are the validator feeding prices.
+	Username   string
+	PrivKeyHex string
+	// MinSources is how many sources must return a price for a round to
+	// feed. Defaults to 1.
+	MinSources int
+	// DeviationBps is how far the median may move from the last fed price
+	// before it is fed again. Defaults to 100.
+	DeviationBps int64
+	// Heartbeat is the age of the last fed price after which the median is
+	// fed even if it did not move. Keep it below the feed_every price
+	// param, missing a feed is penalized. Defaults to one hour.
+	Heartbeat time.Duration
+	// MaxCurrentDeviationBps bounds how far the median may be from the
+	// current chain price. Defaults to 2000.
+	MaxCurrentDeviationBps int64
+	// HistoryLen is how many of the latest history prices bound the median.
+	// Defaults to 24.
+	HistoryLen int
+	// MaxHistoryDeviationBps is how far the median may be below the lowest
+	// or above the highest of those prices. Defaults to 5000.
+	MaxHistoryDeviationBps int64
+	// Interval is the time between rounds of Run. Defaults to one minute.
+	Interval time.Duration
+	// OnError is called with the error of every failed round of Run.
+	OnError func(err error)
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Metrics count the rounds and submissions of a Feeder.
+type Metrics struct {
+	Rounds         int64                `json:"rounds"`
+	Submissions    int64                `json:"submissions"`
+	SubmitFailures int64                `json:"submit_failures"`
+	Rejected       int64                `json:"rejected"`
+	Skipped        int64                `json:"skipped"`
+	SourceFailures map[string]int64     `json:"source_failures"`
+	LastPrice      linotypes.MiniDollar `json:"last_price"`
+	LastSubmitAt   time.Time            `json:"last_submit_at"`
+	LastTxHash     string               `json:"last_tx_hash"`
+	LastError      string               `json:"last_error"`
+}
+
+// RoundResult is the outcome of a round.
+type RoundResult struct {
+	// Prices are the prices returned by each source.
+	Prices map[string]linotypes.MiniDollar `json:"prices"`
+	Median linotypes.MiniDollar            `json:"median"`
+	// Reason is why the median was fed, empty if it was not.
+	Reason   string                   `json:"reason"`
+	Response *model.BroadcastResponse `json:"response"`
+}
+
+// Feeder feeds the median price of its sources.
+type Feeder struct {
+	chain   Chain
+	sources []Source
+	opts    Options
+
+	mtx     sync.Mutex
+	metrics Metrics
+}
+
+// NewFeeder returns an instance of Feeder feeding prices of @p sources to @p chain.
+func NewFeeder(chain Chain, sources []Source, opts Options) (*Feeder, error) {
+	if opts.Username == "" || opts.PrivKeyHex == "" {
+		return nil, errors.InvalidArg("feeder username and private key are required")
+	}
+	if opts.MinSources <= 0 {
+		opts.MinSources = 1
+	}
+	if len(sources) < opts.MinSources {
+		return nil, errors.InvalidArgf("%v sources, need at least %v", len(sources), opts.MinSources)
+	}
+	if opts.DeviationBps <= 0 {
+		opts.DeviationBps = 100
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = time.Hour
+	}
+	if opts.MaxCurrentDeviationBps <= 0 {
+		opts.MaxCurrentDeviationBps = 2000
+	}
+	if opts.HistoryLen <= 0 {
+		opts.HistoryLen = 24
+	}
+	if opts.MaxHistoryDeviationBps <= 0 {
+		opts.MaxHistoryDeviationBps = 5000
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Feeder{
+		chain:   chain,
+		sources: sources,
+		opts:    opts,
+		metrics: Metrics{SourceFailures: map[string]int64{}},
+	}, nil
+}
+
+// Run plays a round every Interval until the context is done. Failed rounds
+// are reported to OnError and do not stop the feeder.
+func (f *Feeder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(f.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := f.Round(ctx); err != nil && f.opts.OnError != nil {
+			f.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Round fetches all sources, checks their median against the chain prices
+// and feeds it if it deviates from the last fed price or the heartbeat is due.
+func (f *Feeder) Round(ctx context.Context) (*RoundResult, error) {
+	res, err := f.round(ctx)
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.metrics.Rounds++
+	if err != nil {
+		f.metrics.LastError = err.Error()
+	}
+	return res, err
+}
+
+// Metrics returns a copy of the feeder's metrics.
+func (f *Feeder) Metrics() Metrics {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	metrics := f.metrics
+	metrics.SourceFailures = make(map[string]int64, len(f.metrics.SourceFailures))
+	for name, n := range f.metrics.SourceFailures {
+		metrics.SourceFailures[name] = n
+	}
+	return metrics
+}
+
+func (f *Feeder) round(ctx context.Context) (*RoundResult, error) {
+	prices := f.fetch(ctx)
+	if len(prices) < f.opts.MinSources {
+		return nil, errors.QueryFailf("%v of %v price sources answered, need %v",
+			len(prices), len(f.sources), f.opts.MinSources)
+	}
+	res := &RoundResult{Prices: prices, Median: Median(prices)}
+
+	if err := f.checkBounds(ctx, res.Median); err != nil {
+		f.count(func(m *Metrics) { m.Rejected++ })
+		return res, err
+	}
+
+	reason, err := f.reason(ctx, res.Median)
+	if err != nil {
+		return res, err
+	}
+	if reason == "" {
+		f.count(func(m *Metrics) { m.Skipped++ })
+		return res, nil
+	}
+
+	resp, feedErr := f.chain.FeedPrice(ctx, f.opts.Username, res.Median, f.opts.PrivKeyHex)
+	if feedErr != nil {
+		f.count(func(m *Metrics) { m.SubmitFailures++ })
+		return res, feedErr
+	}
+	res.Reason = reason
+	res.Response = resp
+	f.count(func(m *Metrics) {
+		m.Submissions++
+		m.LastPrice = res.Median
+		m.LastSubmitAt = f.opts.Now()
+		if resp != nil {
+			m.LastTxHash = resp.CommitHash
+		}
+	})
+	return res, nil
+}
+
+// fetch queries all sources concurrently and returns the prices of those
+// that answered, keyed by source name.
+func (f *Feeder) fetch(ctx context.Context) map[string]linotypes.MiniDollar {
+	var wg sync.WaitGroup
+	prices := make([]linotypes.MiniDollar, len(f.sources))
+	errs := make([]error, len(f.sources))
+	for i, source := range f.sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			prices[i], errs[i] = source.Price(ctx)
+		}(i, source)
+	}
+	wg.Wait()
+
+	res := map[string]linotypes.MiniDollar{}
+	for i, source := range f.sources {
+		if errs[i] != nil || !prices[i].IsPositive() {
+			f.count(func(m *Metrics) { m.SourceFailures[source.Name()]++ })
+			continue
+		}
+		res[source.Name()] = prices[i]
+	}
+	return res
+}
+
+// checkBounds rejects a median far from the current price or outside of the
+// range of the latest history prices.
+func (f *Feeder) checkBounds(ctx context.Context, median linotypes.MiniDollar) error {
+	current, err := f.chain.GetCurrentPrice(ctx)
+	if err != nil {
+		return err
+	}
+	if current.IsPositive() && price.DeviationBps(median, current) > f.opts.MaxCurrentDeviationBps {
+		return errors.InvalidArgf("median price %v deviates more than %v bps from current price %v",
+			median, f.opts.MaxCurrentDeviationBps, current)
+	}
+
+	history, err := f.chain.GetHistoryPrice(ctx)
+	if err != nil {
+		return err
+	}
+	if len(history) > f.opts.HistoryLen {
+		history = history[len(history)-f.opts.HistoryLen:]
+	}
+	if len(history) == 0 {
+		return nil
+	}
+	low, high := history[0].Price, history[0].Price
+	for _, h := range history[1:] {
+		if h.Price.LT(low) {
+			low = h.Price
+		}
+		if high.LT(h.Price) {
+			high = h.Price
+		}
+	}
+	if (median.LT(low) && price.DeviationBps(median, low) > f.opts.MaxHistoryDeviationBps) ||
+		(high.LT(median) && price.DeviationBps(median, high) > f.opts.MaxHistoryDeviationBps) {
+		return errors.InvalidArgf("median price %v deviates more than %v bps from history range [%v, %v]",
+			median, f.opts.MaxHistoryDeviationBps, low, high)
+	}
+	return nil
+}
+
+// reason returns why @p median should be fed, or an empty string.
+func (f *Feeder) reason(ctx context.Context, median linotypes.MiniDollar) (string, error) {
+	last, err := f.chain.GetLastFeed(ctx, f.opts.Username)
+	if err != nil {
+		if errors.IsEmptyResponse(err) {
+			return ReasonFirstFeed, nil
+		}
+		return "", err
+	}
+	if price.DeviationBps(median, last.Price) >= f.opts.DeviationBps {
+		return ReasonDeviation, nil
+	}
+	if f.opts.Now().Sub(time.Unix(last.UpdateAt, 0)) >= f.opts.Heartbeat {
+		return ReasonHeartbeat, nil
+	}
+	return "", nil
+}
+
+func (f *Feeder) count(fn func(m *Metrics)) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	fn(&f.metrics)
+}
+
+// Median returns the median of @p prices, the mean of the middle two if
+// their number is even.
+func Median(prices map[string]linotypes.MiniDollar) linotypes.MiniDollar {
+	sorted := make([]linotypes.MiniDollar, 0, len(prices))
+	for _, price := range prices {
+		sorted = append(sorted, price)
+	}
+	if len(sorted) == 0 {
+		return linotypes.NewMiniDollar(0)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LT(sorted[j]) })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return linotypes.NewMiniDollarFromInt(sorted[mid-1].Add(sorted[mid].Int).QuoRaw(2))
+}
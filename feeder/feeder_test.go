@@ -0,0 +1,261 @@
+package feeder
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linotypes "github.com/lino-network/lino/types"
+	pricemodel "github.com/lino-network/lino/x/price/model"
+)
+
+var now = time.Unix(1600000000, 0)
+
+type fakeChain struct {
+	current  linotypes.MiniDollar
+	history  []pricemodel.FeedHistory
+	lastFeed *pricemodel.FedPrice
+	fed      []linotypes.MiniDollar
+}
+
+func (c *fakeChain) GetCurrentPrice(ctx context.Context) (linotypes.MiniDollar, error) {
+	return c.current, nil
+}
+
+func (c *fakeChain) GetHistoryPrice(ctx context.Context) ([]pricemodel.FeedHistory, error) {
+	return c.history, nil
+}
+
+func (c *fakeChain) GetLastFeed(ctx context.Context, validator string) (*pricemodel.FedPrice, error) {
+	if c.lastFeed == nil {
+		return nil, errors.EmptyResponse("last fed price is not found")
+	}
+	return c.lastFeed, nil
+}
+
+func (c *fakeChain) FeedPrice(ctx context.Context, username string, price linotypes.MiniDollar,
+	privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	c.fed = append(c.fed, price)
+	return &model.BroadcastResponse{CommitHash: fmt.Sprintf("TX%d", len(c.fed))}, nil
+}
+
+type staticSource struct {
+	name  string
+	price string
+}
+
+func (s staticSource) Name() string {
+	return s.name
+}
+
+func (s staticSource) Price(ctx context.Context) (linotypes.MiniDollar, error) {
+	if s.price == "" {
+		return linotypes.NewMiniDollar(0), errors.QueryFail("source is down")
+	}
+	return ParseUSDPrice(s.price)
+}
+
+func TestParseUSDPrice(t *testing.T) {
+	testCases := map[string]struct {
+		usd         string
+		expectPrice int64
+		expectErr   bool
+	}{
+		"testnet price":   {usd: "0.012", expectPrice: 1200},
+		"with newline":    {usd: "0.0125\n", expectPrice: 1250},
+		"sub minidollar":  {usd: "0.0000123456", expectPrice: 1},
+		"zero price":      {usd: "0", expectErr: true},
+		"negative price":  {usd: "-0.01", expectErr: true},
+		"not a number":    {usd: "n/a", expectErr: true},
+		"one dollar lino": {usd: "1", expectPrice: 100000},
+	}
+
+	for testName, tc := range testCases {
+		price, err := ParseUSDPrice(tc.usd)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err == nil && price.Int64() != tc.expectPrice {
+			t.Errorf("%s: diff price, got %v, want %v", testName, price, tc.expectPrice)
+		}
+	}
+}
+
+func TestMedianAndDeviation(t *testing.T) {
+	prices := map[string]linotypes.MiniDollar{
+		"a": linotypes.NewMiniDollar(1300),
+		"b": linotypes.NewMiniDollar(1200),
+		"c": linotypes.NewMiniDollar(1000),
+	}
+	if median := Median(prices); median.Int64() != 1200 {
+		t.Errorf("diff odd median, got %v, want 1200", median)
+	}
+	prices["d"] = linotypes.NewMiniDollar(1210)
+	if median := Median(prices); median.Int64() != 1205 {
+		t.Errorf("diff even median, got %v, want 1205", median)
+	}
+}
+
+func TestSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/price" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "0.0121")
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "feeder")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "price")
+	if err := ioutil.WriteFile(path, []byte("0.0119\n"), 0644); err != nil {
+		t.Fatalf("failed to write price file: %v", err)
+	}
+
+	testCases := map[string]struct {
+		source      Source
+		expectPrice int64
+		expectErr   bool
+	}{
+		"http source":    {source: NewHTTPSource("http", server.URL+"/price", nil), expectPrice: 1210},
+		"http not found": {source: NewHTTPSource("http", server.URL+"/missing", nil), expectErr: true},
+		"file source":    {source: NewFileSource(path), expectPrice: 1190},
+		"missing file":   {source: NewFileSource(filepath.Join(dir, "missing")), expectErr: true},
+	}
+
+	for testName, tc := range testCases {
+		price, err := tc.source.Price(context.Background())
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err == nil && price.Int64() != tc.expectPrice {
+			t.Errorf("%s: diff price, got %v, want %v", testName, price, tc.expectPrice)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	history := []pricemodel.FeedHistory{
+		{Price: linotypes.NewMiniDollar(1100)},
+		{Price: linotypes.NewMiniDollar(1250)},
+	}
+	sources := []Source{
+		staticSource{name: "a", price: "0.0120"},
+		staticSource{name: "b", price: "0.0122"},
+		staticSource{name: "c", price: "0.0500"},
+	}
+	testCases := map[string]struct {
+		sources      []Source
+		current      int64
+		lastFeed     *pricemodel.FedPrice
+		expectReason string
+		expectErr    bool
+	}{
+		"first feed": {
+			sources: sources, current: 1200, expectReason: ReasonFirstFeed,
+		},
+		"price moved": {
+			sources: sources, current: 1200, expectReason: ReasonDeviation,
+			lastFeed: &pricemodel.FedPrice{Price: linotypes.NewMiniDollar(1180), UpdateAt: now.Unix()},
+		},
+		"heartbeat due": {
+			sources: sources, current: 1200, expectReason: ReasonHeartbeat,
+			lastFeed: &pricemodel.FedPrice{Price: linotypes.NewMiniDollar(1220), UpdateAt: now.Add(-2 * time.Hour).Unix()},
+		},
+		"nothing to do": {
+			sources: sources, current: 1200, expectReason: "",
+			lastFeed: &pricemodel.FedPrice{Price: linotypes.NewMiniDollar(1225), UpdateAt: now.Add(-time.Minute).Unix()},
+		},
+		"far from current price": {
+			sources: sources, current: 900, expectErr: true,
+		},
+		"far from history": {
+			sources:   []Source{staticSource{name: "a", price: "0.02"}, staticSource{name: "b", price: "0.02"}},
+			current:   1900,
+			expectErr: true,
+		},
+		"too few sources": {
+			sources:   []Source{staticSource{name: "a"}, staticSource{name: "b"}, staticSource{name: "c", price: "0.012"}},
+			current:   1200,
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		chain := &fakeChain{
+			current:  linotypes.NewMiniDollar(tc.current),
+			history:  history,
+			lastFeed: tc.lastFeed,
+		}
+		f, err := NewFeeder(chain, tc.sources, Options{
+			Username:   "val",
+			PrivKeyHex: "key",
+			MinSources: 2,
+			Now:        func() time.Time { return now },
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to create feeder: %v", testName, err)
+		}
+
+		res, err := f.Round(context.Background())
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if tc.expectErr {
+			if len(chain.fed) != 0 {
+				t.Errorf("%s: fed %v after an error", testName, chain.fed)
+			}
+			continue
+		}
+		if res.Reason != tc.expectReason {
+			t.Errorf("%s: diff reason, got %q, want %q", testName, res.Reason, tc.expectReason)
+		}
+		if res.Median.Int64() != 1220 {
+			t.Errorf("%s: diff median, got %v, want 1220", testName, res.Median)
+		}
+		if (tc.expectReason != "") != (len(chain.fed) == 1) {
+			t.Errorf("%s: diff feeds, got %v", testName, chain.fed)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	chain := &fakeChain{current: linotypes.NewMiniDollar(1200)}
+	f, err := NewFeeder(chain, []Source{
+		staticSource{name: "a", price: "0.0120"},
+		staticSource{name: "down"},
+	}, Options{Username: "val", PrivKeyHex: "key", Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("failed to create feeder: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := f.Round(context.Background()); err != nil {
+			t.Fatalf("round %v failed: %v", i, err)
+		}
+		chain.lastFeed = &pricemodel.FedPrice{Price: chain.fed[len(chain.fed)-1], UpdateAt: now.Unix()}
+	}
+
+	metrics := f.Metrics()
+	if metrics.Rounds != 2 || metrics.Submissions != 1 || metrics.Skipped != 1 {
+		t.Errorf("diff counts, got %+v", metrics)
+	}
+	if metrics.SourceFailures["down"] != 2 || metrics.LastTxHash != "TX1" || metrics.LastPrice.Int64() != 1200 {
+		t.Errorf("diff metrics, got %+v", metrics)
+	}
+}
@@ -0,0 +1,109 @@
+package feeder
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// miniDollarPerCoinPerUSD converts a USD price of one LINO to the MiniDollar
+// price of one coin: 1 LINO is 10^5 coins and 1 USD is 10^10 MiniDollar.
+const miniDollarPerCoinPerUSD = 100000
+
+// Source is a price source, such as an exchange ticker.
+type Source interface {
+	// Name identifies the source in metrics and results.
+	Name() string
+	// Price returns the MiniDollar price of one coin, as fed to the chain.
+	Price(ctx context.Context) (linotypes.MiniDollar, error)
+}
+
+// ParseUSDPrice parses the USD price of one LINO, e.g. "0.012", into the
+// MiniDollar price of one coin.
+func ParseUSDPrice(usd string) (linotypes.MiniDollar, error) {
+	dec, err := sdk.NewDecFromStr(strings.TrimSpace(usd))
+	if err != nil {
+		return linotypes.NewMiniDollar(0), errors.InvalidArgf("invalid usd price %q", usd).AddCause(err)
+	}
+	price := dec.MulInt64(miniDollarPerCoinPerUSD).TruncateInt()
+	if !price.IsPositive() {
+		return linotypes.NewMiniDollar(0), errors.InvalidArgf("usd price %q is not positive", usd)
+	}
+	return linotypes.NewMiniDollarFromInt(price), nil
+}
+
+type fileSource struct {
+	path string
+}
+
+// NewFileSource returns a Source reading the USD price of one LINO from the
+// file at @p path, which another process keeps up to date.
+func NewFileSource(path string) Source {
+	return &fileSource{path: path}
+}
+
+func (s *fileSource) Name() string {
+	return "file:" + s.path
+}
+
+func (s *fileSource) Price(ctx context.Context) (linotypes.MiniDollar, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return linotypes.NewMiniDollar(0), errors.InvalidArgf("failed to read price file %s", s.path).AddCause(err)
+	}
+	return ParseUSDPrice(string(data))
+}
+
+type httpSource struct {
+	name   string
+	url    string
+	parse  func(body []byte) (linotypes.MiniDollar, error)
+	client *http.Client
+}
+
+// NewHTTPSource returns a Source fetching @p url with a GET request and
+// parsing the response body with @p parse. A nil @p parse expects the body
+// to be the USD price of one LINO.
+func NewHTTPSource(name, url string, parse func(body []byte) (linotypes.MiniDollar, error)) Source {
+	if parse == nil {
+		parse = func(body []byte) (linotypes.MiniDollar, error) {
+			return ParseUSDPrice(string(body))
+		}
+	}
+	return &httpSource{
+		name:   name,
+		url:    url,
+		parse:  parse,
+		client: &http.Client{},
+	}
+}
+
+func (s *httpSource) Name() string {
+	return s.name
+}
+
+func (s *httpSource) Price(ctx context.Context) (linotypes.MiniDollar, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return linotypes.NewMiniDollar(0), errors.InvalidArgf("invalid price url %s", s.url).AddCause(err)
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return linotypes.NewMiniDollar(0), errors.QueryFailf("failed to fetch price from %s", s.name).AddCause(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return linotypes.NewMiniDollar(0), errors.QueryFailf("failed to read price from %s", s.name).AddCause(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return linotypes.NewMiniDollar(0), errors.QueryFailf("price source %s returned %s", s.name, resp.Status)
+	}
+	return s.parse(body)
+}
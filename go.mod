@@ -10,4 +10,5 @@ require (
 	github.com/lino-network/lino v0.6.11
 	github.com/spf13/viper v1.4.0
 	github.com/tendermint/tendermint v0.32.6
+	github.com/tendermint/tm-db v0.2.0
 )
@@ -0,0 +1,306 @@
+// Package ida converts app IDA amounts to and from MiniDollar, USD and LINO
+// at the prices on chain, and reports how well the IDA reserve pool covers
+// the IDA outstanding.
+//
+// IDA balances are kept on chain in MiniDollar: one MiniIDA of an app is
+// worth the app's MiniIDAPrice in MiniDollar, and minting converts coins to
+// MiniDollar at the current LINO price.
+package ida
+
+import (
+	"context"
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	linotypes "github.com/lino-network/lino/types"
+	"github.com/lino-network/lino/x/developer/model"
+	"github.com/lino-network/lino/x/developer/types"
+)
+
+// Source provides the app IDA, the reserve pool and the LINO price.
+// *query.Query implements it.
+type Source interface {
+	GetIDA(ctx context.Context, app string) (*model.AppIDA, error)
+	GetIDAStats(ctx context.Context, app string) (*model.AppIDAStats, error)
+	GetIDABalance(ctx context.Context, username, app string) (*types.QueryResultIDABalance, error)
+	GetReservePool(ctx context.Context) (*model.ReservePool, error)
+	GetCurrentPrice(ctx context.Context) (linotypes.MiniDollar, error)
+}
+
+// Rates are the prices IDA of an app is converted at.
+type Rates struct {
+	App string `json:"app"`
+	// MiniIDAPrice is the MiniDollar price of one MiniIDA of the app.
+	MiniIDAPrice linotypes.MiniDollar `json:"mini_ida_price"`
+	// CoinPrice is the MiniDollar price of one coin.
+	CoinPrice linotypes.MiniDollar `json:"coin_price"`
+}
+
+// NewRates returns the rates of @p ida at @p coinPrice.
+func NewRates(ida *model.AppIDA, coinPrice linotypes.MiniDollar) (*Rates, error) {
+	if ida.IsRevoked {
+		return nil, errors.InvalidArgf("ida of %s is revoked", ida.App)
+	}
+	if !ida.MiniIDAPrice.IsPositive() {
+		return nil, errors.InvalidArgf("invalid ida price %v of %s", ida.MiniIDAPrice, ida.App)
+	}
+	if !coinPrice.IsPositive() {
+		return nil, errors.InvalidArgf("invalid price %v", coinPrice)
+	}
+	return &Rates{App: string(ida.App), MiniIDAPrice: ida.MiniIDAPrice, CoinPrice: coinPrice}, nil
+}
+
+// IDAPriceUSD returns the USD price of one IDA.
+func (r *Rates) IDAPriceUSD() amount.Amount {
+	return amount.NewFromMiniDollar(linotypes.MiniIDAToMiniDollar(sdk.NewInt(linotypes.Decimals), r.MiniIDAPrice))
+}
+
+// IDAToUSD returns the value of an IDA amount in USD.
+func (r *Rates) IDAToUSD(ida amount.Amount) (amount.Amount, error) {
+	miniIDA, err := ida.MiniIDA()
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	return amount.NewFromMiniDollar(linotypes.MiniIDAToMiniDollar(miniIDA, r.MiniIDAPrice)), nil
+}
+
+// USDToIDA returns the IDA worth a USD amount, rounded down to a MiniIDA.
+func (r *Rates) USDToIDA(usd amount.Amount) (amount.Amount, error) {
+	miniDollar, err := usd.MiniDollar()
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	return amount.NewFromMiniIDA(miniDollar.Quo(r.MiniIDAPrice.Int)), nil
+}
+
+// IDAToLINO returns the LINO an IDA amount is burned into, rounded down to a
+// coin as the chain does.
+func (r *Rates) IDAToLINO(ida amount.Amount) (amount.Amount, error) {
+	usd, err := r.IDAToUSD(ida)
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	return usd.ToLINO(r.CoinPrice)
+}
+
+// LINOToIDA returns the IDA minted from a LINO amount, rounded down to a
+// MiniIDA.
+func (r *Rates) LINOToIDA(lino amount.Amount) (amount.Amount, error) {
+	usd, err := lino.ToUSD(r.CoinPrice)
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	return r.USDToIDA(usd)
+}
+
+// MintCost returns the LINO to spend with IDAMint to get at least an IDA
+// amount, rounded up to a coin.
+func (r *Rates) MintCost(ida amount.Amount) (amount.Amount, error) {
+	usd, err := r.IDAToUSD(ida)
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	miniDollar, _ := usd.MiniDollar()
+	coins := miniDollar.Add(r.CoinPrice.Int).SubRaw(1).Quo(r.CoinPrice.Int)
+	return amount.NewFromCoin(linotypes.NewCoin(coins)), nil
+}
+
+// Coverage is how well the LINO in the IDA reserve pool covers the IDA
+// outstanding, valued at the current price.
+type Coverage struct {
+	CoinPrice linotypes.MiniDollar `json:"coin_price"`
+	// Reserve is the LINO in the reserve pool and ReserveValue its value.
+	Reserve      linotypes.Coin       `json:"reserve"`
+	ReserveValue linotypes.MiniDollar `json:"reserve_value"`
+	// Outstanding is the value of the IDA of all apps.
+	Outstanding linotypes.MiniDollar `json:"outstanding"`
+	// Ratio is ReserveValue over Outstanding, zero if nothing is outstanding.
+	Ratio sdk.Dec `json:"ratio"`
+}
+
+// AppCoverage is the share of an app in the IDA outstanding.
+type AppCoverage struct {
+	Coverage
+	App string `json:"app"`
+	// AppOutstanding is the value of the IDA of the app and AppShare its
+	// share in Outstanding.
+	AppOutstanding linotypes.MiniDollar `json:"app_outstanding"`
+	AppShare       sdk.Dec              `json:"app_share"`
+	// RedeemCost is the LINO burning all IDA of the app takes out of the
+	// reserve pool.
+	RedeemCost linotypes.Coin `json:"redeem_cost"`
+}
+
+// NewCoverage returns the coverage of @p pool at @p coinPrice.
+func NewCoverage(pool *model.ReservePool, coinPrice linotypes.MiniDollar) *Coverage {
+	value := linotypes.NewMiniDollarFromInt(pool.Total.Amount.Mul(coinPrice.Int))
+	return &Coverage{
+		CoinPrice:    coinPrice,
+		Reserve:      pool.Total,
+		ReserveValue: value,
+		Outstanding:  pool.TotalMiniDollar,
+		Ratio:        ratio(value.Int, pool.TotalMiniDollar.Int),
+	}
+}
+
+// NewAppCoverage returns the coverage of @p pool with the share of the app
+// of @p stats.
+func NewAppCoverage(
+	app string, stats *model.AppIDAStats, pool *model.ReservePool, coinPrice linotypes.MiniDollar) (*AppCoverage, error) {
+	if !coinPrice.IsPositive() {
+		return nil, errors.InvalidArgf("invalid price %v", coinPrice)
+	}
+	coverage := &AppCoverage{
+		Coverage:       *NewCoverage(pool, coinPrice),
+		App:            app,
+		AppOutstanding: stats.Total,
+		AppShare:       ratio(stats.Total.Int, pool.TotalMiniDollar.Int),
+		RedeemCost:     linotypes.NewCoin(stats.Total.Quo(coinPrice.Int)),
+	}
+	return coverage, nil
+}
+
+// Holding is the IDA of a user in an app and its value.
+type Holding struct {
+	Username string        `json:"username"`
+	App      string        `json:"app"`
+	Unauthed bool          `json:"unauthed"`
+	IDA      amount.Amount `json:"ida"`
+	USD      amount.Amount `json:"usd"`
+	LINO     amount.Amount `json:"lino"`
+}
+
+// Calculator answers IDA questions against the chain.
+type Calculator struct {
+	source Source
+}
+
+// NewCalculator returns an instance of Calculator reading from @p source.
+func NewCalculator(source Source) *Calculator {
+	return &Calculator{source: source}
+}
+
+// Rates returns the rates of the IDA of @p app at the current price.
+func (c *Calculator) Rates(ctx context.Context, app string) (*Rates, error) {
+	var ida *model.AppIDA
+	var price linotypes.MiniDollar
+	err := all(
+		func() (err error) {
+			ida, err = c.source.GetIDA(ctx, app)
+			return err
+		},
+		func() (err error) {
+			price, err = c.source.GetCurrentPrice(ctx)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return NewRates(ida, price)
+}
+
+// Coverage returns the coverage of the reserve pool with the share of @p app.
+func (c *Calculator) Coverage(ctx context.Context, app string) (*AppCoverage, error) {
+	var stats *model.AppIDAStats
+	var pool *model.ReservePool
+	var price linotypes.MiniDollar
+	err := all(
+		func() (err error) {
+			stats, err = c.source.GetIDAStats(ctx, app)
+			return err
+		},
+		func() (err error) {
+			pool, err = c.source.GetReservePool(ctx)
+			return err
+		},
+		func() (err error) {
+			price, err = c.source.GetCurrentPrice(ctx)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return NewAppCoverage(app, stats, pool, price)
+}
+
+// Holding returns the IDA of @p username in @p app and its value at the
+// current price. The chain reports balances rounded down to a MiniIDA.
+func (c *Calculator) Holding(ctx context.Context, username, app string) (*Holding, error) {
+	var rates *Rates
+	var balance *types.QueryResultIDABalance
+	err := all(
+		func() (err error) {
+			rates, err = c.Rates(ctx, app)
+			return err
+		},
+		func() (err error) {
+			balance, err = c.source.GetIDABalance(ctx, username, app)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ida, err := parseBalance(balance.Amount)
+	if err != nil {
+		return nil, err
+	}
+	usd, err := rates.IDAToUSD(ida)
+	if err != nil {
+		return nil, err
+	}
+	lino, err := rates.IDAToLINO(ida)
+	if err != nil {
+		return nil, err
+	}
+	return &Holding{
+		Username: username,
+		App:      app,
+		Unauthed: balance.Unauthed,
+		IDA:      ida,
+		USD:      usd,
+		LINO:     lino,
+	}, nil
+}
+
+// parseBalance parses the IDA amount of a balance query, a decimal with 18
+// digits.
+func parseBalance(s string) (amount.Amount, error) {
+	dec, err := sdk.NewDecFromStr(s)
+	if err != nil {
+		return amount.Amount{}, errors.InvalidArgf("invalid ida balance %q", s).AddCause(err)
+	}
+	return amount.NewFromMiniIDA(dec.MulInt64(linotypes.Decimals).TruncateInt()), nil
+}
+
+func ratio(num, denom sdk.Int) sdk.Dec {
+	if !denom.IsPositive() {
+		return sdk.ZeroDec()
+	}
+	return num.ToDec().QuoInt(denom)
+}
+
+// all runs @p fs concurrently and returns the first error.
+func all(fs ...func() error) error {
+	errs := make([]error, len(fs))
+	var wg sync.WaitGroup
+	for i, f := range fs {
+		wg.Add(1)
+		go func(i int, f func() error) {
+			defer wg.Done()
+			errs[i] = f()
+		}(i, f)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
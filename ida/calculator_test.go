@@ -0,0 +1,175 @@
+package ida
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	linotypes "github.com/lino-network/lino/types"
+	"github.com/lino-network/lino/x/developer/model"
+	"github.com/lino-network/lino/x/developer/types"
+)
+
+type fakeSource struct {
+	ida      *model.AppIDA
+	stats    *model.AppIDAStats
+	balances map[string]string
+	pool     *model.ReservePool
+	price    linotypes.MiniDollar
+}
+
+func (s *fakeSource) GetIDA(ctx context.Context, app string) (*model.AppIDA, error) {
+	return s.ida, nil
+}
+
+func (s *fakeSource) GetIDAStats(ctx context.Context, app string) (*model.AppIDAStats, error) {
+	return s.stats, nil
+}
+
+func (s *fakeSource) GetIDABalance(ctx context.Context, username, app string) (*types.QueryResultIDABalance, error) {
+	balance, ok := s.balances[username]
+	if !ok {
+		return nil, errors.EmptyResponse("ida bank is not found")
+	}
+	return &types.QueryResultIDABalance{Amount: balance}, nil
+}
+
+func (s *fakeSource) GetReservePool(ctx context.Context) (*model.ReservePool, error) {
+	return s.pool, nil
+}
+
+func (s *fakeSource) GetCurrentPrice(ctx context.Context) (linotypes.MiniDollar, error) {
+	return s.price, nil
+}
+
+// newFakeSource returns an app IDA worth 0.01 USD at a LINO price of 0.012 USD.
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		ida:      &model.AppIDA{App: "app", MiniIDAPrice: linotypes.NewMiniDollar(1000)},
+		stats:    &model.AppIDAStats{Total: linotypes.NewMiniDollar(25000000000)},
+		balances: map[string]string{"user": "12.345670000000000000"},
+		pool: &model.ReservePool{
+			Total:           linotypes.MustLinoToCoin("1000"),
+			TotalMiniDollar: linotypes.NewMiniDollar(100000000000),
+		},
+		price: linotypes.NewMiniDollar(1200),
+	}
+}
+
+func TestRates(t *testing.T) {
+	rates, err := NewCalculator(newFakeSource()).Rates(context.Background(), "app")
+	if err != nil {
+		t.Fatalf("failed to get rates: %v", err)
+	}
+	if usd := rates.IDAPriceUSD().String(); usd != "0.01" {
+		t.Errorf("diff ida price, got %v, want 0.01", usd)
+	}
+
+	testCases := map[string]struct {
+		convert   func(amount.Amount) (amount.Amount, error)
+		in        amount.Amount
+		expectOut string
+		expectErr bool
+	}{
+		"ida to usd":          {convert: rates.IDAToUSD, in: amount.MustParse("100", amount.IDA), expectOut: "1"},
+		"usd to ida":          {convert: rates.USDToIDA, in: amount.MustParse("1", amount.USD), expectOut: "100"},
+		"usd to ida rounding": {convert: rates.USDToIDA, in: amount.MustParse("0.0000000999", amount.USD), expectOut: "0"},
+		"ida to lino":         {convert: rates.IDAToLINO, in: amount.MustParse("100", amount.IDA), expectOut: "83.33333"},
+		"lino to ida":         {convert: rates.LINOToIDA, in: amount.MustParse("83.33333", amount.LINO), expectOut: "99.99999"},
+		"mint cost":           {convert: rates.MintCost, in: amount.MustParse("100", amount.IDA), expectOut: "83.33334"},
+		"exact mint cost":     {convert: rates.MintCost, in: amount.MustParse("1.2", amount.IDA), expectOut: "1"},
+		"wrong unit":          {convert: rates.IDAToUSD, in: amount.MustParse("1", amount.LINO), expectErr: true},
+	}
+
+	for testName, tc := range testCases {
+		out, err := tc.convert(tc.in)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err == nil && out.String() != tc.expectOut {
+			t.Errorf("%s: diff amount, got %v, want %v", testName, out, tc.expectOut)
+		}
+	}
+}
+
+func TestNewRates(t *testing.T) {
+	price := linotypes.NewMiniDollar(1200)
+	testCases := map[string]struct {
+		ida       *model.AppIDA
+		price     linotypes.MiniDollar
+		expectErr bool
+	}{
+		"valid":         {ida: &model.AppIDA{MiniIDAPrice: linotypes.NewMiniDollar(1000)}, price: price},
+		"revoked":       {ida: &model.AppIDA{MiniIDAPrice: linotypes.NewMiniDollar(1000), IsRevoked: true}, price: price, expectErr: true},
+		"no ida price":  {ida: &model.AppIDA{MiniIDAPrice: linotypes.NewMiniDollar(0)}, price: price, expectErr: true},
+		"no coin price": {ida: &model.AppIDA{MiniIDAPrice: linotypes.NewMiniDollar(1000)}, price: linotypes.NewMiniDollar(0), expectErr: true},
+	}
+
+	for testName, tc := range testCases {
+		if _, err := NewRates(tc.ida, tc.price); (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	coverage, err := NewCalculator(newFakeSource()).Coverage(context.Background(), "app")
+	if err != nil {
+		t.Fatalf("failed to get coverage: %v", err)
+	}
+	if !coverage.ReserveValue.Equal(linotypes.NewMiniDollar(120000000000)) {
+		t.Errorf("diff reserve value, got %v", coverage.ReserveValue)
+	}
+	if !coverage.Ratio.Equal(sdk.MustNewDecFromStr("1.2")) {
+		t.Errorf("diff ratio, got %v, want 1.2", coverage.Ratio)
+	}
+	if !coverage.AppShare.Equal(sdk.MustNewDecFromStr("0.25")) {
+		t.Errorf("diff app share, got %v, want 0.25", coverage.AppShare)
+	}
+	if !coverage.RedeemCost.IsEqual(linotypes.NewCoinFromInt64(20833333)) {
+		t.Errorf("diff redeem cost, got %v, want 20833333", coverage.RedeemCost)
+	}
+
+	empty := NewCoverage(&model.ReservePool{
+		Total:           linotypes.NewCoinFromInt64(0),
+		TotalMiniDollar: linotypes.NewMiniDollar(0),
+	}, linotypes.NewMiniDollar(1200))
+	if !empty.Ratio.IsZero() {
+		t.Errorf("diff empty ratio, got %v, want 0", empty.Ratio)
+	}
+}
+
+func TestHolding(t *testing.T) {
+	calculator := NewCalculator(newFakeSource())
+	holding, err := calculator.Holding(context.Background(), "user", "app")
+	if err != nil {
+		t.Fatalf("failed to get holding: %v", err)
+	}
+	if holding.IDA.String() != "12.34567" || holding.USD.String() != "0.1234567" || holding.LINO.String() != "10.28805" {
+		t.Errorf("diff holding, got %v IDA, %v USD, %v LINO", holding.IDA, holding.USD, holding.LINO)
+	}
+
+	b, err := json.Marshal(holding)
+	if err != nil {
+		t.Fatalf("failed to marshal holding: %v", err)
+	}
+	var parsed Holding
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatalf("failed to unmarshal holding %s: %v", b, err)
+	}
+	if parsed.Username != holding.Username || parsed.App != holding.App || parsed.Unauthed != holding.Unauthed ||
+		parsed.IDA.Unit() != amount.IDA || !parsed.IDA.Equal(holding.IDA) ||
+		parsed.USD.Unit() != amount.USD || !parsed.USD.Equal(holding.USD) ||
+		parsed.LINO.Unit() != amount.LINO || !parsed.LINO.Equal(holding.LINO) {
+		t.Errorf("diff holding after json round trip, got %+v, want %+v", parsed, holding)
+	}
+
+	_, err = calculator.Holding(context.Background(), "nobody", "app")
+	if !errors.IsEmptyResponse(err) {
+		t.Errorf("diff err of missing bank, got %v", err)
+	}
+}
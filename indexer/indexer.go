@@ -0,0 +1,297 @@
+// Package indexer builds a local index of account history from decoded
+// blocks: ledger entries, donations, posts and stake events that the
+// chain's queriers don't provide, stored in an embedded database.
+//
+// Ledger entries are the transfers msgs carry, not balance changes. The
+// bandwidth fee the chain charges signers that are not apps depends on the
+// msg fee of the block, which blocks don't carry, so it is not recorded and
+// a ledger summary does not reconcile with a saving balance.
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/scanner"
+	linotypes "github.com/lino-network/lino/types"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	devtypes "github.com/lino-network/lino/x/developer/types"
+	posttypes "github.com/lino-network/lino/x/post/types"
+	votetypes "github.com/lino-network/lino/x/vote/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+var (
+	cursorKey          = []byte("cursor")
+	ledgerPrefix       = "l/"
+	donationToPrefix   = "dt/"
+	donationFromPrefix = "df/"
+	postPrefix         = "p/"
+	postEventPrefix    = "pe/"
+	stakeEventPrefix   = "s/"
+	heightKeyFormat    = "%020d"
+	positionKeyFormat  = heightKeyFormat + "/%010d/%04d"
+)
+
+// Indexer writes decoded blocks into an embedded database and answers
+// history queries from it. It is also the scanner.Checkpoint of the blocks
+// it indexes; the cursor is written in the same batch as the records of a
+// block, so a restart never indexes a block twice.
+type Indexer struct {
+	db dbm.DB
+}
+
+var _ scanner.Checkpoint = &Indexer{}
+
+// NewIndexer returns an instance of Indexer storing into @p db, e.g. a
+// dbm.NewGoLevelDB for persistence or a dbm.NewMemDB for tests.
+func NewIndexer(db dbm.DB) *Indexer {
+	return &Indexer{db: db}
+}
+
+// Run indexes blocks of @p source until opts.EndHeight, or until the context
+// is done when following the tip.
+func (idx *Indexer) Run(ctx context.Context, source scanner.BlockSource, opts scanner.Options) error {
+	return scanner.NewScanner(source, idx, opts).Run(ctx, idx.HandleBlock)
+}
+
+// Load implements scanner.Checkpoint.
+func (idx *Indexer) Load() (int64, error) {
+	value := idx.db.Get(cursorKey)
+	if value == nil {
+		return 0, nil
+	}
+	height, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0, errors.UnmarshaFailed("invalid indexer cursor").AddCause(err)
+	}
+	return height, nil
+}
+
+// Save implements scanner.Checkpoint.
+func (idx *Indexer) Save(height int64) error {
+	idx.db.SetSync(cursorKey, []byte(strconv.FormatInt(height, 10)))
+	return nil
+}
+
+// HandleBlock indexes the msgs of successful txs in @p block. It is the
+// scanner.Handler used by Run.
+func (idx *Indexer) HandleBlock(ctx context.Context, block *scanner.Block) error {
+	batch := idx.db.NewBatch()
+	defer batch.Close()
+
+	w := &blockWriter{idx: idx, batch: batch, posts: map[string]*Post{}}
+	for _, tx := range block.Txs {
+		if tx.DecodeErr != nil || tx.Code != 0 {
+			continue
+		}
+		for i, msg := range tx.Tx.Msgs {
+			pos := Position{
+				Height:   block.Height,
+				Time:     block.Time,
+				TxHash:   tx.Hash,
+				TxIndex:  tx.Index,
+				MsgIndex: i,
+			}
+			if err := w.indexMsg(pos, msg); err != nil {
+				return err
+			}
+		}
+	}
+	for key, post := range w.posts {
+		if err := w.set(key, post); err != nil {
+			return err
+		}
+	}
+
+	batch.Set(cursorKey, []byte(strconv.FormatInt(block.Height+1, 10)))
+	batch.WriteSync()
+	return nil
+}
+
+// blockWriter collects the records of one block into a batch.
+type blockWriter struct {
+	idx   *Indexer
+	batch dbm.Batch
+	posts map[string]*Post
+}
+
+func (w *blockWriter) indexMsg(pos Position, msg sdk.Msg) error {
+	switch msg := msg.(type) {
+	case acctypes.TransferMsg:
+		return w.transfer(pos, string(msg.Sender), string(msg.Receiver), msg.Amount, msg.Memo)
+	case acctypes.TransferV2Msg:
+		return w.transfer(pos, msg.Sender.String(), msg.Receiver.String(), msg.Amount, msg.Memo)
+	case acctypes.RegisterV2Msg:
+		return w.ledger(pos, msg.Referrer.String(), string(msg.NewUser), EntryRegisterFee, msg.RegisterFee, true, "")
+	case posttypes.DonateMsg:
+		if err := w.ledger(pos, string(msg.Username), string(msg.Author), EntryDonateOut, msg.Amount, true, msg.Memo); err != nil {
+			return err
+		}
+		if err := w.ledger(pos, string(msg.Author), string(msg.Username), EntryDonationIn, msg.Amount, false, msg.Memo); err != nil {
+			return err
+		}
+		return w.donation(pos, &Donation{
+			From:   string(msg.Username),
+			Author: string(msg.Author),
+			PostID: msg.PostID,
+			App:    string(msg.FromApp),
+			Amount: string(msg.Amount),
+			Memo:   msg.Memo,
+		})
+	case posttypes.IDADonateMsg:
+		return w.donation(pos, &Donation{
+			From:   string(msg.Username),
+			Author: string(msg.Author),
+			PostID: msg.PostID,
+			App:    string(msg.App),
+			Amount: string(msg.Amount),
+			IsIDA:  true,
+			Memo:   msg.Memo,
+		})
+	case posttypes.CreatePostMsg:
+		return w.post(pos, string(msg.Author), msg.PostID, PostCreated, func(post *Post) {
+			*post = Post{
+				Author:    string(msg.Author),
+				PostID:    msg.PostID,
+				Title:     msg.Title,
+				CreatedBy: string(msg.CreatedBy),
+				CreatedAt: pos.Height,
+				UpdatedAt: pos.Height,
+				Time:      pos.Time,
+			}
+		})
+	case posttypes.UpdatePostMsg:
+		return w.post(pos, string(msg.Author), msg.PostID, PostUpdated, func(post *Post) {
+			post.Title = msg.Title
+			post.UpdatedAt = pos.Height
+		})
+	case posttypes.DeletePostMsg:
+		return w.post(pos, string(msg.Author), msg.PostID, PostDeleted, func(post *Post) {
+			post.IsDeleted = true
+			post.UpdatedAt = pos.Height
+		})
+	case votetypes.StakeInMsg:
+		if err := w.ledger(pos, string(msg.Username), "", EntryStakeIn, msg.Deposit, true, ""); err != nil {
+			return err
+		}
+		return w.stake(pos, string(msg.Username), "", StakeIn, msg.Deposit)
+	case votetypes.StakeInForMsg:
+		if err := w.ledger(pos, string(msg.Sender), string(msg.Receiver), EntryStakeInFor, msg.Deposit, true, ""); err != nil {
+			return err
+		}
+		if err := w.stake(pos, string(msg.Sender), string(msg.Receiver), StakeInFor, msg.Deposit); err != nil {
+			return err
+		}
+		return w.stake(pos, string(msg.Receiver), string(msg.Sender), StakeInReceived, msg.Deposit)
+	case votetypes.StakeOutMsg:
+		return w.stake(pos, string(msg.Username), "", StakeOut, msg.Amount)
+	case votetypes.ClaimInterestMsg:
+		return w.stake(pos, string(msg.Username), "", ClaimInterest, "")
+	case devtypes.IDAConvertFromLinoMsg:
+		return w.ledger(pos, string(msg.Username), string(msg.App), EntryIDAConversion, msg.Amount, true, "")
+	case devtypes.IDAMintMsg:
+		return w.ledger(pos, string(msg.Username), "", EntryIDAMint, msg.Amount, true, "")
+	}
+	return nil
+}
+
+func (w *blockWriter) transfer(pos Position, from, to string, amount linotypes.LNO, memo string) error {
+	if err := w.ledger(pos, from, to, EntryTransferOut, amount, true, memo); err != nil {
+		return err
+	}
+	return w.ledger(pos, to, from, EntryTransferIn, amount, false, memo)
+}
+
+func (w *blockWriter) ledger(
+	pos Position, account, counterparty string, kind EntryKind, amount linotypes.LNO, debit bool, memo string) error {
+	coin, err := parseLNO(pos, amount)
+	if err != nil {
+		return err
+	}
+	if debit {
+		coin = coin.Neg()
+	}
+	entry := &LedgerEntry{
+		Position:     pos,
+		Account:      account,
+		Counterparty: counterparty,
+		Kind:         kind,
+		Amount:       coin,
+		Memo:         memo,
+	}
+	return w.set(ledgerPrefix+account+"/"+positionKey(pos)+"/"+string(kind), entry)
+}
+
+func (w *blockWriter) donation(pos Position, donation *Donation) error {
+	donation.Position = pos
+	if err := w.set(donationToPrefix+donation.Author+"/"+positionKey(pos), donation); err != nil {
+		return err
+	}
+	return w.set(donationFromPrefix+donation.From+"/"+positionKey(pos), donation)
+}
+
+func (w *blockWriter) post(pos Position, author, postID string, kind PostEventKind, apply func(post *Post)) error {
+	key := postKey(author, postID)
+	post, ok := w.posts[key]
+	if !ok {
+		post = &Post{Author: author, PostID: postID}
+		if value := w.idx.db.Get([]byte(key)); value != nil {
+			if err := json.Unmarshal(value, post); err != nil {
+				return errors.UnmarshaFailed("failed to decode post").AddCause(err)
+			}
+		}
+		w.posts[key] = post
+	}
+	apply(post)
+
+	event := &PostEvent{Position: pos, Author: author, PostID: postID, Kind: kind}
+	return w.set(postEventPrefix+author+"/"+positionKey(pos), event)
+}
+
+func (w *blockWriter) stake(pos Position, account, counterparty string, kind StakeKind, amount linotypes.LNO) error {
+	coin := linotypes.NewCoinFromInt64(0)
+	if amount != "" {
+		var err error
+		if coin, err = parseLNO(pos, amount); err != nil {
+			return err
+		}
+	}
+	event := &StakeEvent{
+		Position:     pos,
+		Account:      account,
+		Counterparty: counterparty,
+		Kind:         kind,
+		Amount:       coin,
+	}
+	return w.set(stakeEventPrefix+account+"/"+positionKey(pos)+"/"+string(kind), event)
+}
+
+func (w *blockWriter) set(key string, value interface{}) error {
+	bz, err := json.Marshal(value)
+	if err != nil {
+		return errors.InvalidArgf("failed to encode %s", key).AddCause(err)
+	}
+	w.batch.Set([]byte(key), bz)
+	return nil
+}
+
+func parseLNO(pos Position, amount linotypes.LNO) (linotypes.Coin, error) {
+	coin, err := linotypes.LinoToCoin(amount)
+	if err != nil {
+		return coin, errors.InvalidArgf("invalid amount %s at height %v", amount, pos.Height).AddCause(err)
+	}
+	return coin, nil
+}
+
+func positionKey(pos Position) string {
+	return fmt.Sprintf(positionKeyFormat, pos.Height, pos.TxIndex, pos.MsgIndex)
+}
+
+func postKey(author, postID string) string {
+	return postPrefix + author + "/" + postID
+}
@@ -0,0 +1,156 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lino-network/lino-go/scanner"
+	linotypes "github.com/lino-network/lino/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// testdata/chain.json records five blocks between alice, bob and carol,
+// including a failed transfer and an undecodable tx.
+func newTestIndexer(t *testing.T) *Indexer {
+	fixture, err := scanner.LoadFixture("testdata/chain.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	idx := NewIndexer(dbm.NewMemDB())
+	if err := idx.Run(context.Background(), fixture, scanner.Options{EndHeight: 5}); err != nil {
+		t.Fatalf("failed to index fixture: %v", err)
+	}
+	return idx
+}
+
+func TestLedger(t *testing.T) {
+	idx := newTestIndexer(t)
+
+	testCases := map[string]struct {
+		username    string
+		fromHeight  int64
+		toHeight    int64
+		expectKinds []EntryKind
+		expectNet   int64
+	}{
+		"alice pays fee, transfers, donates and stakes for bob": {
+			username:    "alice",
+			fromHeight:  1,
+			toHeight:    5,
+			expectKinds: []EntryKind{EntryRegisterFee, EntryTransferOut, EntryDonateOut, EntryStakeInFor, EntryTransferIn},
+			expectNet:   -15,
+		},
+		"failed transfer from carol is not credited to bob": {
+			username:    "bob",
+			fromHeight:  1,
+			toHeight:    5,
+			expectKinds: []EntryKind{EntryTransferIn, EntryDonationIn, EntryStakeIn, EntryTransferOut},
+			expectNet:   10,
+		},
+		"height range is inclusive": {
+			username:    "bob",
+			fromHeight:  2,
+			toHeight:    3,
+			expectKinds: []EntryKind{EntryDonationIn, EntryStakeIn},
+			expectNet:   2,
+		},
+	}
+
+	for testName, tc := range testCases {
+		entries, err := idx.LedgerFromTo(tc.username, tc.fromHeight, tc.toHeight)
+		if err != nil {
+			t.Errorf("%s: failed to get ledger, got err %v", testName, err)
+			continue
+		}
+		if len(entries) != len(tc.expectKinds) {
+			t.Errorf("%s: diff number of entries, got %d, want %d", testName, len(entries), len(tc.expectKinds))
+			continue
+		}
+		for i, entry := range entries {
+			if entry.Kind != tc.expectKinds[i] {
+				t.Errorf("%s: diff kind of entry %d, got %v, want %v", testName, i, entry.Kind, tc.expectKinds[i])
+			}
+		}
+
+		summary, err := idx.LedgerSummary(tc.username, tc.fromHeight, tc.toHeight)
+		if err != nil {
+			t.Errorf("%s: failed to get summary, got err %v", testName, err)
+		} else if !summary.Net.IsEqual(linotypes.NewCoinFromInt64(tc.expectNet * linotypes.Decimals)) {
+			t.Errorf("%s: diff net, got %v, want %v LNO", testName, summary.Net, tc.expectNet)
+		}
+	}
+}
+
+func TestRecentLedger(t *testing.T) {
+	idx := newTestIndexer(t)
+
+	if _, err := idx.RecentLedger("bob", 0); err == nil {
+		t.Errorf("RecentLedger should return InvalidArg err")
+	}
+	if _, err := idx.LedgerFromTo("bob", 3, 2); err == nil {
+		t.Errorf("LedgerFromTo should return InvalidArg err")
+	}
+
+	entries, err := idx.RecentLedger("bob", 2)
+	if err != nil {
+		t.Fatalf("failed to get recent ledger: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Memo != "back" || entries[1].Kind != EntryStakeIn {
+		t.Errorf("RecentLedger got non-ordered resp, got %+v", entries)
+	}
+}
+
+func TestDonationsAndPosts(t *testing.T) {
+	idx := newTestIndexer(t)
+
+	summary, err := idx.DonationSummary("bob", "p1", 1, 5)
+	if err != nil {
+		t.Fatalf("failed to get donation summary: %v", err)
+	}
+	if summary.Count != 2 || !summary.Lino.IsEqual(linotypes.NewCoinFromInt64(5*linotypes.Decimals)) {
+		t.Errorf("diff donation summary, got %+v", summary)
+	}
+	if ida, ok := summary.IDA["dlive"]; !ok || ida.Int64() != 250000 {
+		t.Errorf("diff ida donations, got %v", summary.IDA)
+	}
+
+	donations, err := idx.DonationsFrom("carol", 1, 5)
+	if err != nil || len(donations) != 1 || !donations[0].IsIDA {
+		t.Errorf("diff donations from carol, got %+v, err %v", donations, err)
+	}
+
+	post, err := idx.GetPost("bob", "p1")
+	if err != nil {
+		t.Fatalf("failed to get post: %v", err)
+	}
+	if post.Title != "first, edited" || !post.IsDeleted || post.CreatedAt != 2 || post.UpdatedAt != 5 {
+		t.Errorf("diff post, got %+v", post)
+	}
+
+	events, err := idx.PostActivity("bob", 1, 5)
+	if err != nil || len(events) != 3 || events[0].Kind != PostCreated || events[2].Kind != PostDeleted {
+		t.Errorf("diff post activity, got %+v, err %v", events, err)
+	}
+}
+
+func TestStakeEventsAndCursor(t *testing.T) {
+	idx := newTestIndexer(t)
+
+	events, err := idx.StakeEvents("bob", 1, 5)
+	if err != nil {
+		t.Fatalf("failed to get stake events: %v", err)
+	}
+	expectKinds := []StakeKind{StakeIn, StakeInReceived, ClaimInterest, StakeOut}
+	if len(events) != len(expectKinds) {
+		t.Fatalf("diff number of stake events, got %+v", events)
+	}
+	for i, event := range events {
+		if event.Kind != expectKinds[i] {
+			t.Errorf("diff kind of stake event %d, got %v, want %v", i, event.Kind, expectKinds[i])
+		}
+	}
+
+	if height, err := idx.Load(); err != nil || height != 6 {
+		t.Errorf("diff cursor, got %d, err %v, want 6", height, err)
+	}
+}
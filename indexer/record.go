@@ -0,0 +1,129 @@
+package indexer
+
+import (
+	"time"
+
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// EntryKind is the kind of a ledger entry.
+type EntryKind string
+
+// Ledger entry kinds.
+const (
+	EntryTransferIn    EntryKind = "transfer_in"
+	EntryTransferOut   EntryKind = "transfer_out"
+	EntryDonateOut     EntryKind = "donate_out"
+	EntryDonationIn    EntryKind = "donation_in"
+	EntryRegisterFee   EntryKind = "register_fee"
+	EntryStakeIn       EntryKind = "stake_in"
+	EntryStakeInFor    EntryKind = "stake_in_for"
+	EntryIDAConversion EntryKind = "ida_conversion"
+	EntryIDAMint       EntryKind = "ida_mint"
+)
+
+// StakeKind is the kind of a stake event.
+type StakeKind string
+
+// Stake event kinds.
+const (
+	StakeIn         StakeKind = "stake_in"
+	StakeInFor      StakeKind = "stake_in_for"
+	StakeInReceived StakeKind = "stake_in_received"
+	StakeOut        StakeKind = "stake_out"
+	ClaimInterest   StakeKind = "claim_interest"
+)
+
+// PostEventKind is the kind of a post event.
+type PostEventKind string
+
+// Post event kinds.
+const (
+	PostCreated PostEventKind = "created"
+	PostUpdated PostEventKind = "updated"
+	PostDeleted PostEventKind = "deleted"
+)
+
+// Position locates a msg on chain.
+type Position struct {
+	Height   int64     `json:"height"`
+	Time     time.Time `json:"time"`
+	TxHash   string    `json:"tx_hash"`
+	TxIndex  uint32    `json:"tx_index"`
+	MsgIndex int       `json:"msg_index"`
+}
+
+// LedgerEntry is a LINO amount a msg moved in or out of an account's saving.
+// Amount is negative for debits. Movements that happen without a msg
+// (interest, inflation, frozen money returns, donation friction) and the
+// bandwidth fees of msgs are not recorded, so donations are credited to the
+// author gross.
+type LedgerEntry struct {
+	Position
+	Account      string         `json:"account"`
+	Counterparty string         `json:"counterparty"`
+	Kind         EntryKind      `json:"kind"`
+	Amount       linotypes.Coin `json:"amount"`
+	Memo         string         `json:"memo"`
+}
+
+// Donation is a LINO or IDA donation to a post. IDA donations have App set
+// and Amount in IDA.
+type Donation struct {
+	Position
+	From   string `json:"from"`
+	Author string `json:"author"`
+	PostID string `json:"post_id"`
+	App    string `json:"app"`
+	Amount string `json:"amount"`
+	IsIDA  bool   `json:"is_ida"`
+	Memo   string `json:"memo"`
+}
+
+// Post is the latest indexed state of a post.
+type Post struct {
+	Author    string    `json:"author"`
+	PostID    string    `json:"post_id"`
+	Title     string    `json:"title"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt int64     `json:"created_at"`
+	UpdatedAt int64     `json:"updated_at"`
+	Time      time.Time `json:"time"`
+	IsDeleted bool      `json:"is_deleted"`
+}
+
+// PostEvent is a create, update or delete of a post.
+type PostEvent struct {
+	Position
+	Author string        `json:"author"`
+	PostID string        `json:"post_id"`
+	Kind   PostEventKind `json:"kind"`
+}
+
+// StakeEvent is a stake change of an account. ClaimInterest events carry no
+// amount since the claimed interest is not part of the msg.
+type StakeEvent struct {
+	Position
+	Account      string         `json:"account"`
+	Counterparty string         `json:"counterparty"`
+	Kind         StakeKind      `json:"kind"`
+	Amount       linotypes.Coin `json:"amount"`
+}
+
+// LedgerSummary aggregates the ledger entries of an account in a height range.
+// Net is the sum of the entries, not the change of the saving balance.
+type LedgerSummary struct {
+	Account string         `json:"account"`
+	Credit  linotypes.Coin `json:"credit"`
+	Debit   linotypes.Coin `json:"debit"`
+	Net     linotypes.Coin `json:"net"`
+	Count   int            `json:"count"`
+}
+
+// DonationSummary aggregates donations in a height range. IDA totals are in
+// MiniIDA, keyed by app.
+type DonationSummary struct {
+	Count int                          `json:"count"`
+	Lino  linotypes.Coin               `json:"lino"`
+	IDA   map[string]linotypes.MiniIDA `json:"ida"`
+}
@@ -0,0 +1,239 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lino-network/lino-go/errors"
+	linotypes "github.com/lino-network/lino/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// LedgerFromTo returns the ledger entries of @p username between
+// @p fromHeight and @p toHeight inclusive, oldest first.
+func (idx *Indexer) LedgerFromTo(username string, fromHeight, toHeight int64) ([]*LedgerEntry, error) {
+	res := []*LedgerEntry{}
+	err := idx.iterateRange(ledgerPrefix+username+"/", fromHeight, toHeight, func(value []byte) error {
+		entry := &LedgerEntry{}
+		res = append(res, entry)
+		return json.Unmarshal(value, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RecentLedger returns the latest @p numEntries ledger entries of
+// @p username, newest first.
+func (idx *Indexer) RecentLedger(username string, numEntries int) ([]*LedgerEntry, error) {
+	res := []*LedgerEntry{}
+	err := idx.iterateRecent(ledgerPrefix+username+"/", numEntries, func(value []byte) error {
+		entry := &LedgerEntry{}
+		res = append(res, entry)
+		return json.Unmarshal(value, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// LedgerSummary aggregates the ledger entries of @p username between
+// @p fromHeight and @p toHeight inclusive.
+func (idx *Indexer) LedgerSummary(username string, fromHeight, toHeight int64) (*LedgerSummary, error) {
+	entries, err := idx.LedgerFromTo(username, fromHeight, toHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &LedgerSummary{
+		Account: username,
+		Credit:  linotypes.NewCoinFromInt64(0),
+		Debit:   linotypes.NewCoinFromInt64(0),
+		Net:     linotypes.NewCoinFromInt64(0),
+		Count:   len(entries),
+	}
+	for _, entry := range entries {
+		if entry.Amount.IsNegative() {
+			summary.Debit = summary.Debit.Minus(entry.Amount)
+		} else {
+			summary.Credit = summary.Credit.Plus(entry.Amount)
+		}
+		summary.Net = summary.Net.Plus(entry.Amount)
+	}
+	return summary, nil
+}
+
+// DonationsTo returns donations received by posts of @p author between
+// @p fromHeight and @p toHeight inclusive, oldest first. A non-empty
+// @p postID limits them to one post.
+func (idx *Indexer) DonationsTo(author, postID string, fromHeight, toHeight int64) ([]*Donation, error) {
+	res := []*Donation{}
+	err := idx.iterateRange(donationToPrefix+author+"/", fromHeight, toHeight, func(value []byte) error {
+		donation := &Donation{}
+		if err := json.Unmarshal(value, donation); err != nil {
+			return err
+		}
+		if postID == "" || donation.PostID == postID {
+			res = append(res, donation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DonationsFrom returns donations made by @p username between @p fromHeight
+// and @p toHeight inclusive, oldest first.
+func (idx *Indexer) DonationsFrom(username string, fromHeight, toHeight int64) ([]*Donation, error) {
+	res := []*Donation{}
+	err := idx.iterateRange(donationFromPrefix+username+"/", fromHeight, toHeight, func(value []byte) error {
+		donation := &Donation{}
+		res = append(res, donation)
+		return json.Unmarshal(value, donation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DonationSummary aggregates donations received by posts of @p author,
+// or by one post if @p postID is not empty.
+func (idx *Indexer) DonationSummary(author, postID string, fromHeight, toHeight int64) (*DonationSummary, error) {
+	donations, err := idx.DonationsTo(author, postID, fromHeight, toHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &DonationSummary{
+		Count: len(donations),
+		Lino:  linotypes.NewCoinFromInt64(0),
+		IDA:   map[string]linotypes.MiniIDA{},
+	}
+	for _, donation := range donations {
+		if donation.IsIDA {
+			amount, err := linotypes.IDAStr(donation.Amount).ToMiniIDA()
+			if err != nil {
+				return nil, errors.UnmarshaFailed("invalid ida amount").AddCause(err)
+			}
+			if total, ok := summary.IDA[donation.App]; ok {
+				amount = total.Add(amount)
+			}
+			summary.IDA[donation.App] = amount
+			continue
+		}
+		coin, err := linotypes.LinoToCoin(linotypes.LNO(donation.Amount))
+		if err != nil {
+			return nil, errors.UnmarshaFailed("invalid donation amount").AddCause(err)
+		}
+		summary.Lino = summary.Lino.Plus(coin)
+	}
+	return summary, nil
+}
+
+// GetPost returns the indexed state of a post.
+func (idx *Indexer) GetPost(author, postID string) (*Post, error) {
+	value := idx.db.Get([]byte(postKey(author, postID)))
+	if value == nil {
+		return nil, errors.EmptyResponsef("post %s/%s is not found", author, postID)
+	}
+	post := &Post{}
+	if err := json.Unmarshal(value, post); err != nil {
+		return nil, errors.UnmarshaFailed("failed to decode post").AddCause(err)
+	}
+	return post, nil
+}
+
+// GetPosts returns all indexed posts of @p author, including deleted ones.
+func (idx *Indexer) GetPosts(author string) ([]*Post, error) {
+	prefix := []byte(postPrefix + author + "/")
+	iter := dbm.IteratePrefix(idx.db, prefix)
+	defer iter.Close()
+
+	res := []*Post{}
+	for ; iter.Valid(); iter.Next() {
+		post := &Post{}
+		if err := json.Unmarshal(iter.Value(), post); err != nil {
+			return nil, errors.UnmarshaFailed("failed to decode post").AddCause(err)
+		}
+		res = append(res, post)
+	}
+	return res, nil
+}
+
+// PostActivity returns post events of @p author between @p fromHeight and
+// @p toHeight inclusive, oldest first.
+func (idx *Indexer) PostActivity(author string, fromHeight, toHeight int64) ([]*PostEvent, error) {
+	res := []*PostEvent{}
+	err := idx.iterateRange(postEventPrefix+author+"/", fromHeight, toHeight, func(value []byte) error {
+		event := &PostEvent{}
+		res = append(res, event)
+		return json.Unmarshal(value, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// StakeEvents returns stake events of @p username between @p fromHeight and
+// @p toHeight inclusive, oldest first.
+func (idx *Indexer) StakeEvents(username string, fromHeight, toHeight int64) ([]*StakeEvent, error) {
+	res := []*StakeEvent{}
+	err := idx.iterateRange(stakeEventPrefix+username+"/", fromHeight, toHeight, func(value []byte) error {
+		event := &StakeEvent{}
+		res = append(res, event)
+		return json.Unmarshal(value, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (idx *Indexer) iterateRange(prefix string, fromHeight, toHeight int64, fn func(value []byte) error) error {
+	if fromHeight <= 0 || toHeight < fromHeight {
+		return errors.InvalidArgf("invalid height range [%v, %v]", fromHeight, toHeight)
+	}
+
+	start := []byte(prefix + fmt.Sprintf(heightKeyFormat, fromHeight))
+	end := []byte(prefix + fmt.Sprintf(heightKeyFormat, toHeight+1))
+	iter := idx.db.Iterator(start, end)
+	defer iter.Close()
+
+	for ; iter.Valid(); iter.Next() {
+		if err := fn(iter.Value()); err != nil {
+			return errors.UnmarshaFailed("failed to decode record").AddCause(err)
+		}
+	}
+	return nil
+}
+
+func (idx *Indexer) iterateRecent(prefix string, limit int, fn func(value []byte) error) error {
+	if limit <= 0 {
+		return errors.InvalidArgf("invalid number of records %v", limit)
+	}
+
+	iter := idx.db.ReverseIterator([]byte(prefix), prefixEnd(prefix))
+	defer iter.Close()
+
+	for n := 0; iter.Valid() && n < limit; iter.Next() {
+		if err := fn(iter.Value()); err != nil {
+			return errors.UnmarshaFailed("failed to decode record").AddCause(err)
+		}
+		n++
+	}
+	return nil
+}
+
+// prefixEnd returns the first key after all keys starting with @p prefix,
+// which always ends with a separator.
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	end[len(end)-1]++
+	return end
+}
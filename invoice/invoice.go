@@ -0,0 +1,233 @@
+// Package invoice requests LINO payments and matches incoming transfers to
+// them, as merchants need to know when an order is paid.
+//
+// The memo of a transfer is the only field a payer can use to refer to an
+// order, so every invoice gets a unique memo. A request for the invoice is
+// encoded as a lino: URI that wallets and QR codes can carry, and a matcher
+// follows transfers to the receivers and settles invoices by their memos.
+package invoice
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/util"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// Scheme is the URI scheme of payment requests.
+const Scheme = "lino"
+
+// Status is the state of an invoice.
+type Status string
+
+// Statuses of invoices. Pending and Underpaid invoices are open and expire
+// at their ExpiresAt; the others are final, except that a Paid invoice
+// becomes Overpaid if more is paid.
+const (
+	Pending   Status = "pending"
+	Underpaid Status = "underpaid"
+	Paid      Status = "paid"
+	Overpaid  Status = "overpaid"
+	Expired   Status = "expired"
+)
+
+// IsOpen returns true if the invoice still waits for payments.
+func (s Status) IsOpen() bool {
+	return s == Pending || s == Underpaid
+}
+
+// Payment is a transfer matched to an invoice.
+type Payment struct {
+	// DepositID is the ID of the transfer from the deposit watcher.
+	DepositID string         `json:"deposit_id"`
+	From      string         `json:"from"`
+	Amount    linotypes.Coin `json:"amount"`
+	TxHash    string         `json:"tx_hash"`
+	Height    int64          `json:"height"`
+	Time      time.Time      `json:"time"`
+	// Late is set if the payment arrived once the invoice expired. Late
+	// payments are recorded but do not settle the invoice.
+	Late bool `json:"late"`
+}
+
+// Invoice is a request for Amount to be transferred to Receiver with Memo.
+type Invoice struct {
+	// Receiver is a username or a bech32 address.
+	Receiver  string         `json:"receiver"`
+	Amount    linotypes.Coin `json:"amount"`
+	Memo      string         `json:"memo"`
+	CreatedAt time.Time      `json:"created_at"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	Status    Status         `json:"status"`
+	// Received is the sum of the payments made before expiry.
+	Received linotypes.Coin `json:"received"`
+	Payments []Payment      `json:"payments"`
+}
+
+// NewInvoice returns a pending invoice of @p amt LINO to @p receiver with
+// @p memo, created at @p createdAt and expiring after @p ttl.
+func NewInvoice(receiver string, amt amount.Amount, memo string, createdAt time.Time, ttl time.Duration) (*Invoice, error) {
+	if amt.Unit() != amount.LINO || !amt.IsPositive() {
+		return nil, errors.InvalidArgf("invoice amount must be positive LINO, got %s %s", amt, amt.Unit().Name)
+	}
+	coin, err := amt.Coin()
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		return nil, errors.InvalidArg("invoice ttl must be positive")
+	}
+	invoice := &Invoice{
+		Receiver:  receiver,
+		Amount:    coin,
+		Memo:      memo,
+		CreatedAt: createdAt.UTC().Truncate(time.Second),
+		ExpiresAt: createdAt.Add(ttl).UTC().Truncate(time.Second),
+		Status:    Pending,
+		Received:  linotypes.NewCoinFromInt64(0),
+	}
+	if err := invoice.Request().Validate(); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// NewMemo returns a random memo for an invoice, e.g. "inv-3c7mkq2n5vbxa4de".
+func NewMemo() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.InvalidArg("failed to generate memo").AddCause(err)
+	}
+	return "inv-" + strings.ToLower(base32.StdEncoding.EncodeToString(b)), nil
+}
+
+// Request returns the payment request of the invoice.
+func (inv *Invoice) Request() *Request {
+	return &Request{
+		Receiver:  inv.Receiver,
+		Amount:    inv.Amount,
+		Memo:      inv.Memo,
+		ExpiresAt: inv.ExpiresAt,
+	}
+}
+
+// apply records @p payment and updates the status. It returns false if the
+// payment is already recorded.
+func (inv *Invoice) apply(payment Payment) bool {
+	for _, p := range inv.Payments {
+		if p.DepositID == payment.DepositID {
+			return false
+		}
+	}
+	payment.Late = inv.Status == Expired || !payment.Time.Before(inv.ExpiresAt)
+	inv.Payments = append(inv.Payments, payment)
+	if payment.Late {
+		if inv.Status.IsOpen() {
+			inv.Status = Expired
+		}
+		return true
+	}
+	inv.Received = inv.Received.Plus(payment.Amount)
+	switch {
+	case inv.Received.IsGT(inv.Amount):
+		inv.Status = Overpaid
+	case inv.Received.IsEqual(inv.Amount):
+		inv.Status = Paid
+	default:
+		inv.Status = Underpaid
+	}
+	return true
+}
+
+// expire marks the invoice expired if it is open at @p now. It returns true
+// if the status changed.
+func (inv *Invoice) expire(now time.Time) bool {
+	if !inv.Status.IsOpen() || now.Before(inv.ExpiresAt) {
+		return false
+	}
+	inv.Status = Expired
+	return true
+}
+
+// Request is what a payer needs to pay an invoice.
+type Request struct {
+	Receiver  string
+	Amount    linotypes.Coin
+	Memo      string
+	ExpiresAt time.Time
+}
+
+// Validate checks the fields of the request.
+func (r *Request) Validate() error {
+	if !util.CheckUsername(r.Receiver) {
+		if _, err := sdk.AccAddressFromBech32(r.Receiver); err != nil {
+			return errors.InvalidArgf("invalid receiver %q", r.Receiver)
+		}
+	}
+	if !r.Amount.IsPositive() {
+		return errors.InvalidArg("amount must be positive")
+	}
+	if r.Memo == "" || strings.TrimSpace(r.Memo) != r.Memo {
+		return errors.InvalidArgf("invalid memo %q", r.Memo)
+	}
+	if utf8.RuneCountInString(r.Memo) > linotypes.MaximumMemoLength {
+		return errors.InvalidArgf("memo must be at most %v characters", linotypes.MaximumMemoLength)
+	}
+	return nil
+}
+
+// URI returns the request as lino:<receiver>?amount=<LINO>&exp=<unix>&memo=<memo>.
+// The amount is a plain decimal of LINO and exp is omitted if ExpiresAt is
+// zero.
+func (r *Request) URI() string {
+	query := url.Values{}
+	query.Set("amount", amount.NewFromCoin(r.Amount).String())
+	query.Set("memo", r.Memo)
+	if !r.ExpiresAt.IsZero() {
+		query.Set("exp", strconv.FormatInt(r.ExpiresAt.Unix(), 10))
+	}
+	return (&url.URL{Scheme: Scheme, Opaque: r.Receiver, RawQuery: query.Encode()}).String()
+}
+
+// String implements fmt.Stringer.
+func (r *Request) String() string {
+	return r.URI()
+}
+
+// ParseRequest parses a request from @p uri as returned by URI.
+func ParseRequest(uri string) (*Request, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != Scheme || u.Opaque == "" {
+		return nil, errors.InvalidArgf("invalid payment request %q", uri)
+	}
+	query := u.Query()
+	amt, err := amount.Parse(query.Get("amount"), amount.LINO)
+	if err != nil {
+		return nil, errors.InvalidArgf("invalid amount %q", query.Get("amount")).AddCause(err)
+	}
+	coin, err := amt.Coin()
+	if err != nil {
+		return nil, err
+	}
+	r := &Request{Receiver: u.Opaque, Amount: coin, Memo: query.Get("memo")}
+	if exp := query.Get("exp"); exp != "" {
+		unix, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil {
+			return nil, errors.InvalidArgf("invalid expiry %q", exp)
+		}
+		r.ExpiresAt = time.Unix(unix, 0).UTC()
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,292 @@
+package invoice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/deposit"
+	"github.com/lino-network/lino-go/scanner"
+	linoapp "github.com/lino-network/lino/app"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/state"
+	ttypes "github.com/tendermint/tendermint/types"
+)
+
+var issuedAt = time.Unix(1500000000, 0).UTC()
+
+func lino(n int64) linotypes.Coin {
+	return linotypes.NewCoinFromInt64(n * linotypes.Decimals)
+}
+
+func TestParseRequest(t *testing.T) {
+	testCases := map[string]struct {
+		uri       string
+		expect    *Request
+		expectErr bool
+	}{
+		"full": {
+			uri:    "lino:shop?amount=1.5&exp=1500000600&memo=inv-abc",
+			expect: &Request{Receiver: "shop", Amount: linotypes.NewCoinFromInt64(150000), Memo: "inv-abc", ExpiresAt: issuedAt.Add(10 * time.Minute)},
+		},
+		"escaped memo": {
+			uri:    "lino:shop?amount=2&memo=order+%2342",
+			expect: &Request{Receiver: "shop", Amount: lino(2), Memo: "order #42"},
+		},
+		"other scheme": {
+			uri:       "bitcoin:shop?amount=1&memo=a",
+			expectErr: true,
+		},
+		"invalid receiver": {
+			uri:       "lino:Shop?amount=1&memo=a",
+			expectErr: true,
+		},
+		"too precise": {
+			uri:       "lino:shop?amount=0.000001&memo=a",
+			expectErr: true,
+		},
+		"no memo": {
+			uri:       "lino:shop?amount=1",
+			expectErr: true,
+		},
+		"invalid expiry": {
+			uri:       "lino:shop?amount=1&memo=a&exp=soon",
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		r, err := ParseRequest(tc.uri)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if r.Receiver != tc.expect.Receiver || !r.Amount.IsEqual(tc.expect.Amount) ||
+			r.Memo != tc.expect.Memo || !r.ExpiresAt.Equal(tc.expect.ExpiresAt) {
+			t.Errorf("%s: diff request, got %+v, want %+v", testName, r, tc.expect)
+		}
+		if r.URI() != tc.uri {
+			t.Errorf("%s: diff uri, got %s", testName, r.URI())
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	expiresAt := issuedAt.Add(time.Hour)
+	testCases := map[string]struct {
+		payments       []int64
+		late           []bool
+		expectStatus   Status
+		expectReceived int64
+	}{
+		"paid": {
+			payments: []int64{10}, late: []bool{false},
+			expectStatus: Paid, expectReceived: 10,
+		},
+		"underpaid": {
+			payments: []int64{4, 5}, late: []bool{false, false},
+			expectStatus: Underpaid, expectReceived: 9,
+		},
+		"paid in parts": {
+			payments: []int64{4, 6}, late: []bool{false, false},
+			expectStatus: Paid, expectReceived: 10,
+		},
+		"overpaid after paid": {
+			payments: []int64{10, 1}, late: []bool{false, false},
+			expectStatus: Overpaid, expectReceived: 11,
+		},
+		"late": {
+			payments: []int64{10}, late: []bool{true},
+			expectStatus: Expired, expectReceived: 0,
+		},
+		"underpaid then late": {
+			payments: []int64{4, 6}, late: []bool{false, true},
+			expectStatus: Expired, expectReceived: 4,
+		},
+		"paid then late": {
+			payments: []int64{10, 1}, late: []bool{false, true},
+			expectStatus: Paid, expectReceived: 10,
+		},
+	}
+
+	for testName, tc := range testCases {
+		invoice, err := NewInvoice("shop", amount.MustParse("10", amount.LINO), "inv-1", issuedAt, time.Hour)
+		if err != nil {
+			t.Fatalf("%s: failed to create invoice, got err %v", testName, err)
+		}
+		for i, n := range tc.payments {
+			at := expiresAt.Add(-time.Second)
+			if tc.late[i] {
+				at = expiresAt
+			}
+			invoice.apply(Payment{DepositID: string(rune('a' + i)), Amount: lino(n), Time: at})
+			if invoice.Payments[i].Late != tc.late[i] {
+				t.Errorf("%s: payment %v: diff late, got %v", testName, i, invoice.Payments[i].Late)
+			}
+		}
+		if invoice.apply(Payment{DepositID: "a", Amount: lino(1), Time: issuedAt}) {
+			t.Errorf("%s: applied duplicate payment", testName)
+		}
+		if invoice.Status != tc.expectStatus || !invoice.Received.IsEqual(lino(tc.expectReceived)) {
+			t.Errorf("%s: diff invoice, got %v %v, want %v %v",
+				testName, invoice.Status, invoice.Received, tc.expectStatus, tc.expectReceived)
+		}
+	}
+}
+
+func newFixture(t *testing.T, memo string) *scanner.Fixture {
+	cdc := linoapp.MakeCodec()
+	encode := func(msg sdk.Msg) ttypes.Tx {
+		bz, err := cdc.MarshalJSON(auth.NewStdTx([]sdk.Msg{msg}, auth.StdFee{}, nil, ""))
+		if err != nil {
+			t.Fatalf("failed to encode tx: %v", err)
+		}
+		return bz
+	}
+	add := func(fixture *scanner.Fixture, height int64, at time.Time, txs []ttypes.Tx) {
+		results := &state.ABCIResponses{}
+		for range txs {
+			results.DeliverTx = append(results.DeliverTx, &abci.ResponseDeliverTx{})
+		}
+		block := ttypes.MakeBlock(height, txs, nil, nil)
+		block.Time = at
+		fixture.Add(block, &ctypes.ResultBlockResults{Height: height, Results: results})
+	}
+
+	fixture := &scanner.Fixture{}
+	add(fixture, 1, issuedAt.Add(time.Minute), []ttypes.Tx{
+		encode(acctypes.NewTransferMsg("alice", "shop", "4", memo)),
+		encode(acctypes.NewTransferMsg("alice", "shop", "1", "inv-unknown")),
+		encode(acctypes.NewTransferMsg("alice", "other", "6", memo)),
+	})
+	add(fixture, 2, issuedAt.Add(2*time.Minute), []ttypes.Tx{
+		encode(acctypes.NewTransferMsg("bob", "shop", "6", " "+memo+" ")),
+	})
+	add(fixture, 3, issuedAt.Add(time.Hour), nil)
+	return fixture
+}
+
+func TestMatcher(t *testing.T) {
+	ctx := context.Background()
+	updates := []Status{}
+	unmatched := []string{}
+	m := NewMatcher(nil, nil, nil, Options{
+		Receivers: []string{"shop", "other"},
+		OnUpdate: func(ctx context.Context, invoice *Invoice) error {
+			updates = append(updates, invoice.Status)
+			return nil
+		},
+		OnUnmatched: func(ctx context.Context, d *deposit.Deposit) error {
+			unmatched = append(unmatched, d.To+":"+d.Memo)
+			return nil
+		},
+	})
+	m.now = func() time.Time { return issuedAt }
+
+	if _, err := m.Issue(ctx, "stranger", amount.MustParse("10", amount.LINO), time.Hour); err == nil {
+		t.Errorf("issue to stranger: expect err")
+	}
+	paid, err := m.Issue(ctx, "shop", amount.MustParse("10", amount.LINO), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to issue invoice, got err %v", err)
+	}
+	unpaid, err := m.Issue(ctx, "shop", amount.MustParse("1", amount.LINO), 30*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to issue invoice, got err %v", err)
+	}
+	if r, err := ParseRequest(paid.Request().URI()); err != nil || r.Memo != paid.Memo {
+		t.Errorf("diff request, got %+v, err %v", r, err)
+	}
+
+	s := scanner.NewScanner(newFixture(t, paid.Memo), scanner.NewMemCheckpoint(), scanner.Options{})
+	for height := int64(1); height <= 3; height++ {
+		block, err := s.ScanBlock(ctx, height)
+		if err != nil {
+			t.Fatalf("failed to load block %v, got err %v", height, err)
+		}
+		if err := m.HandleBlock(ctx, block); err != nil {
+			t.Fatalf("failed to handle block %v, got err %v", height, err)
+		}
+		if height == 1 {
+			// delivered again after a restart
+			if err := m.HandleBlock(ctx, block); err != nil {
+				t.Fatalf("failed to handle block again, got err %v", err)
+			}
+		}
+	}
+
+	got, _ := m.Get(ctx, paid.Memo)
+	if got.Status != Paid || len(got.Payments) != 2 || !got.Received.IsEqual(lino(10)) {
+		t.Errorf("diff paid invoice, got %+v", got)
+	}
+	if got, _ := m.Get(ctx, unpaid.Memo); got.Status != Expired {
+		t.Errorf("diff unpaid invoice, got %+v", got)
+	}
+	expectUpdates := []Status{Underpaid, Paid, Expired}
+	if len(updates) != len(expectUpdates) {
+		t.Fatalf("diff updates, got %v, want %v", updates, expectUpdates)
+	}
+	for i := range updates {
+		if updates[i] != expectUpdates[i] {
+			t.Errorf("diff update %v, got %v, want %v", i, updates[i], expectUpdates[i])
+		}
+	}
+	if len(unmatched) != 4 || unmatched[0] != "shop:inv-unknown" || unmatched[1] != "other:"+paid.Memo {
+		t.Errorf("diff unmatched, got %v", unmatched)
+	}
+}
+
+type fakeAccounts map[string]sdk.AccAddress
+
+func (f fakeAccounts) GetAccountInfo(ctx context.Context, username string) (*accmodel.AccountInfo, error) {
+	return &accmodel.AccountInfo{Username: linotypes.AccountKey(username), Address: f[username]}, nil
+}
+
+func TestMatcherPaidAtAddress(t *testing.T) {
+	ctx := context.Background()
+	shopAddr := sdk.AccAddress(secp256k1.GenPrivKeySecp256k1([]byte("shop")).PubKey().Address())
+	m := NewMatcher(nil, nil, nil, Options{Receivers: []string{"shop"}})
+	m.now = func() time.Time { return issuedAt }
+	if err := m.Resolve(ctx, fakeAccounts{"shop": shopAddr}); err != nil {
+		t.Fatalf("failed to resolve receivers, got err %v", err)
+	}
+	invoice, err := m.Issue(ctx, "shop", amount.MustParse("10", amount.LINO), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to issue invoice, got err %v", err)
+	}
+
+	cdc := linoapp.MakeCodec()
+	tx, err := cdc.MarshalJSON(auth.NewStdTx([]sdk.Msg{acctypes.NewTransferV2Msg(
+		linotypes.NewAccOrAddrFromAcc("alice"), linotypes.NewAccOrAddrFromAddr(shopAddr), "10", invoice.Memo),
+	}, auth.StdFee{}, nil, ""))
+	if err != nil {
+		t.Fatalf("failed to encode tx: %v", err)
+	}
+	block := ttypes.MakeBlock(1, []ttypes.Tx{tx}, nil, nil)
+	block.Time = issuedAt.Add(time.Minute)
+	fixture := &scanner.Fixture{}
+	fixture.Add(block, &ctypes.ResultBlockResults{
+		Height: 1, Results: &state.ABCIResponses{DeliverTx: []*abci.ResponseDeliverTx{{}}}})
+
+	scanned, err := scanner.NewScanner(fixture, scanner.NewMemCheckpoint(), scanner.Options{}).ScanBlock(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to load block, got err %v", err)
+	}
+	if err := m.HandleBlock(ctx, scanned); err != nil {
+		t.Fatalf("failed to handle block, got err %v", err)
+	}
+	if got, _ := m.Get(ctx, invoice.Memo); got.Status != Paid || !got.Received.IsEqual(lino(10)) {
+		t.Errorf("diff invoice paid at address, got %+v", got)
+	}
+}
@@ -0,0 +1,219 @@
+package invoice
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/deposit"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/scanner"
+)
+
+// Options configures a Matcher.
+type Options struct {
+	// Receivers are the usernames or bech32 addresses invoices are paid to.
+	// Invoices to a username are also paid at its address once Resolve has
+	// looked it up, which Run does if the block source is also a
+	// deposit.AccountSource.
+	Receivers []string
+	// StartHeight is the first height scanned when the checkpoint is empty.
+	StartHeight int64
+	// Confirmations is how many blocks a transfer waits before it is matched.
+	Confirmations int64
+	// PollInterval is how long to wait for new blocks when at the tip.
+	PollInterval time.Duration
+	// RetryInterval is how long to wait before re-scanning from the
+	// checkpoint after an error. Defaults to five seconds.
+	RetryInterval time.Duration
+	// OnUpdate is called with an invoice whenever a payment is matched to it
+	// or it expires, before the invoice is saved. An error stops the block
+	// and it is scanned again, so updates are delivered at least once.
+	OnUpdate func(ctx context.Context, invoice *Invoice) error
+	// OnUnmatched is called with transfers to the receivers whose memo
+	// matches no invoice of the receiver, e.g. to refund them. Like
+	// deposits, they may be delivered again; deduplicate them by ID.
+	OnUnmatched func(ctx context.Context, deposit *deposit.Deposit) error
+	// OnError is called with every error before the matcher retries.
+	OnError func(err error)
+}
+
+// Matcher issues invoices and settles them with transfers to the receivers.
+// Expiry is checked against block time.
+type Matcher struct {
+	source     scanner.BlockSource
+	checkpoint scanner.Checkpoint
+	store      Store
+	watcher    *deposit.Watcher
+	receivers  map[string]bool
+	opts       Options
+	now        func() time.Time
+}
+
+// NewMatcher returns an instance of Matcher reading blocks from @p source,
+// resuming from @p checkpoint and keeping invoices in @p store.
+func NewMatcher(source scanner.BlockSource, checkpoint scanner.Checkpoint, store Store, opts Options) *Matcher {
+	if opts.RetryInterval == 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	if checkpoint == nil {
+		checkpoint = scanner.NewMemCheckpoint()
+	}
+	if store == nil {
+		store = NewMemStore()
+	}
+	receivers := map[string]bool{}
+	for _, receiver := range opts.Receivers {
+		receivers[receiver] = true
+	}
+	m := &Matcher{
+		source:     source,
+		checkpoint: checkpoint,
+		store:      store,
+		receivers:  receivers,
+		opts:       opts,
+		now:        time.Now,
+	}
+	m.watcher = deposit.NewWatcher(source, checkpoint, deposit.HandlerFunc(m.HandleDeposit), deposit.Options{
+		Accounts: opts.Receivers,
+	})
+	return m
+}
+
+// Issue creates and saves an invoice of @p amt LINO to @p receiver with a
+// new memo, expiring after @p ttl.
+func (m *Matcher) Issue(ctx context.Context, receiver string, amt amount.Amount, ttl time.Duration) (*Invoice, error) {
+	if !m.receivers[receiver] {
+		return nil, errors.InvalidArgf("%s is not a receiver of the matcher", receiver)
+	}
+	memo, err := NewMemo()
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := NewInvoice(receiver, amt, memo, m.now(), ttl)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.Put(ctx, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// Get returns the invoice with @p memo.
+func (m *Matcher) Get(ctx context.Context, memo string) (*Invoice, error) {
+	return m.store.Get(ctx, memo)
+}
+
+// Run follows the chain until the context is done. On any error it reports
+// it to OnError, waits RetryInterval and re-scans from the saved height.
+func (m *Matcher) Run(ctx context.Context) error {
+	if len(m.receivers) == 0 {
+		return errors.InvalidArg("no receivers")
+	}
+
+	s := scanner.NewScanner(m.source, m.checkpoint, scanner.Options{
+		StartHeight:   m.opts.StartHeight,
+		Confirmations: m.opts.Confirmations,
+		PollInterval:  m.opts.PollInterval,
+	})
+	accounts, _ := m.source.(deposit.AccountSource)
+	for {
+		var err error
+		if accounts != nil {
+			err = m.Resolve(ctx, accounts)
+		}
+		if err == nil {
+			err = s.Run(ctx, m.HandleBlock)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if m.opts.OnError != nil && err != nil {
+			m.opts.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.opts.RetryInterval):
+		}
+	}
+}
+
+// Resolve looks up the addresses of the receivers in @p accounts, as
+// deposit.Watcher.Resolve.
+func (m *Matcher) Resolve(ctx context.Context, accounts deposit.AccountSource) error {
+	return m.watcher.Resolve(ctx, accounts)
+}
+
+// HandleBlock matches the transfers in @p block, then expires the open
+// invoices whose expiry is not after the block time. It is the
+// scanner.Handler used by Run.
+func (m *Matcher) HandleBlock(ctx context.Context, block *scanner.Block) error {
+	if err := m.watcher.HandleBlock(ctx, block); err != nil {
+		return err
+	}
+	return m.Expire(ctx, block.Time)
+}
+
+// HandleDeposit matches @p d to the invoice with its memo. It implements
+// deposit.Handler, and deposits delivered again are ignored.
+func (m *Matcher) HandleDeposit(ctx context.Context, d *deposit.Deposit) error {
+	invoice, err := m.store.Get(ctx, strings.TrimSpace(d.Memo))
+	if err != nil {
+		if errors.IsEmptyResponse(err) {
+			return m.unmatched(ctx, d)
+		}
+		return err
+	}
+	if invoice.Receiver != d.To && invoice.Receiver != d.Account {
+		return m.unmatched(ctx, d)
+	}
+	applied := invoice.apply(Payment{
+		DepositID: d.ID,
+		From:      d.From,
+		Amount:    d.Amount,
+		TxHash:    d.TxHash,
+		Height:    d.Height,
+		Time:      d.Time,
+	})
+	if !applied {
+		return nil
+	}
+	return m.update(ctx, invoice)
+}
+
+// Expire marks the open invoices expiring at or before @p now expired.
+func (m *Matcher) Expire(ctx context.Context, now time.Time) error {
+	open, err := m.store.Open(ctx)
+	if err != nil {
+		return err
+	}
+	for _, invoice := range open {
+		if !invoice.expire(now) {
+			continue
+		}
+		if err := m.update(ctx, invoice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Matcher) update(ctx context.Context, invoice *Invoice) error {
+	if m.opts.OnUpdate != nil {
+		if err := m.opts.OnUpdate(ctx, invoice); err != nil {
+			return err
+		}
+	}
+	return m.store.Put(ctx, invoice)
+}
+
+func (m *Matcher) unmatched(ctx context.Context, d *deposit.Deposit) error {
+	if m.opts.OnUnmatched == nil {
+		return nil
+	}
+	return m.opts.OnUnmatched(ctx, d)
+}
@@ -0,0 +1,85 @@
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/lino-network/lino-go/errors"
+)
+
+// Store persists invoices by memo.
+type Store interface {
+	// Get returns the invoice with @p memo, or an errors.EmptyResponse
+	// error if there is none.
+	Get(ctx context.Context, memo string) (*Invoice, error)
+	// Put adds or replaces the invoice with the memo of @p invoice.
+	Put(ctx context.Context, invoice *Invoice) error
+	// Open returns the invoices whose status is open.
+	Open(ctx context.Context) ([]*Invoice, error)
+}
+
+// MemStore keeps invoices in memory.
+type MemStore struct {
+	mtx      sync.Mutex
+	invoices map[string][]byte
+}
+
+var _ Store = &MemStore{}
+
+// NewMemStore returns an empty in-memory store.
+func NewMemStore() *MemStore {
+	return &MemStore{invoices: map[string][]byte{}}
+}
+
+// Get implements Store.
+func (s *MemStore) Get(ctx context.Context, memo string) (*Invoice, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	b, ok := s.invoices[memo]
+	if !ok {
+		return nil, errors.EmptyResponsef("invoice %s is not found", memo)
+	}
+	return decode(b)
+}
+
+// Put implements Store.
+func (s *MemStore) Put(ctx context.Context, invoice *Invoice) error {
+	b, err := json.Marshal(invoice)
+	if err != nil {
+		return errors.InvalidArg("failed to encode invoice").AddCause(err)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.invoices[invoice.Memo] = b
+	return nil
+}
+
+// Open implements Store. Invoices are ordered by expiry.
+func (s *MemStore) Open(ctx context.Context) ([]*Invoice, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	res := []*Invoice{}
+	for _, b := range s.invoices {
+		invoice, err := decode(b)
+		if err != nil {
+			return nil, err
+		}
+		if invoice.Status.IsOpen() {
+			res = append(res, invoice)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ExpiresAt.Before(res[j].ExpiresAt)
+	})
+	return res, nil
+}
+
+func decode(b []byte) (*Invoice, error) {
+	invoice := new(Invoice)
+	if err := json.Unmarshal(b, invoice); err != nil {
+		return nil, errors.UnmarshaFailed("invalid invoice").AddCause(err)
+	}
+	return invoice, nil
+}
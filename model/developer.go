@@ -0,0 +1,35 @@
+package model
+
+import (
+	bandwidthmodel "github.com/lino-network/lino/x/bandwidth/model"
+	devmodel "github.com/lino-network/lino/x/developer/model"
+)
+
+//
+// developer related
+//
+
+// AppProfile combines everything the chain knows about an app. IDA, IDAStats
+// and Bandwidth are nil if the app has not issued an IDA or has no bandwidth
+// info yet.
+type AppProfile struct {
+	Username   string                           `json:"username"`
+	Developer  *devmodel.Developer              `json:"developer"`
+	IDA        *devmodel.AppIDA                 `json:"ida"`
+	IDAStats   *devmodel.AppIDAStats            `json:"ida_stats"`
+	Affiliated []string                         `json:"affiliated"`
+	Bandwidth  *bandwidthmodel.AppBandwidthInfo `json:"bandwidth"`
+}
+
+// AppPermission is what an app may do for a user, as permission grants are
+// gone from lino v0.6.x.
+type AppPermission struct {
+	Username string `json:"username"`
+	App      string `json:"app"`
+	// Affiliated is true if the user is the app or one of its affiliated
+	// accounts, which sign msgs of the app.
+	Affiliated bool `json:"affiliated"`
+	// IDAAuthorized is true if the app has a live IDA and the user has not
+	// unauthorized the app with IDAAuthorize.
+	IDAAuthorized bool `json:"ida_authorized"`
+}
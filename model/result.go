@@ -29,11 +29,21 @@ type BlockStatus struct {
 
 type BlockTx struct {
 	Height int64      `json:"height"`
+	Index  uint32     `json:"index"`
+	Hash   string     `json:"hash"`
 	Tx     auth.StdTx `json:"tx"`
 	Code   uint32     `json:"code"`
 	Log    string     `json:"log"`
 }
 
+// TxSearchResult is one page of a tx search, ordered by height and index.
+type TxSearchResult struct {
+	Txs        []*BlockTx `json:"txs"`
+	TotalCount int        `json:"total_count"`
+	Page       int        `json:"page"`
+	PerPage    int        `json:"per_page"`
+}
+
 type BroadcastResponse struct {
 	CommitHash string `json:"commit_hash"`
 	Height     int64  `json:"height"`
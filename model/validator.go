@@ -0,0 +1,31 @@
+package model
+
+import (
+	auth "github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// NamedValidator is a tendermint validator with the Lino username it belongs
+// to. Username is empty if the validator is unknown to the validator module.
+type NamedValidator struct {
+	Username         string        `json:"username"`
+	Address          string        `json:"address"`
+	PubKey           crypto.PubKey `json:"pub_key"`
+	VotingPower      int64         `json:"voting_power"`
+	ProposerPriority int64         `json:"proposer_priority"`
+}
+
+// NamedValidatorSet is the tendermint validator set at a height.
+type NamedValidatorSet struct {
+	Height     int64             `json:"height"`
+	Validators []*NamedValidator `json:"validators"`
+}
+
+// UnconfirmedTxs are decoded txs in the mempool of a node. Total is the
+// number of txs in the mempool, Count the number returned.
+type UnconfirmedTxs struct {
+	Count      int          `json:"count"`
+	Total      int          `json:"total"`
+	TotalBytes int64        `json:"total_bytes"`
+	Txs        []auth.StdTx `json:"txs"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	linotypes "github.com/lino-network/lino/types"
+	votetypes "github.com/lino-network/lino/x/vote/types"
+)
+
+//
+// vote related
+//
+
+// StakeSummary is a combined view of the LINO a user has in the vote module.
+// Frozen is the part of Staked locked by a validator or app duty, Interest is
+// the interest settled at the last stake change and PendingUnlock is the
+// stake-out (or revoke) amount not yet returned to the saving.
+type StakeSummary struct {
+	Username      string              `json:"username"`
+	Staked        linotypes.Coin      `json:"staked"`
+	Frozen        linotypes.Coin      `json:"frozen"`
+	Interest      linotypes.Coin      `json:"interest"`
+	PendingUnlock linotypes.Coin      `json:"pending_unlock"`
+	Duty          votetypes.VoterDuty `json:"duty"`
+}
@@ -0,0 +1,233 @@
+// Package onboarding registers new accounts, e.g. for a signup service: it
+// checks the username, generates or takes the keys of the account, pays the
+// register fee of AccountParam with RegisterV2 and sets the initial meta of
+// the account.
+package onboarding
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/transport"
+	"github.com/lino-network/lino-go/util"
+	"github.com/lino-network/lino/param"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+)
+
+// Chain queries and registers accounts. *api.API implements it.
+type Chain interface {
+	GetAccountInfo(ctx context.Context, username string) (*accmodel.AccountInfo, error)
+	GetAccountParam(ctx context.Context) (*param.AccountParam, error)
+	RegisterV2(ctx context.Context, referrer linotypes.AccOrAddr, registerFee, username, newTxAddr, txPubKeyHex,
+		signingPubKeyHex, referrerPrivKeyHex, txPrivKeyHex string) (*model.BroadcastResponse, errors.Error)
+	UpdateAccountMeta(ctx context.Context, username string, meta string, privKeyHex string) (*model.BroadcastResponse, errors.Error)
+}
+
+// Keys are the keys of an account, hex encoded. The transaction key signs
+// txs and the signing key off-chain payloads.
+type Keys struct {
+	TxPrivKeyHex string `json:"tx_priv_key"`
+	TxPubKeyHex  string `json:"tx_pub_key"`
+	// TxAddress is the hex address of the transaction key.
+	TxAddress string `json:"tx_address"`
+	// SigningPrivKeyHex is empty if only the public key was given, e.g. if
+	// the user keeps the signing key.
+	SigningPrivKeyHex string `json:"signing_priv_key,omitempty"`
+	SigningPubKeyHex  string `json:"signing_pub_key"`
+}
+
+// GenerateKeys returns new secp256k1 transaction and signing keys.
+func GenerateKeys() *Keys {
+	keys := &Keys{}
+	keys.setTx(secp256k1.GenPrivKey())
+	signing := secp256k1.GenPrivKey()
+	keys.SigningPrivKeyHex = hex.EncodeToString(signing.Bytes())
+	keys.SigningPubKeyHex = hex.EncodeToString(signing.PubKey().Bytes())
+	return keys
+}
+
+// NewKeys returns the keys of private transaction key @p txPrivKeyHex and
+// the signing key, given by @p signingKeyHex as either private or public
+// key.
+func NewKeys(txPrivKeyHex, signingKeyHex string) (*Keys, error) {
+	txPriv, err := transport.GetPrivKeyFromHex(txPrivKeyHex)
+	if err != nil {
+		return nil, errors.FailedToGetPrivKeyFromHex("invalid transaction key").AddCause(err)
+	}
+	keys := &Keys{}
+	keys.setTx(txPriv)
+	if signingPriv, err := transport.GetPrivKeyFromHex(signingKeyHex); err == nil {
+		keys.SigningPrivKeyHex = hex.EncodeToString(signingPriv.Bytes())
+		keys.SigningPubKeyHex = hex.EncodeToString(signingPriv.PubKey().Bytes())
+		return keys, nil
+	}
+	signingPub, err := transport.GetPubKeyFromHex(signingKeyHex)
+	if err != nil {
+		return nil, errors.FailedToGetPubKeyFromHex("invalid signing key").AddCause(err)
+	}
+	keys.SigningPubKeyHex = hex.EncodeToString(signingPub.Bytes())
+	return keys, nil
+}
+
+func (keys *Keys) setTx(txPriv crypto.PrivKey) {
+	keys.TxPrivKeyHex = hex.EncodeToString(txPriv.Bytes())
+	keys.TxPubKeyHex = hex.EncodeToString(txPriv.PubKey().Bytes())
+	keys.TxAddress = hex.EncodeToString(txPriv.PubKey().Address())
+}
+
+// Options configures an Onboarder.
+type Options struct {
+	// Referrer pays the register fee of new accounts.
+	Referrer           string
+	ReferrerPrivKeyHex string
+}
+
+// Request is an account to register.
+type Request struct {
+	Username string
+	// Keys of the account. Nil generates new ones.
+	Keys *Keys
+	// RegisterFee in LINO, at least the one of AccountParam which it
+	// defaults to. It is deposited to the new account.
+	RegisterFee string
+	// JSONMeta, if not empty, is set as the meta of the account.
+	JSONMeta string
+}
+
+// Account is a registered account.
+type Account struct {
+	Username    string `json:"username"`
+	Keys        Keys   `json:"keys"`
+	Referrer    string `json:"referrer"`
+	RegisterFee string `json:"register_fee"`
+	// RegisterTx is nil if the account was registered with the same keys
+	// before, e.g. by a request retried after a timeout.
+	RegisterTx *model.BroadcastResponse `json:"register_tx,omitempty"`
+	MetaTx     *model.BroadcastResponse `json:"meta_tx,omitempty"`
+}
+
+// Onboarder registers accounts referred by one account.
+type Onboarder struct {
+	chain Chain
+	opts  Options
+}
+
+// NewOnboarder returns an instance of Onboarder registering accounts on
+// @p chain.
+func NewOnboarder(chain Chain, opts Options) (*Onboarder, error) {
+	if !util.CheckUsername(opts.Referrer) {
+		return nil, errors.InvalidArgf("invalid referrer %q", opts.Referrer)
+	}
+	if opts.ReferrerPrivKeyHex == "" {
+		return nil, errors.InvalidArg("no referrer key")
+	}
+	return &Onboarder{chain: chain, opts: opts}, nil
+}
+
+// CheckUsername returns an error if @p username is invalid or taken.
+func (o *Onboarder) CheckUsername(ctx context.Context, username string) error {
+	_, err := o.lookup(ctx, username)
+	return err
+}
+
+// lookup checks @p username and returns its account info if it is taken.
+func (o *Onboarder) lookup(ctx context.Context, username string) (*accmodel.AccountInfo, error) {
+	if !util.CheckUsername(username) {
+		return nil, errors.InvalidArgf("invalid username %q", username)
+	}
+	info, err := o.chain.GetAccountInfo(ctx, username)
+	if err == nil {
+		return info, errors.InvalidArgf("username %s is taken", username)
+	}
+	if errors.IsEmptyResponse(err) {
+		return nil, nil
+	}
+	return nil, err
+}
+
+// Register registers the account of @p req and sets its meta. Once the keys
+// are known the account is returned, also with an error, so that they are
+// not lost: a request failed to register may be retried with them, and an
+// account whose meta failed to be set is registered nonetheless.
+func (o *Onboarder) Register(ctx context.Context, req Request) (*Account, error) {
+	req.Username = strings.TrimSpace(req.Username)
+	keys := req.Keys
+	if keys == nil {
+		keys = GenerateKeys()
+	} else {
+		var err error
+		signingKey := keys.SigningPrivKeyHex
+		if signingKey == "" {
+			signingKey = keys.SigningPubKeyHex
+		}
+		if keys, err = NewKeys(keys.TxPrivKeyHex, signingKey); err != nil {
+			return nil, err
+		}
+	}
+
+	accountParam, err := o.chain.GetAccountParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fee := amount.NewFromCoin(accountParam.RegisterFee)
+	if req.RegisterFee != "" {
+		given, err := amount.Parse(req.RegisterFee, amount.LINO)
+		if err != nil {
+			return nil, errors.InvalidArgf("invalid register fee %q", req.RegisterFee)
+		}
+		if given.LT(fee) {
+			return nil, errors.InvalidArgf("register fee must be at least %s LINO, got %s", fee, given)
+		}
+		fee = given
+	}
+	acc := &Account{Username: req.Username, Keys: *keys, Referrer: o.opts.Referrer, RegisterFee: fee.String()}
+
+	info, err := o.lookup(ctx, req.Username)
+	if info == nil && err != nil {
+		return acc, err
+	}
+	if info != nil && !registeredWith(info, keys) {
+		return acc, err
+	}
+	if info == nil {
+		acc.RegisterTx, err = o.register(ctx, acc)
+		if err != nil {
+			return acc, err
+		}
+	}
+
+	if req.JSONMeta != "" {
+		resp, err := o.chain.UpdateAccountMeta(ctx, req.Username, req.JSONMeta, keys.TxPrivKeyHex)
+		if err != nil {
+			return acc, err
+		}
+		acc.MetaTx = resp
+	}
+	return acc, nil
+}
+
+func (o *Onboarder) register(ctx context.Context, acc *Account) (*model.BroadcastResponse, error) {
+	resp, err := o.chain.RegisterV2(
+		ctx, linotypes.NewAccOrAddrFromAcc(linotypes.AccountKey(o.opts.Referrer)), acc.RegisterFee, acc.Username,
+		acc.Keys.TxAddress, acc.Keys.TxPubKeyHex, acc.Keys.SigningPubKeyHex, o.opts.ReferrerPrivKeyHex, acc.Keys.TxPrivKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// registeredWith returns true if the account of @p info has @p keys.
+func registeredWith(info *accmodel.AccountInfo, keys *Keys) bool {
+	txPub, err := transport.GetPubKeyFromHex(keys.TxPubKeyHex)
+	if err != nil || info.TransactionKey == nil {
+		return false
+	}
+	return info.TransactionKey.Equals(txPub)
+}
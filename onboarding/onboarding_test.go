@@ -0,0 +1,228 @@
+package onboarding
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/transport"
+	"github.com/lino-network/lino/param"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+)
+
+type registration struct {
+	referrer, fee, username, txAddr, txPub, signingPub, referrerKey, txKey string
+}
+
+type fakeChain struct {
+	infos      map[string]*accmodel.AccountInfo
+	registered []registration
+	metas      map[string]string
+	failMeta   bool
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{infos: map[string]*accmodel.AccountInfo{"taken": {Username: "taken"}}, metas: map[string]string{}}
+}
+
+func (c *fakeChain) GetAccountInfo(ctx context.Context, username string) (*accmodel.AccountInfo, error) {
+	info, ok := c.infos[username]
+	if !ok {
+		return nil, errors.EmptyResponse("account info is not found")
+	}
+	return info, nil
+}
+
+func (c *fakeChain) GetAccountParam(ctx context.Context) (*param.AccountParam, error) {
+	return &param.AccountParam{RegisterFee: linotypes.NewCoinFromInt64(linotypes.Decimals)}, nil
+}
+
+func (c *fakeChain) RegisterV2(ctx context.Context, referrer linotypes.AccOrAddr, registerFee, username, newTxAddr, txPubKeyHex,
+	signingPubKeyHex, referrerPrivKeyHex, txPrivKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	c.registered = append(c.registered, registration{
+		referrer.String(), registerFee, username, newTxAddr, txPubKeyHex, signingPubKeyHex, referrerPrivKeyHex, txPrivKeyHex})
+	txPub, _ := transport.GetPubKeyFromHex(txPubKeyHex)
+	c.infos[username] = &accmodel.AccountInfo{Username: linotypes.AccountKey(username), TransactionKey: txPub}
+	return &model.BroadcastResponse{CommitHash: "hash", Height: 1}, nil
+}
+
+func (c *fakeChain) UpdateAccountMeta(ctx context.Context, username string, meta string, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	if c.failMeta {
+		return nil, errors.BroadcastTimeout("timeout")
+	}
+	c.metas[username] = meta
+	return &model.BroadcastResponse{CommitHash: "meta", Height: 2}, nil
+}
+
+func TestNewKeys(t *testing.T) {
+	tx, signing := secp256k1.GenPrivKey(), secp256k1.GenPrivKey()
+	txHex := hex.EncodeToString(tx.Bytes())
+	testCases := map[string]struct {
+		txKey             string
+		signingKey        string
+		expectSigningPriv bool
+		expectErr         bool
+	}{
+		"private signing key": {
+			txKey:             txHex,
+			signingKey:        hex.EncodeToString(signing.Bytes()),
+			expectSigningPriv: true,
+		},
+		"public signing key": {
+			txKey:      txHex,
+			signingKey: hex.EncodeToString(signing.PubKey().Bytes()),
+		},
+		"invalid signing key": {
+			txKey:      txHex,
+			signingKey: "abcd",
+			expectErr:  true,
+		},
+		"invalid tx key": {
+			txKey:      hex.EncodeToString(tx.PubKey().Bytes()),
+			signingKey: hex.EncodeToString(signing.Bytes()),
+			expectErr:  true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		keys, err := NewKeys(tc.txKey, tc.signingKey)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if keys.TxAddress != hex.EncodeToString(tx.PubKey().Address()) {
+			t.Errorf("%s: diff tx address, got %s", testName, keys.TxAddress)
+		}
+		if keys.SigningPubKeyHex != hex.EncodeToString(signing.PubKey().Bytes()) {
+			t.Errorf("%s: diff signing pub key, got %s", testName, keys.SigningPubKeyHex)
+		}
+		if (keys.SigningPrivKeyHex != "") != tc.expectSigningPriv {
+			t.Errorf("%s: diff signing priv key, got %q", testName, keys.SigningPrivKeyHex)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	testCases := map[string]struct {
+		req            Request
+		failMeta       bool
+		expectErr      bool
+		expectAccount  bool
+		expectRegister bool
+		expectFee      string
+		expectMeta     bool
+	}{
+		"generated keys": {
+			req:            Request{Username: "alice"},
+			expectAccount:  true,
+			expectRegister: true,
+			expectFee:      "1",
+		},
+		"with fee and meta": {
+			req:            Request{Username: "alice", RegisterFee: "2.5", JSONMeta: `{"name":"Alice"}`},
+			expectAccount:  true,
+			expectRegister: true,
+			expectFee:      "2.5",
+			expectMeta:     true,
+		},
+		"fee below param": {
+			req:       Request{Username: "alice", RegisterFee: "0.5"},
+			expectErr: true,
+		},
+		"invalid username": {
+			req:           Request{Username: "Alice!"},
+			expectErr:     true,
+			expectAccount: true,
+		},
+		"taken username": {
+			req:           Request{Username: "taken"},
+			expectErr:     true,
+			expectAccount: true,
+		},
+		"meta fails": {
+			req:            Request{Username: "alice", JSONMeta: "{}"},
+			failMeta:       true,
+			expectErr:      true,
+			expectAccount:  true,
+			expectRegister: true,
+			expectFee:      "1",
+		},
+	}
+
+	for testName, tc := range testCases {
+		chain := newFakeChain()
+		chain.failMeta = tc.failMeta
+		onboarder, err := NewOnboarder(chain, Options{Referrer: "referrer", ReferrerPrivKeyHex: "key"})
+		if err != nil {
+			t.Fatalf("%s: failed to create onboarder, got err %v", testName, err)
+		}
+		acc, err := onboarder.Register(ctx, tc.req)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+		}
+		if (acc != nil) != tc.expectAccount {
+			t.Errorf("%s: diff account, got %+v", testName, acc)
+			continue
+		}
+		expectRegistered := 0
+		if tc.expectRegister {
+			expectRegistered = 1
+		}
+		if len(chain.registered) != expectRegistered {
+			t.Errorf("%s: diff registrations, got %+v", testName, chain.registered)
+			continue
+		}
+		if !tc.expectRegister {
+			continue
+		}
+		got := chain.registered[0]
+		if got.referrer != "referrer" || got.referrerKey != "key" || got.username != tc.req.Username ||
+			got.fee != tc.expectFee || got.txKey != acc.Keys.TxPrivKeyHex || got.txAddr != acc.Keys.TxAddress ||
+			got.signingPub != acc.Keys.SigningPubKeyHex {
+			t.Errorf("%s: diff registration, got %+v, account %+v", testName, got, acc)
+		}
+		if acc.RegisterTx == nil || acc.RegisterFee != tc.expectFee {
+			t.Errorf("%s: diff account, got %+v", testName, acc)
+		}
+		if (acc.MetaTx != nil) != tc.expectMeta || (chain.metas[tc.req.Username] != "") != tc.expectMeta {
+			t.Errorf("%s: diff meta, got tx %+v, meta %q", testName, acc.MetaTx, chain.metas[tc.req.Username])
+		}
+	}
+}
+
+func TestRegisterRetry(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain()
+	chain.failMeta = true
+	onboarder, _ := NewOnboarder(chain, Options{Referrer: "referrer", ReferrerPrivKeyHex: "key"})
+	acc, err := onboarder.Register(ctx, Request{Username: "alice", JSONMeta: "{}"})
+	if err == nil || acc == nil {
+		t.Fatalf("expect failed meta, got account %+v, err %v", acc, err)
+	}
+
+	// retried with the same keys, the account is not registered again.
+	chain.failMeta = false
+	retried, err := onboarder.Register(ctx, Request{Username: "alice", Keys: &acc.Keys, JSONMeta: "{}"})
+	if err != nil {
+		t.Fatalf("failed to retry, got err %v", err)
+	}
+	if len(chain.registered) != 1 || retried.RegisterTx != nil || retried.MetaTx == nil {
+		t.Errorf("diff retry, got registrations %d, account %+v", len(chain.registered), retried)
+	}
+
+	// with other keys the username is taken.
+	if _, err := onboarder.Register(ctx, Request{Username: "alice"}); err == nil {
+		t.Errorf("registered a taken username")
+	}
+	if err := onboarder.CheckUsername(ctx, "bob"); err != nil {
+		t.Errorf("diff check of free username, got err %v", err)
+	}
+}
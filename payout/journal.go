@@ -0,0 +1,187 @@
+package payout
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/lino-network/lino-go/errors"
+)
+
+// Status is the state of a row.
+type Status string
+
+// Statuses of rows. A row is Sending from just before its transaction is
+// broadcast until the result is known. Unknown is reported for rows that
+// were Sending when a run stopped and whose transaction may still commit.
+const (
+	Pending Status = "pending"
+	Sending Status = "sending"
+	Done    Status = "done"
+	Failed  Status = "failed"
+	Unknown Status = "unknown"
+)
+
+// Entry is the progress of a row.
+type Entry struct {
+	RowID  string `json:"row_id"`
+	Status Status `json:"status"`
+	// Seq is the sequence number of the last transaction built for the
+	// row, and Hashes are the hashes of all transactions built for it
+	// while Sending.
+	Seq    uint64   `json:"seq,omitempty"`
+	Hashes []string `json:"hashes,omitempty"`
+	TxHash string   `json:"tx_hash,omitempty"`
+	Height int64    `json:"height,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// Journal persists the progress of rows, so that a rerun skips the rows
+// already paid.
+type Journal interface {
+	// Load returns the latest entries by row ID.
+	Load() (map[string]*Entry, error)
+	// Save saves @p entries, which must be durable when it returns.
+	Save(entries []*Entry) error
+}
+
+// MemJournal keeps entries in memory.
+type MemJournal struct {
+	mtx     sync.Mutex
+	entries map[string]Entry
+}
+
+var _ Journal = &MemJournal{}
+
+// NewMemJournal returns an empty in-memory journal.
+func NewMemJournal() *MemJournal {
+	return &MemJournal{entries: map[string]Entry{}}
+}
+
+// Load implements Journal.
+func (j *MemJournal) Load() (map[string]*Entry, error) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	res := map[string]*Entry{}
+	for id, entry := range j.entries {
+		copied := entry
+		copied.Hashes = append([]string(nil), entry.Hashes...)
+		res[id] = &copied
+	}
+	return res, nil
+}
+
+// Save implements Journal.
+func (j *MemJournal) Save(entries []*Entry) error {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	for _, entry := range entries {
+		copied := *entry
+		copied.Hashes = append([]string(nil), entry.Hashes...)
+		j.entries[entry.RowID] = copied
+	}
+	return nil
+}
+
+// maxEntrySize bounds the size of a journal line.
+const maxEntrySize = 64 * 1024
+
+// FileJournal appends entries to a file as JSON lines and syncs it on every
+// save. A torn last line, left by a crash while saving, is ignored and
+// dropped by the next save.
+type FileJournal struct {
+	path string
+}
+
+var _ Journal = &FileJournal{}
+
+// NewFileJournal returns a journal stored at @p path.
+func NewFileJournal(path string) *FileJournal {
+	return &FileJournal{path: path}
+}
+
+// Load implements Journal.
+func (j *FileJournal) Load() (map[string]*Entry, error) {
+	res := map[string]*Entry{}
+	data, err := ioutil.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return res, nil
+	}
+	if err != nil {
+		return nil, errors.InvalidArgf("failed to read journal %s", j.path).AddCause(err)
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		entry := new(Entry)
+		if err := json.Unmarshal(line, entry); err != nil {
+			if i == len(lines)-1 {
+				// torn by a crash while saving.
+				break
+			}
+			return nil, errors.UnmarshaFailed("invalid journal entry").AddCause(err)
+		}
+		res[entry.RowID] = entry
+	}
+	return res, nil
+}
+
+// Save implements Journal.
+func (j *FileJournal) Save(entries []*Entry) error {
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return errors.InvalidArgf("failed to open journal %s", j.path).AddCause(err)
+	}
+	defer f.Close()
+	end, err := j.dropTornLine(f)
+	if err != nil {
+		return errors.InvalidArgf("failed to read journal %s", j.path).AddCause(err)
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return errors.InvalidArg("failed to encode journal entry").AddCause(err)
+		}
+		buf.Write(b)
+		buf.WriteString("\n")
+	}
+	if _, err := f.WriteAt(buf.Bytes(), end); err != nil {
+		return errors.InvalidArgf("failed to write journal %s", j.path).AddCause(err)
+	}
+	if err := f.Sync(); err != nil {
+		return errors.InvalidArgf("failed to sync journal %s", j.path).AddCause(err)
+	}
+	return nil
+}
+
+// dropTornLine truncates @p f after its last complete line and returns the
+// new size.
+func (j *FileJournal) dropTornLine(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return 0, nil
+	}
+	n := int64(maxEntrySize)
+	if n > size {
+		n = size
+	}
+	tail := make([]byte, n)
+	if _, err := f.ReadAt(tail, size-n); err != nil {
+		return 0, err
+	}
+	if tail[n-1] == '\n' {
+		return size, nil
+	}
+	end := size - n + int64(bytes.LastIndexByte(tail, '\n')+1)
+	return end, f.Truncate(end)
+}
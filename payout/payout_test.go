@@ -0,0 +1,370 @@
+package payout
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/api"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	devtypes "github.com/lino-network/lino/x/developer/types"
+	ttypes "github.com/tendermint/tendermint/types"
+)
+
+type fakeChain struct {
+	accounts map[string]bool
+	saving   linotypes.Coin
+	ida      string
+	seq      uint64
+	// txs are the committed txs by hash, and built the msgs of built txs.
+	txs   map[string]*accmodel.Transaction
+	built map[string][]sdk.Msg
+	// paid counts the transfers to each receiver.
+	paid map[string]int
+	// reject makes the chain reject txs paying these receivers.
+	reject map[string]bool
+	// timeout makes the next broadcast time out, committing the tx if
+	// commitOnTimeout is set.
+	timeout         bool
+	commitOnTimeout bool
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		accounts: map[string]bool{"pool": true, "app": true, "alice": true, "bob": true, "carol": true, "dave": true, "erin": true},
+		saving:   linotypes.NewCoinFromInt64(100 * linotypes.Decimals),
+		ida:      "1000",
+		seq:      7,
+		txs:      map[string]*accmodel.Transaction{},
+		built:    map[string][]sdk.Msg{},
+		paid:     map[string]int{},
+		reject:   map[string]bool{},
+	}
+}
+
+func (c *fakeChain) GetAccountInfo(ctx context.Context, username string) (*accmodel.AccountInfo, error) {
+	if !c.accounts[username] {
+		return nil, errors.EmptyResponse("account info is not found")
+	}
+	return &accmodel.AccountInfo{Username: linotypes.AccountKey(username)}, nil
+}
+
+func (c *fakeChain) GetAccountBank(ctx context.Context, username string) (*accmodel.AccountBank, error) {
+	return &accmodel.AccountBank{Saving: c.saving, Sequence: c.seq}, nil
+}
+
+func (c *fakeChain) GetIDABalance(ctx context.Context, username, app string) (*devtypes.QueryResultIDABalance, error) {
+	return &devtypes.QueryResultIDABalance{Amount: c.ida}, nil
+}
+
+func (c *fakeChain) GetTxAndSequenceNumberByUsername(ctx context.Context, username, hash string) (*accmodel.TxAndSequenceNumber, error) {
+	return &accmodel.TxAndSequenceNumber{Username: username, Sequence: c.seq, Tx: c.txs[hash]}, nil
+}
+
+func (c *fakeChain) MakeBatchMsg(msgs []sdk.Msg, privKeyHex string, seq uint64) ([]byte, errors.Error) {
+	b, _ := json.Marshal(struct {
+		Msgs []sdk.Msg
+		Seq  uint64
+	}{msgs, seq})
+	c.built[hex.EncodeToString(ttypes.Tx(b).Hash())] = msgs
+	return b, nil
+}
+
+func (c *fakeChain) commit(hash string) {
+	for _, msg := range c.built[hash] {
+		switch msg := msg.(type) {
+		case acctypes.TransferMsg:
+			c.paid[string(msg.Receiver)]++
+		case devtypes.IDATransferMsg:
+			c.paid[string(msg.To)]++
+		}
+	}
+	c.seq += uint64(len(c.built[hash]))
+	c.txs[hash] = &accmodel.Transaction{Hash: hash, Height: int64(len(c.txs) + 1)}
+}
+
+func (c *fakeChain) GuaranteeBroadcast(ctx context.Context, signers []linotypes.AccOrAddr,
+	f api.MsgBuilderFunc) (*model.BroadcastResponse, []string, errors.Error) {
+	b, err := f([]uint64{c.seq})
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := hex.EncodeToString(ttypes.Tx(b).Hash())
+	if c.timeout {
+		c.timeout = false
+		if c.commitOnTimeout {
+			c.commit(hash)
+		}
+		return nil, []string{hash}, errors.BroadcastTimeoutf("GuaranteeBroadcast timeout")
+	}
+	for _, msg := range c.built[hash] {
+		if transfer, ok := msg.(acctypes.TransferMsg); ok && c.reject[string(transfer.Receiver)] {
+			return nil, []string{hash}, errors.CheckTxFail("CheckTx failed!")
+		}
+	}
+	c.commit(hash)
+	return &model.BroadcastResponse{CommitHash: hash, Height: c.txs[hash].Height}, []string{hash}, nil
+}
+
+func lino(s string) amount.Amount {
+	return amount.MustParse(s, amount.LINO)
+}
+
+func newRunner(t *testing.T, chain Chain, journal Journal) *Runner {
+	runner, err := NewRunner(chain, journal, Options{Sender: "pool", PrivKeyHex: "key"})
+	if err != nil {
+		t.Fatalf("failed to create runner, got err %v", err)
+	}
+	return runner
+}
+
+func TestReadCSV(t *testing.T) {
+	testCases := map[string]struct {
+		csv       string
+		expect    []*Row
+		expectErr bool
+	}{
+		"with ids": {
+			csv:    "id,receiver,amount,memo\nr1,alice,1.5,thanks\nr2, bob ,2,\n",
+			expect: []*Row{{ID: "r1", Receiver: "alice", Amount: lino("1.5"), Memo: "thanks"}, {ID: "r2", Receiver: "bob", Amount: lino("2")}},
+		},
+		"without ids": {
+			csv:    "Amount,Receiver\n3,alice\n4,bob\n",
+			expect: []*Row{{ID: "1", Receiver: "alice", Amount: lino("3")}, {ID: "2", Receiver: "bob", Amount: lino("4")}},
+		},
+		"no amount column": {
+			csv:       "receiver\nalice\n",
+			expectErr: true,
+		},
+		"invalid amount": {
+			csv:       "receiver,amount\nalice,1.000001\n",
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		rows, err := ReadCSV(strings.NewReader(tc.csv), amount.LINO)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if len(rows) != len(tc.expect) {
+			t.Errorf("%s: diff rows, got %v, want %v", testName, len(rows), len(tc.expect))
+			continue
+		}
+		for i, row := range rows {
+			expect := tc.expect[i]
+			if row.ID != expect.ID || row.Receiver != expect.Receiver || !row.Amount.Equal(expect.Amount) || row.Memo != expect.Memo {
+				t.Errorf("%s: diff row %v, got %+v, want %+v", testName, i, row, expect)
+			}
+		}
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	rows, err := ReadJSON(strings.NewReader(`[{"receiver":"alice","amount":"10"},{"id":"x","receiver":"bob","amount":"0.5","memo":"m"}]`), amount.IDA)
+	if err != nil {
+		t.Fatalf("failed to read json, got err %v", err)
+	}
+	if len(rows) != 2 || rows[0].ID != "1" || rows[1].ID != "x" || rows[1].Memo != "m" ||
+		!rows[1].Amount.Equal(amount.MustParse("0.5", amount.IDA)) {
+		t.Errorf("diff rows, got %+v %+v", rows[0], rows[1])
+	}
+}
+
+func TestRowJSON(t *testing.T) {
+	rows := []*Row{
+		{ID: "1", Receiver: "alice", Amount: amount.MustParse("10", amount.LINO), Memo: "m"},
+		{ID: "2", Receiver: "bob", Amount: amount.MustParse("0.5", amount.IDA)},
+		{ID: "3", Receiver: "carol"},
+	}
+	b, err := json.Marshal(rows)
+	if err != nil {
+		t.Fatalf("failed to marshal rows, got err %v", err)
+	}
+	var parsed []*Row
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatalf("failed to unmarshal rows %s, got err %v", b, err)
+	}
+	if len(parsed) != len(rows) {
+		t.Fatalf("diff number of rows, got %v, want %v", len(parsed), len(rows))
+	}
+	for i, row := range rows {
+		got := parsed[i]
+		if got.ID != row.ID || got.Receiver != row.Receiver || got.Memo != row.Memo ||
+			got.Amount.Unit() != row.Amount.Unit() || !got.Amount.Units().Equal(row.Amount.Units()) {
+			t.Errorf("diff row %v after json round trip, got %+v, want %+v", i, got, row)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := map[string]struct {
+		rows      []*Row
+		expectErr string
+	}{
+		"valid": {
+			rows: []*Row{{ID: "1", Receiver: "alice", Amount: lino("60")}, {ID: "2", Receiver: "alice", Amount: lino("40")}},
+		},
+		"invalid receiver": {
+			rows:      []*Row{{ID: "1", Receiver: "Alice", Amount: lino("1")}},
+			expectErr: "invalid receiver",
+		},
+		"self": {
+			rows:      []*Row{{ID: "1", Receiver: "pool", Amount: lino("1")}},
+			expectErr: "cannot pay itself",
+		},
+		"duplicate id": {
+			rows:      []*Row{{ID: "1", Receiver: "alice", Amount: lino("1")}, {ID: "1", Receiver: "bob", Amount: lino("1")}},
+			expectErr: "duplicate id",
+		},
+		"wrong unit": {
+			rows:      []*Row{{ID: "1", Receiver: "alice", Amount: amount.MustParse("1", amount.IDA)}},
+			expectErr: "positive LINO",
+		},
+		"not found": {
+			rows:      []*Row{{ID: "1", Receiver: "alice", Amount: lino("1")}, {ID: "2", Receiver: "zoe", Amount: lino("1")}},
+			expectErr: "zoe is not found",
+		},
+		"over balance": {
+			rows:      []*Row{{ID: "1", Receiver: "alice", Amount: lino("60")}, {ID: "2", Receiver: "bob", Amount: lino("40.00001")}},
+			expectErr: "exceeds the balance",
+		},
+	}
+
+	for testName, tc := range testCases {
+		err := newRunner(t, newFakeChain(), nil).Validate(context.Background(), tc.rows)
+		if tc.expectErr == "" && err != nil {
+			t.Errorf("%s: got err %v", testName, err)
+		}
+		if tc.expectErr != "" && (err == nil || !strings.Contains(err.Error(), tc.expectErr)) {
+			t.Errorf("%s: diff err, got %v, want %q", testName, err, tc.expectErr)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain()
+	chain.reject["dave"] = true
+	journal := NewFileJournal(filepath.Join(t.TempDir(), "journal"))
+	rows := []*Row{
+		{ID: "1", Receiver: "alice", Amount: lino("1")},
+		{ID: "2", Receiver: "bob", Amount: lino("2")},
+		{ID: "3", Receiver: "carol", Amount: lino("3")},
+		{ID: "4", Receiver: "dave", Amount: lino("4")},
+		{ID: "5", Receiver: "erin", Amount: lino("5")},
+	}
+
+	report, err := newRunner(t, chain, journal).Run(ctx, rows)
+	if err != nil {
+		t.Fatalf("run: got err %v", err)
+	}
+	if report.Counts[Done] != 4 || report.Counts[Failed] != 1 || report.Rows[3].Status != Failed {
+		t.Errorf("run: diff report, got %v", report.Counts)
+	}
+	if len(chain.txs) != 3 || report.Rows[0].TxHash != report.Rows[1].TxHash || report.Rows[2].TxHash == report.Rows[4].TxHash {
+		t.Errorf("run: diff batches, got %v txs", len(chain.txs))
+	}
+
+	delete(chain.reject, "dave")
+	report, err = newRunner(t, chain, journal).Run(ctx, rows)
+	if err != nil || !report.Done() {
+		t.Fatalf("rerun: diff report, got %v, err %v", report.Counts, err)
+	}
+	for _, row := range rows {
+		if chain.paid[row.Receiver] != 1 {
+			t.Errorf("rerun: %s paid %v times", row.Receiver, chain.paid[row.Receiver])
+		}
+	}
+
+	var buf strings.Builder
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("failed to write report, got err %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 || !strings.HasPrefix(lines[4], "4,dave,4,,done,"+report.Rows[3].TxHash+",4,") {
+		t.Errorf("diff csv report, got %v", lines)
+	}
+}
+
+func TestRunResumes(t *testing.T) {
+	testCases := map[string]struct {
+		commitOnTimeout bool
+		// usedSeq advances the sequence number before the rerun.
+		usedSeq          bool
+		expectRerun      Status
+		expectPaidBefore int
+		expectPaid       int
+	}{
+		"committed": {
+			commitOnTimeout: true, expectRerun: Done, expectPaidBefore: 1, expectPaid: 1,
+		},
+		"may still commit": {
+			expectRerun: Unknown, expectPaid: 0,
+		},
+		"can no longer commit": {
+			usedSeq: true, expectRerun: Done, expectPaid: 1,
+		},
+	}
+
+	for testName, tc := range testCases {
+		ctx := context.Background()
+		chain := newFakeChain()
+		chain.timeout, chain.commitOnTimeout = true, tc.commitOnTimeout
+		journal := NewMemJournal()
+		rows := []*Row{{ID: "1", Receiver: "alice", Amount: lino("1")}}
+
+		report, err := newRunner(t, chain, journal).Run(ctx, rows)
+		if err == nil || report.Rows[0].Status != Unknown || len(report.Rows[0].Hashes) != 1 {
+			t.Errorf("%s: diff stopped run, got %+v, err %v", testName, report.Rows[0], err)
+			continue
+		}
+		if chain.paid["alice"] != tc.expectPaidBefore {
+			t.Errorf("%s: diff paid before rerun, got %v", testName, chain.paid["alice"])
+		}
+		if tc.usedSeq {
+			chain.seq++
+		}
+		report, err = newRunner(t, chain, journal).Run(ctx, rows)
+		if err != nil || report.Rows[0].Status != tc.expectRerun {
+			t.Errorf("%s: diff rerun, got %+v, err %v", testName, report.Rows[0], err)
+		}
+		if chain.paid["alice"] != tc.expectPaid {
+			t.Errorf("%s: diff paid, got %v, want %v", testName, chain.paid["alice"], tc.expectPaid)
+		}
+	}
+}
+
+func TestFileJournalTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	journal := NewFileJournal(path)
+	if err := journal.Save([]*Entry{{RowID: "1", Status: Done}}); err != nil {
+		t.Fatalf("failed to save, got err %v", err)
+	}
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"row_id":"2","sta`)
+	f.Close()
+
+	entries, err := journal.Load()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("load torn: diff entries, got %v, err %v", entries, err)
+	}
+	if err := journal.Save([]*Entry{{RowID: "2", Status: Failed}}); err != nil {
+		t.Fatalf("failed to save, got err %v", err)
+	}
+	entries, err = journal.Load()
+	if err != nil || len(entries) != 2 || entries["2"].Status != Failed {
+		t.Errorf("load: diff entries, got %v, err %v", entries, err)
+	}
+}
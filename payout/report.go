@@ -0,0 +1,81 @@
+package payout
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lino-network/lino-go/errors"
+)
+
+// RowReport is the outcome of a row.
+type RowReport struct {
+	Row    *Row   `json:"row"`
+	Status Status `json:"status"`
+	TxHash string `json:"tx_hash"`
+	Height int64  `json:"height"`
+	Error  string `json:"error"`
+	// Hashes are the transactions that may still pay an Unknown row.
+	Hashes []string `json:"hashes"`
+}
+
+// Report reconciles the rows of a payout with their transactions.
+type Report struct {
+	Rows []*RowReport `json:"rows"`
+	// Counts is the number of rows by status.
+	Counts map[Status]int `json:"counts"`
+}
+
+// reportOf returns the report of @p rows by @p entries.
+func reportOf(rows []*Row, entries map[string]*Entry) *Report {
+	report := &Report{Counts: map[Status]int{}}
+	for _, row := range rows {
+		rowReport := &RowReport{Row: row, Status: Pending}
+		if entry, ok := entries[row.ID]; ok {
+			rowReport.Status = entry.Status
+			rowReport.TxHash = entry.TxHash
+			rowReport.Height = entry.Height
+			rowReport.Error = entry.Error
+			if entry.Status == Sending {
+				rowReport.Status = Unknown
+				rowReport.Hashes = entry.Hashes
+			}
+		}
+		report.Rows = append(report.Rows, rowReport)
+		report.Counts[rowReport.Status]++
+	}
+	return report
+}
+
+// Done returns true if all rows are paid.
+func (r *Report) Done() bool {
+	return r.Counts[Done] == len(r.Rows)
+}
+
+// WriteCSV writes the report as CSV with the columns id, receiver, amount,
+// memo, status, tx_hash, height and error. The tx_hash of an unknown row
+// lists the transactions that may pay it, separated by spaces.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "receiver", "amount", "memo", "status", "tx_hash", "height", "error"})
+	for _, row := range r.Rows {
+		txHash := row.TxHash
+		if row.Status == Unknown {
+			txHash = strings.Join(row.Hashes, " ")
+		}
+		height := ""
+		if row.Height != 0 {
+			height = strconv.FormatInt(row.Height, 10)
+		}
+		writer.Write([]string{
+			row.Row.ID, row.Row.Receiver, row.Row.Amount.String(), row.Row.Memo,
+			string(row.Status), txHash, height, row.Error,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return errors.InvalidArg("failed to write report").AddCause(err)
+	}
+	return nil
+}
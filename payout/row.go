@@ -0,0 +1,121 @@
+package payout
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/util"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// Row is a payment of a payout. ID identifies the row across runs, so it
+// must not change when the list is run again.
+type Row struct {
+	ID       string        `json:"id"`
+	Receiver string        `json:"receiver"`
+	Amount   amount.Amount `json:"amount"`
+	Memo     string        `json:"memo"`
+}
+
+// ReadCSV reads rows of @p unit from CSV with a header naming the columns
+// receiver, amount and optionally memo and id. Rows without an id get their
+// 1-based number below the header.
+func ReadCSV(r io.Reader, unit amount.Unit) ([]*Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.InvalidArg("failed to read csv header").AddCause(err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"receiver", "amount"} {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.InvalidArgf("csv has no %s column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := []*Row{}
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.InvalidArgf("failed to read csv row %d", n).AddCause(err)
+		}
+		amt, err := amount.Parse(field(record, "amount"), unit)
+		if err != nil {
+			return nil, errors.InvalidArgf("row %d: invalid amount %q", n, field(record, "amount")).AddCause(err)
+		}
+		id := field(record, "id")
+		if id == "" {
+			id = strconv.Itoa(n)
+		}
+		rows = append(rows, &Row{
+			ID:       id,
+			Receiver: field(record, "receiver"),
+			Amount:   amt,
+			Memo:     field(record, "memo"),
+		})
+	}
+	return rows, nil
+}
+
+// ReadJSON reads rows of @p unit from a JSON array of objects with id,
+// receiver, amount and memo. Amounts are decimal strings and rows without
+// an id get their 1-based index.
+func ReadJSON(r io.Reader, unit amount.Unit) ([]*Row, error) {
+	var records []struct {
+		ID       string `json:"id"`
+		Receiver string `json:"receiver"`
+		Amount   string `json:"amount"`
+		Memo     string `json:"memo"`
+	}
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, errors.UnmarshaFailed("invalid payout json").AddCause(err)
+	}
+	rows := make([]*Row, len(records))
+	for i, record := range records {
+		amt, err := amount.Parse(record.Amount, unit)
+		if err != nil {
+			return nil, errors.InvalidArgf("row %d: invalid amount %q", i+1, record.Amount).AddCause(err)
+		}
+		if record.ID == "" {
+			record.ID = strconv.Itoa(i + 1)
+		}
+		rows[i] = &Row{ID: record.ID, Receiver: record.Receiver, Amount: amt, Memo: record.Memo}
+	}
+	return rows, nil
+}
+
+// validate checks @p row of a payout from @p from in @p unit as the chain does.
+func (row *Row) validate(from string, unit amount.Unit) error {
+	if !util.CheckUsername(row.Receiver) {
+		return errors.InvalidArgf("row %s: invalid receiver %q", row.ID, row.Receiver)
+	}
+	if row.Receiver == from {
+		return errors.InvalidArgf("row %s: %s cannot pay itself", row.ID, from)
+	}
+	if row.Amount == (amount.Amount{}) || row.Amount.Unit() != unit || !row.Amount.IsPositive() {
+		return errors.InvalidArgf("row %s: amount must be positive %s", row.ID, unit.Name)
+	}
+	if len(row.Memo) > linotypes.MaximumMemoLength {
+		return errors.InvalidArgf("row %s: memo must be at most %v bytes", row.ID, linotypes.MaximumMemoLength)
+	}
+	return nil
+}
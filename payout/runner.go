@@ -0,0 +1,324 @@
+// Package payout pays LINO or IDA to lists of recipients, e.g. rewards or
+// airdrops, and journals the progress of every row so that a run stopped by
+// a crash or an error can be run again without paying any row twice.
+//
+// Rows are checked up front: receivers must be valid existing accounts and
+// the total must be covered by the balance. They are then paid in batches
+// of up to linotypes.TxSigLimit transfers per transaction with
+// GuaranteeBroadcast; a batch the chain rejects is retried row by row.
+package payout
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/api"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/util"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	devtypes "github.com/lino-network/lino/x/developer/types"
+)
+
+// maxProblems is how many invalid rows Validate reports.
+const maxProblems = 10
+
+// Chain reads accounts and broadcasts batches. *api.API implements it.
+type Chain interface {
+	GetAccountInfo(ctx context.Context, username string) (*accmodel.AccountInfo, error)
+	GetAccountBank(ctx context.Context, username string) (*accmodel.AccountBank, error)
+	GetIDABalance(ctx context.Context, username, app string) (*devtypes.QueryResultIDABalance, error)
+	api.TxSource
+	GuaranteeBroadcast(ctx context.Context, signers []linotypes.AccOrAddr, f api.MsgBuilderFunc) (*model.BroadcastResponse, []string, errors.Error)
+	MakeBatchMsg(msgs []sdk.Msg, privKeyHex string, seq uint64) ([]byte, errors.Error)
+}
+
+// Options configures a Runner.
+type Options struct {
+	// Sender pays LINO. It is ignored if App is set.
+	Sender string
+	// App, if set, pays its IDA, with transactions signed by Signer, the
+	// app or one of its affiliated accounts. Empty Signer is App.
+	App    string
+	Signer string
+	// PrivKeyHex is the private key of Sender, or of Signer for IDA.
+	PrivKeyHex string
+	// BatchSize is the number of rows per transaction, at most and by
+	// default linotypes.TxSigLimit.
+	BatchSize int
+}
+
+// Runner pays rows and journals their progress.
+type Runner struct {
+	chain   Chain
+	journal Journal
+	opts    Options
+}
+
+// NewRunner returns an instance of Runner paying on @p chain with progress
+// kept in @p journal.
+func NewRunner(chain Chain, journal Journal, opts Options) (*Runner, error) {
+	if opts.App != "" {
+		if opts.Signer == "" {
+			opts.Signer = opts.App
+		}
+		if !util.CheckUsername(opts.App) || !util.CheckUsername(opts.Signer) {
+			return nil, errors.InvalidArgf("invalid app %q or signer %q", opts.App, opts.Signer)
+		}
+	} else if !util.CheckUsername(opts.Sender) {
+		return nil, errors.InvalidArgf("invalid sender %q", opts.Sender)
+	}
+	if opts.PrivKeyHex == "" {
+		return nil, errors.InvalidArg("no private key")
+	}
+	if opts.BatchSize <= 0 || opts.BatchSize > linotypes.TxSigLimit {
+		opts.BatchSize = linotypes.TxSigLimit
+	}
+	if journal == nil {
+		journal = NewMemJournal()
+	}
+	return &Runner{chain: chain, journal: journal, opts: opts}, nil
+}
+
+// unit returns the unit the runner pays.
+func (r *Runner) unit() amount.Unit {
+	if r.opts.App != "" {
+		return amount.IDA
+	}
+	return amount.LINO
+}
+
+// from returns the account paying.
+func (r *Runner) from() string {
+	if r.opts.App != "" {
+		return r.opts.App
+	}
+	return r.opts.Sender
+}
+
+// signer returns the account signing the transactions.
+func (r *Runner) signer() string {
+	if r.opts.App != "" {
+		return r.opts.Signer
+	}
+	return r.opts.Sender
+}
+
+// Validate checks @p rows and, for the rows not paid yet by the journal,
+// that their receivers exist and that the balance covers them.
+func (r *Runner) Validate(ctx context.Context, rows []*Row) error {
+	entries, err := r.journal.Load()
+	if err != nil {
+		return err
+	}
+	return r.validate(ctx, rows, entries)
+}
+
+func (r *Runner) validate(ctx context.Context, rows []*Row, entries map[string]*Entry) error {
+	problems := []string{}
+	ids := map[string]bool{}
+	for _, row := range rows {
+		if ids[row.ID] {
+			problems = append(problems, fmt.Sprintf("row %s: duplicate id", row.ID))
+		}
+		ids[row.ID] = true
+		if err := row.validate(r.from(), r.unit()); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return problemsError(problems)
+	}
+
+	total := amount.Zero(r.unit())
+	exists := map[string]bool{}
+	for _, row := range rows {
+		if entry, ok := entries[row.ID]; ok && entry.Status == Done {
+			continue
+		}
+		total = total.Add(row.Amount)
+		if _, ok := exists[row.Receiver]; ok {
+			continue
+		}
+		_, err := r.chain.GetAccountInfo(ctx, row.Receiver)
+		if err != nil {
+			if !errors.IsEmptyResponse(err) {
+				return err
+			}
+		}
+		exists[row.Receiver] = err == nil
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("row %s: account %s is not found", row.ID, row.Receiver))
+		}
+	}
+	if len(problems) > 0 {
+		return problemsError(problems)
+	}
+
+	balance, err := r.balance(ctx)
+	if err != nil {
+		return err
+	}
+	if total.GT(balance) {
+		return errors.InvalidArgf("total %s %s exceeds the balance %s of %s",
+			total, r.unit().Name, balance, r.from())
+	}
+	return nil
+}
+
+// balance returns the balance paying the rows.
+func (r *Runner) balance(ctx context.Context) (amount.Amount, error) {
+	if r.opts.App != "" {
+		bank, err := r.chain.GetIDABalance(ctx, r.opts.App, r.opts.App)
+		if err != nil {
+			return amount.Amount{}, err
+		}
+		return amount.Parse(bank.Amount, amount.IDA)
+	}
+	bank, err := r.chain.GetAccountBank(ctx, r.opts.Sender)
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	return amount.NewFromCoin(bank.Saving), nil
+}
+
+func problemsError(problems []string) error {
+	n := len(problems)
+	if n > maxProblems {
+		problems = append(problems[:maxProblems], fmt.Sprintf("and %d more", n-maxProblems))
+	}
+	return errors.InvalidArgf("%d invalid rows: %s", n, strings.Join(problems, "; "))
+}
+
+// Run validates @p rows, settles the rows left Sending by a previous run,
+// pays the rows that are not done and returns the report of all rows.
+// Failed rows are paid again by the next run. Errors other than a rejected
+// transaction stop the run; the report is returned with them if the rows
+// were validated.
+func (r *Runner) Run(ctx context.Context, rows []*Row) (*Report, error) {
+	entries, err := r.journal.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.validate(ctx, rows, entries); err != nil {
+		return nil, err
+	}
+
+	todo := []*Row{}
+	for _, row := range rows {
+		entry, ok := entries[row.ID]
+		if ok && entry.Status == Sending {
+			if err := r.settle(ctx, entry); err != nil {
+				return reportOf(rows, entries), err
+			}
+		}
+		if !ok || entry.Status == Pending || entry.Status == Failed {
+			todo = append(todo, row)
+		}
+	}
+
+	for len(todo) > 0 {
+		n := r.opts.BatchSize
+		if n > len(todo) {
+			n = len(todo)
+		}
+		if err := r.pay(ctx, todo[:n], entries); err != nil {
+			return reportOf(rows, entries), err
+		}
+		todo = todo[n:]
+	}
+	return reportOf(rows, entries), nil
+}
+
+// settle resolves @p entry left Sending by a stopped run from the
+// transactions built for it. It stays Sending if one of them may still
+// commit, and becomes Pending if none of them can.
+func (r *Runner) settle(ctx context.Context, entry *Entry) error {
+	tx, open, err := api.SettleTxs(ctx, r.chain, r.signer(), api.TxRecord{Seq: entry.Seq, Hashes: entry.Hashes})
+	if err != nil {
+		return err
+	}
+	if tx != nil {
+		entry.Status, entry.TxHash, entry.Height, entry.Error = Done, tx.Hash, tx.Height, ""
+		return r.journal.Save([]*Entry{entry})
+	}
+	if open {
+		return nil
+	}
+	entry.Status, entry.Hashes, entry.Seq = Pending, nil, 0
+	return r.journal.Save([]*Entry{entry})
+}
+
+// pay pays @p batch in one transaction. If the chain rejects it, rows of a
+// batch are paid one by one and a single row is marked Failed.
+func (r *Runner) pay(ctx context.Context, batch []*Row, entries map[string]*Entry) error {
+	msgs := make([]sdk.Msg, len(batch))
+	sending := make([]*Entry, len(batch))
+	for i, row := range batch {
+		msgs[i] = r.msg(row)
+		sending[i] = &Entry{RowID: row.ID, Status: Sending}
+		entries[row.ID] = sending[i]
+	}
+
+	// journal every tx before it may be broadcast.
+	record := &api.TxRecord{}
+	build := record.Recorder(func(seqs []uint64) ([]byte, errors.Error) {
+		return r.chain.MakeBatchMsg(msgs, r.opts.PrivKeyHex, seqs[0])
+	}, func() error {
+		for _, entry := range sending {
+			entry.Seq, entry.Hashes = record.Seq, append([]string(nil), record.Hashes...)
+		}
+		return r.journal.Save(sending)
+	})
+	resp, _, linoErr := r.chain.GuaranteeBroadcast(ctx, util.GetSignerList(r.signer()), build)
+	if linoErr == nil {
+		for _, entry := range sending {
+			entry.Status, entry.TxHash, entry.Height = Done, resp.CommitHash, resp.Height
+		}
+		return r.journal.Save(sending)
+	}
+	if linoErr.CodeType() != errors.CodeCheckTxFail && linoErr.CodeType() != errors.CodeDeliverTxFail {
+		// rows never built stay pending.
+		for _, entry := range sending {
+			if len(entry.Hashes) == 0 {
+				entry.Status = Pending
+			}
+		}
+		return linoErr
+	}
+	if len(batch) > 1 {
+		for _, row := range batch {
+			if err := r.pay(ctx, []*Row{row}, entries); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	sending[0].Status, sending[0].Error = Failed, linoErr.Error()
+	return r.journal.Save(sending)
+}
+
+// msg returns the transfer of @p row.
+func (r *Runner) msg(row *Row) sdk.Msg {
+	if r.opts.App != "" {
+		return devtypes.IDATransferMsg{
+			App:    linotypes.AccountKey(r.opts.App),
+			Amount: linotypes.IDAStr(row.Amount.String()),
+			From:   linotypes.AccountKey(r.opts.App),
+			To:     linotypes.AccountKey(row.Receiver),
+			Signer: linotypes.AccountKey(r.opts.Signer),
+			Memo:   row.Memo,
+		}
+	}
+	return acctypes.TransferMsg{
+		Sender:   linotypes.AccountKey(r.opts.Sender),
+		Receiver: linotypes.AccountKey(row.Receiver),
+		Amount:   linotypes.LNO(row.Amount.String()),
+		Memo:     row.Memo,
+	}
+}
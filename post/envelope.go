@@ -0,0 +1,185 @@
+// Package post publishes posts whose content is a versioned envelope: a body
+// with media references, tags, links and the hash of the full content, kept
+// in the Content of the post as compact JSON.
+//
+// The chain stores only a title and a content string per post, so anything
+// beyond the body travels in the envelope. Content that is not an envelope
+// is read as the body of a version 0 envelope.
+package post
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/util"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// CurrentVersion is the version of the envelopes this package writes.
+const CurrentVersion = 1
+
+// Limits are the sizes the chain accepts for a post. They are fixed in the
+// chain code, not in the PostParam.
+type Limits struct {
+	// MaxPostIDLength is in bytes, MaxTitleLength and MaxContentLength in
+	// characters.
+	MaxPostIDLength  int
+	MaxTitleLength   int
+	MaxContentLength int
+}
+
+// DefaultLimits are the limits of the chain.
+var DefaultLimits = Limits{
+	MaxPostIDLength:  linotypes.MaximumLengthOfPostID,
+	MaxTitleLength:   linotypes.MaxPostTitleLength,
+	MaxContentLength: linotypes.MaxPostContentLength,
+}
+
+// Media is a reference to media stored off chain.
+type Media struct {
+	// Type is the MIME type of the media.
+	Type string `json:"type"`
+	URL  string `json:"url"`
+	// Hash is the hex SHA-256 of the media, if known.
+	Hash string `json:"hash,omitempty"`
+}
+
+// Envelope is the content of a post.
+type Envelope struct {
+	Version int      `json:"v"`
+	Body    string   `json:"body"`
+	Media   []Media  `json:"media,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// Links maps identifiers to URLs.
+	Links map[string]string `json:"links,omitempty"`
+	// Hash is the hex SHA-256 of the full content, of which Body may be an
+	// excerpt.
+	Hash string `json:"hash,omitempty"`
+}
+
+// NewEnvelope returns an envelope of @p body hashed as the full content.
+func NewEnvelope(body string) *Envelope {
+	return &Envelope{Version: CurrentVersion, Body: body, Hash: Hash([]byte(body))}
+}
+
+// Hash returns the hex SHA-256 of @p content.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify returns true if @p content is the full content of the envelope.
+// An envelope without a hash verifies nothing.
+func (e *Envelope) Verify(content []byte) bool {
+	return e.Hash != "" && e.Hash == Hash(content)
+}
+
+// Validate checks the fields of the envelope.
+func (e *Envelope) Validate() error {
+	if e.Version < 1 || e.Version > CurrentVersion {
+		return errors.InvalidArgf("unsupported envelope version %v", e.Version)
+	}
+	for i, media := range e.Media {
+		if media.URL == "" {
+			return errors.InvalidArgf("media %v: empty url", i)
+		}
+	}
+	for _, tag := range e.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return errors.InvalidArg("empty tag")
+		}
+	}
+	for id, url := range e.Links {
+		if id == "" || url == "" {
+			return errors.InvalidArgf("invalid link %q: %q", id, url)
+		}
+	}
+	if e.Hash != "" {
+		if b, err := hex.DecodeString(e.Hash); err != nil || len(b) != sha256.Size {
+			return errors.InvalidArgf("invalid content hash %q", e.Hash)
+		}
+	}
+	return nil
+}
+
+// Encode returns the envelope as post content.
+func (e *Envelope) Encode() (string, error) {
+	if err := e.Validate(); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(e); err != nil {
+		return "", errors.InvalidArg("failed to encode envelope").AddCause(err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// Parse returns the envelope of post @p content. Content that is not an
+// envelope is the body of a version 0 envelope.
+func Parse(content string) (*Envelope, error) {
+	if !strings.HasPrefix(content, "{") {
+		return &Envelope{Body: content}, nil
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &probe); err != nil {
+		return &Envelope{Body: content}, nil
+	}
+	if _, ok := probe["v"]; !ok {
+		return &Envelope{Body: content}, nil
+	}
+	envelope := new(Envelope)
+	if err := json.Unmarshal([]byte(content), envelope); err != nil {
+		return nil, errors.UnmarshaFailed("invalid envelope").AddCause(err)
+	}
+	if err := envelope.Validate(); err != nil {
+		return nil, err
+	}
+	return envelope, nil
+}
+
+// WithLinks returns @p content with @p links added to its envelope. Content
+// that is not an envelope becomes the body of one.
+func WithLinks(content string, links map[string]string) (string, error) {
+	if len(links) == 0 {
+		return content, nil
+	}
+	envelope, err := Parse(content)
+	if err != nil {
+		return "", err
+	}
+	if envelope.Version == 0 {
+		envelope.Version = CurrentVersion
+	}
+	if envelope.Links == nil {
+		envelope.Links = map[string]string{}
+	}
+	for id, url := range links {
+		envelope.Links[id] = url
+	}
+	return envelope.Encode()
+}
+
+// Validate checks a post of @p author with @p postID, @p title and
+// @p content against @p limits.
+func Validate(limits Limits, author, postID, title, content string) error {
+	if !util.CheckUsername(author) {
+		return errors.InvalidArgf("invalid author %q", author)
+	}
+	if postID == "" || len(postID) > limits.MaxPostIDLength {
+		return errors.InvalidArgf("post id must be 1 to %v bytes, got %v", limits.MaxPostIDLength, len(postID))
+	}
+	if n := utf8.RuneCountInString(title); n > limits.MaxTitleLength {
+		return errors.InvalidArgf("title must be at most %v characters, got %v", limits.MaxTitleLength, n)
+	}
+	if n := utf8.RuneCountInString(content); n > limits.MaxContentLength {
+		return errors.InvalidArgf("content must be at most %v characters, got %v", limits.MaxContentLength, n)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package post
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linotypes "github.com/lino-network/lino/types"
+	postmodel "github.com/lino-network/lino/x/post/model"
+)
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		content   string
+		expect    *Envelope
+		expectErr bool
+	}{
+		"envelope": {
+			content: `{"v":1,"body":"<b>hi</b>","tags":["go"],"links":{"img":"https://a.b/c.png"}}`,
+			expect: &Envelope{
+				Version: 1, Body: "<b>hi</b>", Tags: []string{"go"},
+				Links: map[string]string{"img": "https://a.b/c.png"},
+			},
+		},
+		"plain text": {
+			content: "hello",
+			expect:  &Envelope{Body: "hello"},
+		},
+		"other json": {
+			content: `{"title":"x"}`,
+			expect:  &Envelope{Body: `{"title":"x"}`},
+		},
+		"future version": {
+			content:   `{"v":2,"body":"x"}`,
+			expectErr: true,
+		},
+		"invalid hash": {
+			content:   `{"v":1,"body":"x","hash":"abc"}`,
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		envelope, err := Parse(tc.content)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if !reflect.DeepEqual(envelope, tc.expect) {
+			t.Errorf("%s: diff envelope, got %+v, want %+v", testName, envelope, tc.expect)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	envelope := NewEnvelope("<p>hello & welcome</p>")
+	envelope.Media = []Media{{Type: "image/png", URL: "https://a.b/c.png"}}
+	envelope.Tags = []string{"news"}
+	content, err := envelope.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode, got err %v", err)
+	}
+	if strings.Contains(content, `\u003c`) || strings.HasSuffix(content, "\n") {
+		t.Errorf("content is not compact: %s", content)
+	}
+	parsed, err := Parse(content)
+	if err != nil {
+		t.Fatalf("failed to parse, got err %v", err)
+	}
+	if !reflect.DeepEqual(parsed, envelope) {
+		t.Errorf("diff envelope, got %+v, want %+v", parsed, envelope)
+	}
+	if !parsed.Verify([]byte("<p>hello & welcome</p>")) || parsed.Verify([]byte("other")) {
+		t.Errorf("diff verify")
+	}
+
+	if _, err := (&Envelope{Body: "x"}).Encode(); err == nil {
+		t.Errorf("version 0: expect err")
+	}
+}
+
+func TestWithLinks(t *testing.T) {
+	testCases := map[string]struct {
+		content string
+		links   map[string]string
+		expect  string
+	}{
+		"no links": {
+			content: "hello",
+			expect:  "hello",
+		},
+		"plain text": {
+			content: "hello",
+			links:   map[string]string{"b": "https://b", "a": "https://a"},
+			expect:  `{"v":1,"body":"hello","links":{"a":"https://a","b":"https://b"}}`,
+		},
+		"envelope": {
+			content: `{"v":1,"body":"hello","tags":["t"],"links":{"a":"https://old"}}`,
+			links:   map[string]string{"a": "https://a"},
+			expect:  `{"v":1,"body":"hello","tags":["t"],"links":{"a":"https://a"}}`,
+		},
+	}
+
+	for testName, tc := range testCases {
+		content, err := WithLinks(tc.content, tc.links)
+		if err != nil {
+			t.Errorf("%s: failed to add links, got err %v", testName, err)
+			continue
+		}
+		if content != tc.expect {
+			t.Errorf("%s: diff content, got %s, want %s", testName, content, tc.expect)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := map[string]struct {
+		author    string
+		postID    string
+		title     string
+		content   string
+		expectErr bool
+	}{
+		"valid": {
+			author: "alice", postID: "p1", title: strings.Repeat("題", 100), content: strings.Repeat("文", 1000),
+		},
+		"invalid author": {
+			author: "A", postID: "p1", expectErr: true,
+		},
+		"empty post id": {
+			author: "alice", expectErr: true,
+		},
+		"long post id": {
+			author: "alice", postID: strings.Repeat("p", 51), expectErr: true,
+		},
+		"long title": {
+			author: "alice", postID: "p1", title: strings.Repeat("t", 101), expectErr: true,
+		},
+		"long content": {
+			author: "alice", postID: "p1", content: strings.Repeat("c", 1001), expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		err := Validate(DefaultLimits, tc.author, tc.postID, tc.title, tc.content)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+		}
+	}
+}
+
+type fakeChain struct {
+	posts map[string]*postmodel.Post
+}
+
+func (c *fakeChain) GetPostInfo(ctx context.Context, author, postID string) (*postmodel.Post, error) {
+	post, ok := c.posts[author+"#"+postID]
+	if !ok {
+		return nil, errors.EmptyResponse("post is not found")
+	}
+	copied := *post
+	return &copied, nil
+}
+
+func (c *fakeChain) GetUserAllPosts(ctx context.Context, username string) (map[string]*postmodel.Post, error) {
+	posts := map[string]*postmodel.Post{}
+	for permlink, post := range c.posts {
+		if string(post.Author) == username {
+			posts[permlink] = post
+		}
+	}
+	return posts, nil
+}
+
+func (c *fakeChain) CreatePost(
+	ctx context.Context, author, postID, title, content, createdBy string, preauth bool,
+	privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	c.posts[author+"#"+postID] = &postmodel.Post{
+		Author:    linotypes.AccountKey(author),
+		PostID:    postID,
+		Title:     title,
+		Content:   content,
+		CreatedBy: linotypes.AccountKey(createdBy),
+		CreatedAt: int64(len(c.posts)),
+	}
+	return &model.BroadcastResponse{}, nil
+}
+
+func (c *fakeChain) UpdatePost(
+	ctx context.Context, author, title, postID, content string, links map[string]string,
+	privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	post := c.posts[author+"#"+postID]
+	post.Title, post.Content = title, content
+	return &model.BroadcastResponse{}, nil
+}
+
+func (c *fakeChain) DeletePost(ctx context.Context, author, postID string, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	post := c.posts[author+"#"+postID]
+	post.Title, post.Content, post.IsDeleted = "", "", true
+	return &model.BroadcastResponse{}, nil
+}
+
+func TestPublisher(t *testing.T) {
+	ctx := context.Background()
+	chain := &fakeChain{posts: map[string]*postmodel.Post{
+		"alice#legacy": {Author: "alice", PostID: "legacy", Title: "old", Content: "plain", CreatedAt: -1},
+	}}
+	publisher := NewPublisher(chain)
+
+	envelope := NewEnvelope("hello")
+	envelope.Tags = []string{"news"}
+	if _, err := publisher.Create(ctx, Draft{Author: "alice", PostID: "p1", Title: "Hi", Envelope: envelope}, "key"); err != nil {
+		t.Fatalf("create: got err %v", err)
+	}
+	if created := chain.posts["alice#p1"]; created.CreatedBy != "alice" {
+		t.Errorf("create: diff created by, got %v", created.CreatedBy)
+	}
+	tooLong := NewEnvelope(strings.Repeat("x", 1000))
+	if _, err := publisher.Create(ctx, Draft{Author: "alice", PostID: "p2", Envelope: tooLong}, "key"); err == nil {
+		t.Errorf("create too long: expect err")
+	}
+
+	_, err := publisher.Update(ctx, "alice", "p1", func(post *Post) error {
+		post.Title = "Hi again"
+		post.Envelope.Links = map[string]string{"src": "https://a.b"}
+		return nil
+	}, "key")
+	if err != nil {
+		t.Fatalf("update: got err %v", err)
+	}
+	updated, _ := publisher.Get(ctx, "alice", "p1")
+	expect := &Envelope{
+		Version: 1, Body: "hello", Tags: []string{"news"},
+		Links: map[string]string{"src": "https://a.b"}, Hash: Hash([]byte("hello")),
+	}
+	if updated.Title != "Hi again" || !reflect.DeepEqual(updated.Envelope, expect) {
+		t.Errorf("update: diff post, got %+v %+v", updated, updated.Envelope)
+	}
+
+	if _, err := publisher.Update(ctx, "alice", "legacy", func(post *Post) error { return nil }, "key"); err != nil {
+		t.Fatalf("update legacy: got err %v", err)
+	}
+	if content := chain.posts["alice#legacy"].Content; content != `{"v":1,"body":"plain"}` {
+		t.Errorf("update legacy: diff content, got %s", content)
+	}
+
+	deleted, _, err := publisher.Delete(ctx, "alice", "legacy", "key")
+	if err != nil || deleted.Envelope.Body != "plain" {
+		t.Errorf("delete: diff post, got %+v, err %v", deleted, err)
+	}
+	if _, err := publisher.Update(ctx, "alice", "legacy", func(post *Post) error { return nil }, "key"); err == nil {
+		t.Errorf("update deleted: expect err")
+	}
+
+	posts, err := publisher.List(ctx, "alice")
+	if err != nil || len(posts) != 1 || posts[0].PostID != "p1" {
+		t.Errorf("list: diff posts, got %+v, err %v", posts, err)
+	}
+}
@@ -0,0 +1,191 @@
+package post
+
+import (
+	"context"
+	"sort"
+
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	postmodel "github.com/lino-network/lino/x/post/model"
+)
+
+// Source provides posts. *query.Query implements it.
+type Source interface {
+	GetPostInfo(ctx context.Context, author, postID string) (*postmodel.Post, error)
+	GetUserAllPosts(ctx context.Context, username string) (map[string]*postmodel.Post, error)
+}
+
+// Chain reads and broadcasts posts. *api.API implements it.
+type Chain interface {
+	Source
+	CreatePost(
+		ctx context.Context, author, postID, title, content, createdBy string, preauth bool,
+		privKeyHex string) (*model.BroadcastResponse, errors.Error)
+	UpdatePost(
+		ctx context.Context, author, title, postID, content string, links map[string]string,
+		privKeyHex string) (*model.BroadcastResponse, errors.Error)
+	DeletePost(ctx context.Context, author, postID string, privKeyHex string) (*model.BroadcastResponse, errors.Error)
+}
+
+// Post is a post with its content parsed.
+type Post struct {
+	Author    string    `json:"author"`
+	PostID    string    `json:"post_id"`
+	Title     string    `json:"title"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt int64     `json:"created_at"`
+	UpdatedAt int64     `json:"updated_at"`
+	IsDeleted bool      `json:"is_deleted"`
+	Envelope  *Envelope `json:"envelope"`
+}
+
+// NewPost returns @p post with its content parsed.
+func NewPost(post *postmodel.Post) (*Post, error) {
+	envelope, err := Parse(post.Content)
+	if err != nil {
+		return nil, err
+	}
+	return &Post{
+		Author:    string(post.Author),
+		PostID:    post.PostID,
+		Title:     post.Title,
+		CreatedBy: string(post.CreatedBy),
+		CreatedAt: post.CreatedAt,
+		UpdatedAt: post.UpdatedAt,
+		IsDeleted: post.IsDeleted,
+		Envelope:  envelope,
+	}, nil
+}
+
+// Draft is a post to create.
+type Draft struct {
+	Author   string
+	PostID   string
+	Title    string
+	Envelope *Envelope
+	// CreatedBy is the app creating the post for Author. It signs the post
+	// unless Preauth is set, in which case Author does. Empty is Author.
+	CreatedBy string
+	Preauth   bool
+}
+
+// Publisher creates, updates and reads posts with envelope content.
+type Publisher struct {
+	chain  Chain
+	limits Limits
+}
+
+// NewPublisher returns an instance of Publisher on @p chain checking posts
+// against DefaultLimits.
+func NewPublisher(chain Chain) *Publisher {
+	return &Publisher{chain: chain, limits: DefaultLimits}
+}
+
+// Create creates the post of @p draft, signed with @p privKeyHex.
+func (p *Publisher) Create(ctx context.Context, draft Draft, privKeyHex string) (*model.BroadcastResponse, error) {
+	if draft.Envelope == nil {
+		return nil, errors.InvalidArg("no envelope")
+	}
+	content, err := draft.Envelope.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(p.limits, draft.Author, draft.PostID, draft.Title, content); err != nil {
+		return nil, err
+	}
+	createdBy := draft.CreatedBy
+	if createdBy == "" {
+		createdBy = draft.Author
+	}
+	resp, linoErr := p.chain.CreatePost(
+		ctx, draft.Author, draft.PostID, draft.Title, content, createdBy, draft.Preauth, privKeyHex)
+	if linoErr != nil {
+		return resp, linoErr
+	}
+	return resp, nil
+}
+
+// Update reads the post of @p author with @p postID, lets @p edit change its
+// title and envelope, and updates it with @p privKeyHex of the author. The
+// fields @p edit leaves alone are kept, and a version 0 envelope is upgraded.
+func (p *Publisher) Update(
+	ctx context.Context, author, postID string, edit func(post *Post) error, privKeyHex string) (*model.BroadcastResponse, error) {
+	post, err := p.Get(ctx, author, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.IsDeleted {
+		return nil, errors.InvalidArgf("post %s#%s is deleted", author, postID)
+	}
+	if err := edit(post); err != nil {
+		return nil, err
+	}
+	if post.Envelope == nil {
+		return nil, errors.InvalidArg("no envelope")
+	}
+	if post.Envelope.Version == 0 {
+		post.Envelope.Version = CurrentVersion
+	}
+	content, err := post.Envelope.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(p.limits, author, postID, post.Title, content); err != nil {
+		return nil, err
+	}
+	resp, linoErr := p.chain.UpdatePost(ctx, author, post.Title, postID, content, nil, privKeyHex)
+	if linoErr != nil {
+		return resp, linoErr
+	}
+	return resp, nil
+}
+
+// Delete deletes the post of @p author with @p postID and returns it as it
+// was, as the chain clears the content of deleted posts.
+func (p *Publisher) Delete(ctx context.Context, author, postID, privKeyHex string) (*Post, *model.BroadcastResponse, error) {
+	post, err := p.Get(ctx, author, postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, linoErr := p.chain.DeletePost(ctx, author, postID, privKeyHex)
+	if linoErr != nil {
+		return post, resp, linoErr
+	}
+	return post, resp, nil
+}
+
+// Get returns the post of @p author with @p postID.
+func (p *Publisher) Get(ctx context.Context, author, postID string) (*Post, error) {
+	info, err := p.chain.GetPostInfo(ctx, author, postID)
+	if err != nil {
+		return nil, err
+	}
+	return NewPost(info)
+}
+
+// List returns the posts of @p author that are not deleted, oldest first.
+// Posts whose envelope is invalid are skipped.
+func (p *Publisher) List(ctx context.Context, author string) ([]*Post, error) {
+	infos, err := p.chain.GetUserAllPosts(ctx, author)
+	if err != nil {
+		return nil, err
+	}
+	posts := []*Post{}
+	for _, info := range infos {
+		if info.IsDeleted {
+			continue
+		}
+		post, err := NewPost(info)
+		if err != nil {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt != posts[j].CreatedAt {
+			return posts[i].CreatedAt < posts[j].CreatedAt
+		}
+		return posts[i].PostID < posts[j].PostID
+	})
+	return posts, nil
+}
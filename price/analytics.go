@@ -0,0 +1,214 @@
+// Package price analyzes the LINO price history fed by validators and
+// converts amounts between LINO, MiniDollar and USD.
+package price
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	linotypes "github.com/lino-network/lino/types"
+	pricemodel "github.com/lino-network/lino/x/price/model"
+	valmodel "github.com/lino-network/lino/x/validator/model"
+)
+
+// Source provides the price history and validator feeds. *query.Query implements it.
+type Source interface {
+	GetCurrentPrice(ctx context.Context) (linotypes.MiniDollar, error)
+	GetHistoryPrice(ctx context.Context) ([]pricemodel.FeedHistory, error)
+	GetLastFeed(ctx context.Context, validator string) (*pricemodel.FedPrice, error)
+	GetAllValidators(ctx context.Context) (*valmodel.ValidatorList, error)
+}
+
+// Point is a price in effect from Time until the next point.
+type Point struct {
+	Price linotypes.MiniDollar `json:"price"`
+	Time  time.Time            `json:"time"`
+}
+
+// WindowStats are the statistics of the prices in effect during a window.
+type WindowStats struct {
+	From time.Time            `json:"from"`
+	To   time.Time            `json:"to"`
+	TWAP linotypes.MiniDollar `json:"twap"`
+	Min  linotypes.MiniDollar `json:"min"`
+	Max  linotypes.MiniDollar `json:"max"`
+	// Points is the number of prices in effect during the window.
+	Points int `json:"points"`
+}
+
+// ValidatorDeviation is how far the last price fed by a validator is from
+// the consensus price, in basis points, negative if below. It is clamped to
+// an int64 for outliers.
+type ValidatorDeviation struct {
+	Validator    string               `json:"validator"`
+	Fed          bool                 `json:"fed"`
+	Price        linotypes.MiniDollar `json:"price"`
+	UpdateAt     time.Time            `json:"update_at"`
+	DeviationBps int64                `json:"deviation_bps"`
+}
+
+// HistoryPoints returns the points of a price history, oldest first.
+func HistoryPoints(history []pricemodel.FeedHistory) []Point {
+	points := make([]Point, len(history))
+	for i, h := range history {
+		points[i] = Point{Price: h.Price, Time: time.Unix(h.UpdateAt, 0)}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points
+}
+
+// Window computes the stats of @p points, ordered oldest first, between
+// @p from and @p to. The price in effect at @p from is the last point
+// before it; the window starts at the first point if there is none.
+func Window(points []Point, from, to time.Time) (*WindowStats, error) {
+	if !from.Before(to) {
+		return nil, errors.InvalidArgf("invalid window [%v, %v)", from, to)
+	}
+
+	start := sort.Search(len(points), func(i int) bool { return points[i].Time.After(from) })
+	if start > 0 {
+		start--
+	}
+	end := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(to) })
+	if start >= end {
+		return nil, errors.EmptyResponsef("no price in window [%v, %v)", from, to)
+	}
+	inWindow := points[start:end]
+
+	stats := &WindowStats{
+		From:   from,
+		To:     to,
+		Min:    inWindow[0].Price,
+		Max:    inWindow[0].Price,
+		Points: len(inWindow),
+	}
+	weighted := sdk.ZeroInt()
+	total := int64(0)
+	for i, point := range inWindow {
+		begin, finish := point.Time, to
+		if begin.Before(from) {
+			begin = from
+		}
+		if i+1 < len(inWindow) {
+			finish = inWindow[i+1].Time
+		}
+		seconds := int64(finish.Sub(begin) / time.Second)
+		weighted = weighted.Add(point.Price.MulRaw(seconds))
+		total += seconds
+
+		if point.Price.LT(stats.Min) {
+			stats.Min = point.Price
+		}
+		if stats.Max.LT(point.Price) {
+			stats.Max = point.Price
+		}
+	}
+	if total == 0 {
+		stats.TWAP = inWindow[len(inWindow)-1].Price
+	} else {
+		stats.TWAP = linotypes.NewMiniDollarFromInt(weighted.QuoRaw(total))
+	}
+	return stats, nil
+}
+
+// DeviationBps returns how far @p price is from @p ref, in basis points of
+// @p ref. It is math.MaxInt64 if @p ref is not positive or the deviation
+// overflows an int64.
+func DeviationBps(price, ref linotypes.MiniDollar) int64 {
+	if !ref.IsPositive() {
+		return math.MaxInt64
+	}
+	diff := price.Sub(ref.Int)
+	if diff.IsNegative() {
+		diff = diff.Neg()
+	}
+	bps := diff.MulRaw(10000).Quo(ref.Int)
+	if !bps.IsInt64() {
+		return math.MaxInt64
+	}
+	return bps.Int64()
+}
+
+// Analyzer answers price questions against the chain.
+type Analyzer struct {
+	source Source
+}
+
+// NewAnalyzer returns an instance of Analyzer reading from @p source.
+func NewAnalyzer(source Source) *Analyzer {
+	return &Analyzer{source: source}
+}
+
+// Points returns the price history as points, oldest first.
+func (a *Analyzer) Points(ctx context.Context) ([]Point, error) {
+	history, err := a.source.GetHistoryPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return HistoryPoints(history), nil
+}
+
+// Window returns the stats of the price history between @p from and @p to.
+func (a *Analyzer) Window(ctx context.Context, from, to time.Time) (*WindowStats, error) {
+	points, err := a.Points(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Window(points, from, to)
+}
+
+// ValidatorDeviations returns the deviation of the last feed of every oncall
+// validator from the current price, ordered by username. Validators that
+// never fed are returned with Fed false.
+func (a *Analyzer) ValidatorDeviations(ctx context.Context) ([]*ValidatorDeviation, error) {
+	current, err := a.source.GetCurrentPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !current.IsPositive() {
+		return nil, errors.EmptyResponse("current price is not set")
+	}
+	list, err := a.source.GetAllValidators(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*ValidatorDeviation, len(list.Oncall))
+	errs := make([]error, len(list.Oncall))
+	var wg sync.WaitGroup
+	for i, validator := range list.Oncall {
+		wg.Add(1)
+		go func(i int, validator string) {
+			defer wg.Done()
+			res[i] = &ValidatorDeviation{Validator: validator}
+			fed, err := a.source.GetLastFeed(ctx, validator)
+			if err != nil {
+				if !errors.IsEmptyResponse(err) {
+					errs[i] = err
+				}
+				return
+			}
+			res[i].Fed = true
+			res[i].Price = fed.Price
+			res[i].UpdateAt = time.Unix(fed.UpdateAt, 0)
+			res[i].DeviationBps = DeviationBps(fed.Price, current)
+			if fed.Price.LT(current) {
+				res[i].DeviationBps = -res[i].DeviationBps
+			}
+		}(i, string(validator))
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Validator < res[j].Validator })
+	return res, nil
+}
@@ -0,0 +1,73 @@
+package price
+
+import (
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// usdPriceDecimals is the number of decimals of the USD price of one LINO
+// in the MiniDollar price of one coin: 10^10 MiniDollar per USD over 10^5
+// coins per LINO.
+const usdPriceDecimals = 5
+
+// CoinToMiniDollar returns the value of @p coin at @p price, the MiniDollar
+// price of one coin as returned by GetCurrentPrice.
+func CoinToMiniDollar(coin linotypes.Coin, price linotypes.MiniDollar) linotypes.MiniDollar {
+	return linotypes.NewMiniDollarFromInt(coin.Amount.Mul(price.Int))
+}
+
+// MiniDollarToCoin returns the coins worth @p value at @p price, rounded down.
+func MiniDollarToCoin(value, price linotypes.MiniDollar) (linotypes.Coin, error) {
+	if !price.IsPositive() {
+		return linotypes.NewCoinFromInt64(0), errors.InvalidArgf("invalid price %v", price)
+	}
+	return linotypes.NewCoin(value.Quo(price.Int)), nil
+}
+
+// ParseUSD parses a USD amount, e.g. "12.5", into MiniDollar. Amounts with
+// more than 10 decimals are rejected.
+func ParseUSD(usd string) (linotypes.MiniDollar, error) {
+	a, err := amount.ParseUSD(usd)
+	if err != nil {
+		return linotypes.NewMiniDollar(0), err
+	}
+	return a.MiniDollar()
+}
+
+// FormatUSD formats a MiniDollar amount in USD without trailing zeros.
+func FormatUSD(value linotypes.MiniDollar) string {
+	return amount.NewFromMiniDollar(value).String()
+}
+
+// PriceToUSD returns the USD price of one LINO given the MiniDollar price
+// of one coin.
+func PriceToUSD(price linotypes.MiniDollar) string {
+	return amount.NewFromUnits(price.Int, amount.Unit{Name: "USD", Decimals: usdPriceDecimals}).String()
+}
+
+// LNOToUSD returns the USD value of @p lno at @p price.
+func LNOToUSD(lno linotypes.LNO, price linotypes.MiniDollar) (string, error) {
+	a, err := amount.ParseLNO(lno)
+	if err != nil {
+		return "", err
+	}
+	usd, err := a.ToUSD(price)
+	if err != nil {
+		return "", err
+	}
+	return usd.String(), nil
+}
+
+// USDToLNO returns the LINO worth @p usd at @p price, rounded down to a coin.
+func USDToLNO(usd string, price linotypes.MiniDollar) (linotypes.LNO, error) {
+	a, err := amount.ParseUSD(usd)
+	if err != nil {
+		return "", err
+	}
+	lino, err := a.ToLINO(price)
+	if err != nil {
+		return "", err
+	}
+	return lino.String(), nil
+}
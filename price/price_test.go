@@ -0,0 +1,203 @@
+package price
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	linotypes "github.com/lino-network/lino/types"
+	pricemodel "github.com/lino-network/lino/x/price/model"
+	valmodel "github.com/lino-network/lino/x/validator/model"
+)
+
+var t0 = time.Unix(1600000000, 0)
+
+type fakeSource struct {
+	current linotypes.MiniDollar
+	history []pricemodel.FeedHistory
+	feeds   map[string]*pricemodel.FedPrice
+	oncall  []linotypes.AccountKey
+}
+
+func (s *fakeSource) GetCurrentPrice(ctx context.Context) (linotypes.MiniDollar, error) {
+	return s.current, nil
+}
+
+func (s *fakeSource) GetHistoryPrice(ctx context.Context) ([]pricemodel.FeedHistory, error) {
+	return s.history, nil
+}
+
+func (s *fakeSource) GetLastFeed(ctx context.Context, validator string) (*pricemodel.FedPrice, error) {
+	fed, ok := s.feeds[validator]
+	if !ok {
+		return nil, errors.EmptyResponse("last fed price is not found")
+	}
+	return fed, nil
+}
+
+func (s *fakeSource) GetAllValidators(ctx context.Context) (*valmodel.ValidatorList, error) {
+	return &valmodel.ValidatorList{Oncall: s.oncall}, nil
+}
+
+func TestConversions(t *testing.T) {
+	price := linotypes.NewMiniDollar(1200)
+
+	if usd := PriceToUSD(price); usd != "0.012" {
+		t.Errorf("diff price in usd, got %v, want 0.012", usd)
+	}
+	usd, err := LNOToUSD("1000.5", price)
+	if err != nil || usd != "12.006" {
+		t.Errorf("diff lno to usd, got %v %v, want 12.006", usd, err)
+	}
+	lno, err := USDToLNO("12.006", price)
+	if err != nil || lno != "1000.5" {
+		t.Errorf("diff usd to lno, got %v %v, want 1000.5", lno, err)
+	}
+	coin, err := MiniDollarToCoin(linotypes.NewMiniDollar(2500), price)
+	if err != nil || !coin.IsEqual(linotypes.NewCoinFromInt64(2)) {
+		t.Errorf("diff minidollar to coin, got %v %v, want 2", coin, err)
+	}
+	if _, err := MiniDollarToCoin(linotypes.NewMiniDollar(1), linotypes.NewMiniDollar(0)); err == nil {
+		t.Errorf("zero price should fail")
+	}
+}
+
+func TestParseAndFormatUSD(t *testing.T) {
+	testCases := map[string]struct {
+		usd          string
+		expectAmount int64
+		expectFormat string
+		expectErr    bool
+	}{
+		"whole dollars":     {usd: "12", expectAmount: 120000000000, expectFormat: "12"},
+		"cents":             {usd: "0.05", expectAmount: 500000000, expectFormat: "0.05"},
+		"one minidollar":    {usd: "0.0000000001", expectAmount: 1, expectFormat: "0.0000000001"},
+		"negative":          {usd: "-1.5", expectAmount: -15000000000, expectFormat: "-1.5"},
+		"too many decimals": {usd: "0.00000000001", expectErr: true},
+		"thousands comma":   {usd: "1,000", expectErr: true},
+		"exponent":          {usd: "1e3", expectErr: true},
+		"empty":             {usd: "", expectErr: true},
+	}
+
+	for testName, tc := range testCases {
+		amount, err := ParseUSD(tc.usd)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if amount.Int64() != tc.expectAmount {
+			t.Errorf("%s: diff amount, got %v, want %v", testName, amount, tc.expectAmount)
+		}
+		if format := FormatUSD(amount); format != tc.expectFormat {
+			t.Errorf("%s: diff format, got %v, want %v", testName, format, tc.expectFormat)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	history := []pricemodel.FeedHistory{
+		{Price: linotypes.NewMiniDollar(1300), UpdateAt: t0.Add(2 * time.Hour).Unix()},
+		{Price: linotypes.NewMiniDollar(1000), UpdateAt: t0.Unix()},
+		{Price: linotypes.NewMiniDollar(1200), UpdateAt: t0.Add(time.Hour).Unix()},
+	}
+	points := HistoryPoints(history)
+
+	testCases := map[string]struct {
+		from, to    time.Time
+		expectStats *WindowStats
+		expectErr   bool
+	}{
+		"whole history": {
+			from: t0, to: t0.Add(3 * time.Hour),
+			expectStats: &WindowStats{TWAP: linotypes.NewMiniDollar(1166), Min: linotypes.NewMiniDollar(1000),
+				Max: linotypes.NewMiniDollar(1300), Points: 3},
+		},
+		"starts between points": {
+			from: t0.Add(30 * time.Minute), to: t0.Add(90 * time.Minute),
+			expectStats: &WindowStats{TWAP: linotypes.NewMiniDollar(1100), Min: linotypes.NewMiniDollar(1000),
+				Max: linotypes.NewMiniDollar(1200), Points: 2},
+		},
+		"after last point": {
+			from: t0.Add(5 * time.Hour), to: t0.Add(6 * time.Hour),
+			expectStats: &WindowStats{TWAP: linotypes.NewMiniDollar(1300), Min: linotypes.NewMiniDollar(1300),
+				Max: linotypes.NewMiniDollar(1300), Points: 1},
+		},
+		"before first point": {
+			from: t0.Add(-2 * time.Hour), to: t0.Add(-time.Hour), expectErr: true,
+		},
+		"empty window": {
+			from: t0, to: t0, expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		stats, err := Window(points, tc.from, tc.to)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		tc.expectStats.From, tc.expectStats.To = tc.from, tc.to
+		if !reflect.DeepEqual(stats, tc.expectStats) {
+			t.Errorf("%s: diff stats, got %+v, want %+v", testName, stats, tc.expectStats)
+		}
+	}
+}
+
+func TestDeviationBps(t *testing.T) {
+	testCases := map[string]struct {
+		price     int64
+		ref       int64
+		expectBps int64
+	}{
+		"above":    {price: 1260, ref: 1200, expectBps: 500},
+		"below":    {price: 1188, ref: 1200, expectBps: 100},
+		"equal":    {price: 1200, ref: 1200, expectBps: 0},
+		"no ref":   {price: 1200, ref: 0, expectBps: math.MaxInt64},
+		"overflow": {price: math.MaxInt64, ref: 1, expectBps: math.MaxInt64},
+	}
+
+	for testName, tc := range testCases {
+		bps := DeviationBps(linotypes.NewMiniDollar(tc.price), linotypes.NewMiniDollar(tc.ref))
+		if bps != tc.expectBps {
+			t.Errorf("%s: diff deviation, got %v, want %v", testName, bps, tc.expectBps)
+		}
+	}
+}
+
+func TestValidatorDeviations(t *testing.T) {
+	outlier := linotypes.NewMiniDollarFromInt(sdk.NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), 80)))
+	analyzer := NewAnalyzer(&fakeSource{
+		current: linotypes.NewMiniDollar(1200),
+		oncall:  []linotypes.AccountKey{"carol", "alice", "bob", "dave"},
+		feeds: map[string]*pricemodel.FedPrice{
+			"alice": {Validator: "alice", Price: linotypes.NewMiniDollar(1260), UpdateAt: t0.Unix()},
+			"bob":   {Validator: "bob", Price: linotypes.NewMiniDollar(1188), UpdateAt: t0.Unix()},
+			"dave":  {Validator: "dave", Price: outlier, UpdateAt: t0.Unix()},
+		},
+	})
+
+	deviations, err := analyzer.ValidatorDeviations(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get deviations: %v", err)
+	}
+	expect := []*ValidatorDeviation{
+		{Validator: "alice", Fed: true, Price: linotypes.NewMiniDollar(1260), UpdateAt: t0, DeviationBps: 500},
+		{Validator: "bob", Fed: true, Price: linotypes.NewMiniDollar(1188), UpdateAt: t0, DeviationBps: -100},
+		{Validator: "carol"},
+		{Validator: "dave", Fed: true, Price: outlier, UpdateAt: t0, DeviationBps: math.MaxInt64},
+	}
+	if !reflect.DeepEqual(deviations, expect) {
+		t.Errorf("diff deviations, got %+v, want %+v", deviations, expect)
+	}
+}
@@ -2,8 +2,10 @@ package query
 
 import (
 	"context"
+	"sync"
 
 	"github.com/lino-network/lino-go/errors"
+	linomodel "github.com/lino-network/lino-go/model"
 	linotypes "github.com/lino-network/lino/types"
 	"github.com/lino-network/lino/x/developer/model"
 	"github.com/lino-network/lino/x/developer/types"
@@ -71,12 +73,40 @@ func (query *Query) GetAffiliated(ctx context.Context, developerName string) ([]
 		return nil, err
 	}
 	var affiliatedAccs []string
-	if err := query.transport.Cdc.UnmarshalJSON(resp, affiliatedAccs); err != nil {
+	if err := query.transport.Cdc.UnmarshalJSON(resp, &affiliatedAccs); err != nil {
 		return nil, err
 	}
 	return affiliatedAccs, nil
 }
 
+// GetAppPermission returns whether @p username is affiliated with @p app and
+// whether its IDA of @p app is authorized.
+func (query *Query) GetAppPermission(ctx context.Context, username, app string) (*linomodel.AppPermission, error) {
+	affiliated, err := query.GetAffiliated(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	permission := &linomodel.AppPermission{Username: username, App: app, Affiliated: username == app}
+	for _, acc := range affiliated {
+		if acc == username {
+			permission.Affiliated = true
+		}
+	}
+
+	balance, err := query.GetIDABalance(ctx, username, app)
+	if err != nil {
+		// apps without a live IDA have nothing to authorize.
+		linoe, ok := err.(errors.Error)
+		if ok && (linoe.BlockChainCode() == uint32(linotypes.CodeIDANotFound) ||
+			linoe.BlockChainCode() == uint32(linotypes.CodeIDARevoked)) {
+			return permission, nil
+		}
+		return nil, err
+	}
+	permission.IDAAuthorized = !balance.Unauthed
+	return permission, nil
+}
+
 // GetReservePool returns App affiliated account list
 func (query *Query) GetReservePool(ctx context.Context) (*model.ReservePool, error) {
 	resp, err := query.transport.Query(ctx, DeveloperKVStoreKey, types.QueryReservePool, []string{})
@@ -94,6 +124,15 @@ func (query *Query) GetReservePool(ctx context.Context) (*model.ReservePool, err
 func (query *Query) GetIDAStats(ctx context.Context, developerName string) (*model.AppIDAStats, error) {
 	resp, err := query.transport.Query(ctx, DeveloperKVStoreKey, types.QueryIDAStats, []string{developerName})
 	if err != nil {
+		linoe, ok := err.(errors.Error)
+		if ok && linoe.BlockChainCode() == uint32(linotypes.CodeIDANotFound) {
+			return nil, errors.EmptyResponse("ida is not found")
+		}
+		// the chain has no stats of revoked IDAs and deleted apps.
+		if ok && (linoe.BlockChainCode() == uint32(linotypes.CodeIDARevoked) ||
+			linoe.BlockChainCode() == uint32(linotypes.CodeDeveloperNotFound)) {
+			return nil, errors.EmptyResponse("ida stats are not found")
+		}
 		return nil, err
 	}
 	IDAStats := new(model.AppIDAStats)
@@ -103,16 +142,128 @@ func (query *Query) GetIDAStats(ctx context.Context, developerName string) (*mod
 	return IDAStats, nil
 }
 
-// // GetDevelopers returns a list of all developers.
-// func (query *Query) GetDevelopers(ctx context.Context) (*model.DeveloperList, error) {
-// 	resp, err := query.transport.Query(ctx, DeveloperKVStoreKey, DeveloperListSubStore, []string{})
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	developerList := new(model.DeveloperList)
-// 	if err := query.transport.Cdc.UnmarshalJSON(resp, developerList); err != nil {
-// 		return nil, err
-// 	}
-// 	return developerList, nil
-// }
+// GetAppIDAHolders returns the IDA banks of all holders of @p app's IDA,
+// keyed by username. Holders are fetched one leading character at a time.
+func (query *Query) GetAppIDAHolders(ctx context.Context, app string) (map[string]*model.IDABank, error) {
+	prefix := model.GetIDABalanceKey(linotypes.AccountKey(app), "")
+	iter := query.NewSubspaceIterator(DeveloperKVStoreKey, prefix, SubspaceOptions{ChunkBytes: UsernameChunkBytes})
+
+	holders := make(map[string]*model.IDABank)
+	err := iter.ForEach(ctx, func() interface{} { return new(model.IDABank) }, func(key []byte, value interface{}) error {
+		holders[string(key[len(prefix):])] = value.(*model.IDABank)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return holders, nil
+}
+
+// GetDevelopers returns all live developers, keyed by username.
+func (query *Query) GetDevelopers(ctx context.Context) (map[string]model.Developer, error) {
+	resp, err := query.transport.Query(ctx, DeveloperKVStoreKey, types.QueryDeveloperList, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	developers := make(map[string]model.Developer)
+	if err := query.transport.Cdc.UnmarshalJSON(resp, &developers); err != nil {
+		return nil, err
+	}
+	return developers, nil
+}
+
+// GetAppProfile returns the developer, IDA, IDA stats, affiliated accounts
+// and bandwidth info of an app, fetched concurrently.
+func (query *Query) GetAppProfile(ctx context.Context, app string) (*linomodel.AppProfile, error) {
+	profile := &linomodel.AppProfile{Username: app}
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	fetch := func(i int, f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = f()
+		}()
+	}
+
+	fetch(0, func() (err error) {
+		profile.Developer, err = query.GetDeveloper(ctx, app)
+		return err
+	})
+	fetch(1, func() (err error) {
+		profile.IDA, err = query.GetIDA(ctx, app)
+		return ignoreEmptyResponse(err)
+	})
+	fetch(2, func() (err error) {
+		profile.IDAStats, err = query.GetIDAStats(ctx, app)
+		return ignoreEmptyResponse(err)
+	})
+	fetch(3, func() (err error) {
+		profile.Affiliated, err = query.GetAffiliated(ctx, app)
+		return ignoreEmptyResponse(err)
+	})
+	fetch(4, func() (err error) {
+		profile.Bandwidth, err = query.GetAppBandwidthInfo(ctx, app)
+		return ignoreEmptyResponse(err)
+	})
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return profile, nil
+}
+
+// GetAllAppProfiles returns the profiles of all live developers, keyed by
+// username, fetching at most @p concurrency profiles at a time.
+func (query *Query) GetAllAppProfiles(ctx context.Context, concurrency int) (map[string]*linomodel.AppProfile, error) {
+	if concurrency <= 0 {
+		return nil, errors.InvalidArgf("invalid concurrency %v", concurrency)
+	}
+	developers, err := query.GetDevelopers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var mtx sync.Mutex
+	var firstErr error
+	profiles := make(map[string]*linomodel.AppProfile)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for app := range developers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(app string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			profile, err := query.GetAppProfile(ctx, app)
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			profiles[app] = profile
+		}(app)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return profiles, nil
+}
+
+func ignoreEmptyResponse(err error) error {
+	if errors.IsEmptyResponse(err) {
+		return nil
+	}
+	return err
+}
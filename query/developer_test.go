@@ -0,0 +1,197 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/transport"
+	linotypes "github.com/lino-network/lino/types"
+	bandwidthtypes "github.com/lino-network/lino/x/bandwidth/types"
+	"github.com/lino-network/lino/x/developer/model"
+	"github.com/lino-network/lino/x/developer/types"
+)
+
+// abciResult is the answer of the fake node to a query path.
+type abciResult struct {
+	code  uint32
+	value interface{}
+}
+
+// newFakeNode returns a query of a node answering custom queries of
+// @p results by path, and code 1 to others.
+func newFakeNode(t *testing.T, results map[string]abciResult) *Query {
+	query := NewQuery(nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Params struct {
+				Path string `json:"path"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid rpc request, got err %v", err)
+		}
+		res, ok := results[req.Params.Path]
+		if !ok {
+			res = abciResult{code: 1}
+		}
+		var value []byte
+		if res.value != nil {
+			value = query.transport.Cdc.MustMarshalJSON(res.value)
+		}
+		resp, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result": map[string]interface{}{
+				"response": map[string]interface{}{"code": res.code, "value": value},
+			},
+		})
+		w.Write(resp)
+	}))
+	t.Cleanup(server.Close)
+	query.transport = transport.NewTransportFromArgs("test", server.URL, 0)
+	return query
+}
+
+func developerPath(query, app string) string {
+	return strings.Join([]string{"/custom", DeveloperKVStoreKey, query, app}, "/")
+}
+
+func TestGetIDAStats(t *testing.T) {
+	testCases := map[string]struct {
+		result      abciResult
+		expectEmpty bool
+		expectErr   bool
+	}{
+		"stats": {
+			result: abciResult{value: model.AppIDAStats{Total: linotypes.NewMiniDollar(10)}},
+		},
+		"no ida": {
+			result:      abciResult{code: uint32(linotypes.CodeIDANotFound)},
+			expectEmpty: true,
+		},
+		"revoked ida": {
+			result:      abciResult{code: uint32(linotypes.CodeIDARevoked)},
+			expectEmpty: true,
+		},
+		"deleted app": {
+			result:      abciResult{code: uint32(linotypes.CodeDeveloperNotFound)},
+			expectEmpty: true,
+		},
+		"other error": {
+			result:    abciResult{code: uint32(linotypes.CodeInvalidUsername)},
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		query := newFakeNode(t, map[string]abciResult{developerPath(types.QueryIDAStats, "app"): tc.result})
+		stats, err := query.GetIDAStats(context.Background(), "app")
+		isEmpty := errors.IsEmptyResponse(err)
+		if isEmpty != tc.expectEmpty || (err != nil && !isEmpty) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect empty %v, expect err %v", testName, err, tc.expectEmpty, tc.expectErr)
+		}
+		if err == nil && !stats.Total.Equal(linotypes.NewMiniDollar(10)) {
+			t.Errorf("%s: diff stats, got %+v", testName, stats)
+		}
+	}
+}
+
+func TestGetAllAppProfilesRevoked(t *testing.T) {
+	query := newFakeNode(t, map[string]abciResult{
+		strings.Join([]string{"/custom", DeveloperKVStoreKey, types.QueryDeveloperList}, "/"): {value: map[string]model.Developer{
+			"live":    {Username: "live"},
+			"revoked": {Username: "revoked"},
+		}},
+		developerPath(types.QueryDeveloper, "live"):     {value: model.Developer{Username: "live"}},
+		developerPath(types.QueryDeveloper, "revoked"):  {value: model.Developer{Username: "revoked"}},
+		developerPath(types.QueryIDA, "live"):           {value: model.AppIDA{App: "live", MiniIDAPrice: linotypes.NewMiniDollar(1)}},
+		developerPath(types.QueryIDA, "revoked"):        {value: model.AppIDA{App: "revoked", IsRevoked: true}},
+		developerPath(types.QueryIDAStats, "live"):      {value: model.AppIDAStats{Total: linotypes.NewMiniDollar(10)}},
+		developerPath(types.QueryIDAStats, "revoked"):   {code: uint32(linotypes.CodeIDARevoked)},
+		developerPath(types.QueryAffiliated, "live"):    {value: []string{}},
+		developerPath(types.QueryAffiliated, "revoked"): {value: []string{}},
+		strings.Join([]string{"/custom", BandwidthKVStoreKey, bandwidthtypes.QueryAppBandwidthInfo, "live"}, "/"): {
+			code: uint32(linotypes.CodeAppBandwidthInfoNotFound)},
+		strings.Join([]string{"/custom", BandwidthKVStoreKey, bandwidthtypes.QueryAppBandwidthInfo, "revoked"}, "/"): {
+			code: uint32(linotypes.CodeAppBandwidthInfoNotFound)},
+	})
+
+	profiles, err := query.GetAllAppProfiles(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("failed to get profiles, got err %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("diff profiles, got %d, expect 2", len(profiles))
+	}
+	if p := profiles["revoked"]; p.IDA == nil || !p.IDA.IsRevoked || p.IDAStats != nil {
+		t.Errorf("diff revoked profile, got %+v", p)
+	}
+	if p := profiles["live"]; p.IDAStats == nil || !p.IDAStats.Total.Equal(linotypes.NewMiniDollar(10)) {
+		t.Errorf("diff live profile, got %+v", p)
+	}
+}
+
+func TestGetAppPermission(t *testing.T) {
+	testCases := map[string]struct {
+		username        string
+		balance         abciResult
+		expectAffiliate bool
+		expectAuthed    bool
+		expectErr       bool
+	}{
+		"affiliated and authorized": {
+			username:        "bob",
+			balance:         abciResult{value: types.QueryResultIDABalance{Amount: "1"}},
+			expectAffiliate: true,
+			expectAuthed:    true,
+		},
+		"app itself": {
+			username:        "app",
+			balance:         abciResult{value: types.QueryResultIDABalance{Amount: "1"}},
+			expectAffiliate: true,
+			expectAuthed:    true,
+		},
+		"unauthorized ida": {
+			username: "carol",
+			balance:  abciResult{value: types.QueryResultIDABalance{Amount: "1", Unauthed: true}},
+		},
+		"no ida": {
+			username: "carol",
+			balance:  abciResult{code: uint32(linotypes.CodeIDANotFound)},
+		},
+		"revoked ida": {
+			username: "carol",
+			balance:  abciResult{code: uint32(linotypes.CodeIDARevoked)},
+		},
+		"no account": {
+			username:  "nobody",
+			balance:   abciResult{code: uint32(linotypes.CodeAccountNotFound)},
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		query := newFakeNode(t, map[string]abciResult{
+			developerPath(types.QueryAffiliated, "app"):              {value: []string{"bob"}},
+			developerPath(types.QueryIDABalance, "app/"+tc.username): tc.balance,
+		})
+		permission, err := query.GetAppPermission(context.Background(), tc.username, "app")
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if permission.Username != tc.username || permission.App != "app" ||
+			permission.Affiliated != tc.expectAffiliate || permission.IDAAuthorized != tc.expectAuthed {
+			t.Errorf("%s: diff permission, got %+v", testName, permission)
+		}
+	}
+}
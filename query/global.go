@@ -0,0 +1,22 @@
+package query
+
+import (
+	"context"
+
+	"github.com/lino-network/lino/x/global/model"
+	"github.com/lino-network/lino/x/global/types"
+)
+
+// GetGlobalTime returns the chain start time and the time of the last block.
+// Days of stake stats are counted from the chain start time.
+func (query *Query) GetGlobalTime(ctx context.Context) (*model.GlobalTime, error) {
+	resp, err := query.transport.Query(ctx, GlobalKVStoreKey, types.QueryGlobalTime, []string{})
+	if err != nil {
+		return nil, err
+	}
+	globalTime := new(model.GlobalTime)
+	if err := query.transport.Cdc.UnmarshalJSON(resp, globalTime); err != nil {
+		return nil, err
+	}
+	return globalTime, nil
+}
@@ -0,0 +1,205 @@
+package query
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linotypes "github.com/lino-network/lino/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// GetValidatorSet returns the tendermint validator set at a certain height,
+// or the latest one if @p height is 0.
+func (query *Query) GetValidatorSet(ctx context.Context, height int64) (*ctypes.ResultValidators, error) {
+	resp, err := query.transport.QueryValidators(ctx, heightOrLatest(height))
+	if err != nil {
+		return nil, errors.QueryFailf("GetValidatorSet err").AddCause(err)
+	}
+
+	return resp, nil
+}
+
+// GetCommit returns the signed header of a block at a certain height, or
+// the latest one if @p height is 0.
+func (query *Query) GetCommit(ctx context.Context, height int64) (*ctypes.ResultCommit, error) {
+	resp, err := query.transport.QueryCommit(ctx, heightOrLatest(height))
+	if err != nil {
+		return nil, errors.QueryFailf("GetCommit err").AddCause(err)
+	}
+
+	return resp, nil
+}
+
+// GetNetInfo returns the listeners and peers of the node.
+func (query *Query) GetNetInfo(ctx context.Context) (*ctypes.ResultNetInfo, error) {
+	resp, err := query.transport.QueryNetInfo(ctx)
+	if err != nil {
+		return nil, errors.QueryFailf("GetNetInfo err").AddCause(err)
+	}
+
+	return resp, nil
+}
+
+// GetGenesis returns the genesis document of the chain.
+func (query *Query) GetGenesis(ctx context.Context) (*ctypes.ResultGenesis, error) {
+	resp, err := query.transport.QueryGenesis(ctx)
+	if err != nil {
+		return nil, errors.QueryFailf("GetGenesis err").AddCause(err)
+	}
+
+	return resp, nil
+}
+
+// GetConsensusState returns the height, round and step of the consensus
+// the node is in, with a summary of the votes.
+func (query *Query) GetConsensusState(ctx context.Context) (*ctypes.ResultConsensusState, error) {
+	resp, err := query.transport.QueryConsensusState(ctx)
+	if err != nil {
+		return nil, errors.QueryFailf("GetConsensusState err").AddCause(err)
+	}
+
+	return resp, nil
+}
+
+// DumpConsensusState returns the full consensus state of the node and of
+// its peers.
+func (query *Query) DumpConsensusState(ctx context.Context) (*ctypes.ResultDumpConsensusState, error) {
+	resp, err := query.transport.QueryDumpConsensusState(ctx)
+	if err != nil {
+		return nil, errors.QueryFailf("DumpConsensusState err").AddCause(err)
+	}
+
+	return resp, nil
+}
+
+// GetUnconfirmedTxs returns at most @p limit decoded txs in the mempool of the node.
+func (query *Query) GetUnconfirmedTxs(ctx context.Context, limit int) (*model.UnconfirmedTxs, error) {
+	if limit <= 0 {
+		return nil, errors.InvalidArgf("invalid limit %v", limit)
+	}
+	resp, err := query.transport.QueryUnconfirmedTxs(ctx, limit)
+	if err != nil {
+		return nil, errors.QueryFailf("GetUnconfirmedTxs err").AddCause(err)
+	}
+
+	res := &model.UnconfirmedTxs{
+		Count:      resp.Count,
+		Total:      resp.Total,
+		TotalBytes: resp.TotalBytes,
+		Txs:        make([]auth.StdTx, len(resp.Txs)),
+	}
+	for i, tx := range resp.Txs {
+		if err := query.transport.Cdc.UnmarshalJSON(tx, &res.Txs[i]); err != nil {
+			return nil, errors.QueryFailf("Unmarshal tx err").AddCause(err)
+		}
+	}
+	return res, nil
+}
+
+// GetNumUnconfirmedTxs returns the number and total bytes of txs in the
+// mempool of the node, without the txs.
+func (query *Query) GetNumUnconfirmedTxs(ctx context.Context) (*ctypes.ResultUnconfirmedTxs, error) {
+	resp, err := query.transport.QueryNumUnconfirmedTxs(ctx)
+	if err != nil {
+		return nil, errors.QueryFailf("GetNumUnconfirmedTxs err").AddCause(err)
+	}
+
+	return resp, nil
+}
+
+// GetValidatorUsernames returns the usernames of all oncall, standby,
+// candidate and jailed validators, keyed by the uppercase hex address of
+// their tendermint validator key.
+func (query *Query) GetValidatorUsernames(ctx context.Context) (map[string]string, error) {
+	list, err := query.GetAllValidators(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[linotypes.AccountKey]bool{}
+	usernames := []linotypes.AccountKey{}
+	for _, group := range [][]linotypes.AccountKey{list.Oncall, list.Standby, list.Candidates, list.Jail} {
+		for _, username := range group {
+			if !seen[username] {
+				seen[username] = true
+				usernames = append(usernames, username)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	addrs := make([]string, len(usernames))
+	errs := make([]error, len(usernames))
+	for i, username := range usernames {
+		wg.Add(1)
+		go func(i int, username linotypes.AccountKey) {
+			defer wg.Done()
+			val, err := query.GetValidator(ctx, string(username))
+			if err != nil {
+				errs[i] = ignoreEmptyResponse(err)
+				return
+			}
+			if val.PubKey != nil {
+				addrs[i] = val.PubKey.Address().String()
+			}
+		}(i, username)
+	}
+	wg.Wait()
+
+	res := make(map[string]string, len(usernames))
+	for i, username := range usernames {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if addrs[i] != "" {
+			res[addrs[i]] = string(username)
+		}
+	}
+	return res, nil
+}
+
+// GetNamedValidatorSet returns the tendermint validator set at a certain
+// height, or the latest one if @p height is 0, with the Lino username of
+// every validator. Usernames are resolved against the current validators,
+// so validators that have since left have an empty username.
+func (query *Query) GetNamedValidatorSet(ctx context.Context, height int64) (*model.NamedValidatorSet, error) {
+	set, err := query.GetValidatorSet(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	usernames, err := query.GetValidatorUsernames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NameValidators(set, usernames), nil
+}
+
+// NameValidators attaches the usernames returned by GetValidatorUsernames
+// to a tendermint validator set.
+func NameValidators(set *ctypes.ResultValidators, usernames map[string]string) *model.NamedValidatorSet {
+	res := &model.NamedValidatorSet{
+		Height:     set.BlockHeight,
+		Validators: make([]*model.NamedValidator, len(set.Validators)),
+	}
+	for i, val := range set.Validators {
+		addr := val.Address.String()
+		res.Validators[i] = &model.NamedValidator{
+			Username:         usernames[addr],
+			Address:          addr,
+			PubKey:           val.PubKey,
+			VotingPower:      val.VotingPower,
+			ProposerPriority: val.ProposerPriority,
+		}
+	}
+	return res
+}
+
+func heightOrLatest(height int64) *int64 {
+	if height <= 0 {
+		return nil
+	}
+	return &height
+}
@@ -0,0 +1,254 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/transport"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// newFakeRPC returns a query of a node answering the tendermint rpc
+// methods of @p results, and a method not found error to others. The node
+// waits for @p delay before answering.
+func newFakeRPC(t *testing.T, results map[string]interface{}, delay time.Duration) *Query {
+	query := NewQuery(nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid rpc request, got err %v", err)
+		}
+		time.Sleep(delay)
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if res, ok := results[req.Method]; ok {
+			resp["result"] = json.RawMessage(query.transport.Cdc.MustMarshalJSON(res))
+		} else {
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "Method not found"}
+		}
+		bz, _ := json.Marshal(resp)
+		w.Write(bz)
+	}))
+	t.Cleanup(server.Close)
+	query.transport = transport.NewTransportFromArgs("test", server.URL, 0)
+	return query
+}
+
+func TestNameValidators(t *testing.T) {
+	val1 := tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 10)
+	val1.ProposerPriority = -5
+	val2 := tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 20)
+	addr1, addr2 := val1.Address.String(), val2.Address.String()
+
+	testCases := map[string]struct {
+		set       *ctypes.ResultValidators
+		usernames map[string]string
+		expect    *model.NamedValidatorSet
+	}{
+		"empty set": {
+			set:       &ctypes.ResultValidators{BlockHeight: 3},
+			usernames: map[string]string{addr1: "val1"},
+			expect:    &model.NamedValidatorSet{Height: 3, Validators: []*model.NamedValidator{}},
+		},
+		"all named": {
+			set:       &ctypes.ResultValidators{BlockHeight: 5, Validators: []*tmtypes.Validator{val1, val2}},
+			usernames: map[string]string{addr1: "val1", addr2: "val2"},
+			expect: &model.NamedValidatorSet{Height: 5, Validators: []*model.NamedValidator{
+				{Username: "val1", Address: addr1, PubKey: val1.PubKey, VotingPower: 10, ProposerPriority: -5},
+				{Username: "val2", Address: addr2, PubKey: val2.PubKey, VotingPower: 20},
+			}},
+		},
+		"left validator": {
+			set:       &ctypes.ResultValidators{BlockHeight: 5, Validators: []*tmtypes.Validator{val1, val2}},
+			usernames: map[string]string{addr2: "val2"},
+			expect: &model.NamedValidatorSet{Height: 5, Validators: []*model.NamedValidator{
+				{Address: addr1, PubKey: val1.PubKey, VotingPower: 10, ProposerPriority: -5},
+				{Username: "val2", Address: addr2, PubKey: val2.PubKey, VotingPower: 20},
+			}},
+		},
+	}
+
+	for testName, tc := range testCases {
+		got := NameValidators(tc.set, tc.usernames)
+		if !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("%s: diff result, got %v, expect %v", testName, got, tc.expect)
+		}
+	}
+}
+
+func TestNodeQueries(t *testing.T) {
+	val := tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 10)
+	results := map[string]interface{}{
+		"validators":           &ctypes.ResultValidators{BlockHeight: 7, Validators: []*tmtypes.Validator{val}},
+		"commit":               &ctypes.ResultCommit{CanonicalCommit: true},
+		"net_info":             &ctypes.ResultNetInfo{Listening: true, NPeers: 2},
+		"genesis":              &ctypes.ResultGenesis{Genesis: &tmtypes.GenesisDoc{ChainID: "test-chain"}},
+		"consensus_state":      &ctypes.ResultConsensusState{RoundState: json.RawMessage(`{"height/round/step":"7/0/1"}`)},
+		"dump_consensus_state": &ctypes.ResultDumpConsensusState{RoundState: json.RawMessage(`{"height":"7"}`)},
+		"num_unconfirmed_txs":  &ctypes.ResultUnconfirmedTxs{Count: 1, Total: 1, TotalBytes: 100},
+	}
+
+	testCases := map[string]struct {
+		query  func(ctx context.Context, query *Query) (interface{}, error)
+		check  func(res interface{}) bool
+		method string
+	}{
+		"validator set": {
+			query: func(ctx context.Context, query *Query) (interface{}, error) {
+				return query.GetValidatorSet(ctx, 7)
+			},
+			check: func(res interface{}) bool {
+				set := res.(*ctypes.ResultValidators)
+				return set.BlockHeight == 7 && len(set.Validators) == 1 && set.Validators[0].PubKey.Equals(val.PubKey)
+			},
+			method: "validators",
+		},
+		"commit": {
+			query: func(ctx context.Context, query *Query) (interface{}, error) {
+				return query.GetCommit(ctx, 0)
+			},
+			check:  func(res interface{}) bool { return res.(*ctypes.ResultCommit).CanonicalCommit },
+			method: "commit",
+		},
+		"net info": {
+			query: func(ctx context.Context, query *Query) (interface{}, error) {
+				return query.GetNetInfo(ctx)
+			},
+			check: func(res interface{}) bool {
+				info := res.(*ctypes.ResultNetInfo)
+				return info.Listening && info.NPeers == 2
+			},
+			method: "net_info",
+		},
+		"genesis": {
+			query: func(ctx context.Context, query *Query) (interface{}, error) {
+				return query.GetGenesis(ctx)
+			},
+			check:  func(res interface{}) bool { return res.(*ctypes.ResultGenesis).Genesis.ChainID == "test-chain" },
+			method: "genesis",
+		},
+		"consensus state": {
+			query: func(ctx context.Context, query *Query) (interface{}, error) {
+				return query.GetConsensusState(ctx)
+			},
+			check: func(res interface{}) bool {
+				return string(res.(*ctypes.ResultConsensusState).RoundState) == `{"height/round/step":"7/0/1"}`
+			},
+			method: "consensus_state",
+		},
+		"dump consensus state": {
+			query: func(ctx context.Context, query *Query) (interface{}, error) {
+				return query.DumpConsensusState(ctx)
+			},
+			check: func(res interface{}) bool {
+				return string(res.(*ctypes.ResultDumpConsensusState).RoundState) == `{"height":"7"}`
+			},
+			method: "dump_consensus_state",
+		},
+		"num unconfirmed txs": {
+			query: func(ctx context.Context, query *Query) (interface{}, error) {
+				return query.GetNumUnconfirmedTxs(ctx)
+			},
+			check: func(res interface{}) bool {
+				txs := res.(*ctypes.ResultUnconfirmedTxs)
+				return txs.Count == 1 && txs.TotalBytes == 100
+			},
+			method: "num_unconfirmed_txs",
+		},
+	}
+
+	ctx := context.Background()
+	for testName, tc := range testCases {
+		res, err := tc.query(ctx, newFakeRPC(t, results, 0))
+		if err != nil {
+			t.Errorf("%s: failed to query, got err %v", testName, err)
+		} else if !tc.check(res) {
+			t.Errorf("%s: diff result, got %+v", testName, res)
+		}
+
+		withoutMethod := map[string]interface{}{}
+		for method, res := range results {
+			if method != tc.method {
+				withoutMethod[method] = res
+			}
+		}
+		if _, err := tc.query(ctx, newFakeRPC(t, withoutMethod, 0)); err == nil {
+			t.Errorf("%s: expect err on rpc error", testName)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		_, err = tc.query(timeoutCtx, newFakeRPC(t, results, time.Second))
+		cancel()
+		if linoe, ok := err.(errors.Error); !ok || linoe.Cause() == nil {
+			t.Errorf("%s: expect query fail err, got %v", testName, err)
+		} else if cause, ok := linoe.Cause().(errors.Error); !ok || cause.CodeType() != errors.CodeTimeout {
+			t.Errorf("%s: expect timeout cause, got %v", testName, linoe.Cause())
+		}
+	}
+}
+
+func TestGetUnconfirmedTxs(t *testing.T) {
+	stdTx := auth.StdTx{Memo: "memo", Fee: auth.StdFee{Amount: sdk.NewCoins(sdk.NewInt64Coin("linocoin", 1))}}
+	txBytes := transport.NewTransportFromArgs("test", "", 0).Cdc.MustMarshalJSON(stdTx)
+
+	testCases := map[string]struct {
+		limit     int
+		txs       tmtypes.Txs
+		expectTxs []auth.StdTx
+		expectErr bool
+	}{
+		"txs": {
+			limit:     10,
+			txs:       tmtypes.Txs{txBytes, txBytes},
+			expectTxs: []auth.StdTx{stdTx, stdTx},
+		},
+		"no txs": {
+			limit:     10,
+			txs:       tmtypes.Txs{},
+			expectTxs: []auth.StdTx{},
+		},
+		"invalid limit": {
+			limit:     0,
+			expectErr: true,
+		},
+		"invalid tx": {
+			limit:     10,
+			txs:       tmtypes.Txs{[]byte("not a tx")},
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		query := newFakeRPC(t, map[string]interface{}{
+			"unconfirmed_txs": &ctypes.ResultUnconfirmedTxs{Count: len(tc.txs), Total: 5, TotalBytes: 100, Txs: tc.txs},
+		}, 0)
+		res, err := query.GetUnconfirmedTxs(context.Background(), tc.limit)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expect err, got %+v", testName, res)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: failed to get unconfirmed txs, got err %v", testName, err)
+			continue
+		}
+		expect := &model.UnconfirmedTxs{Count: len(tc.txs), Total: 5, TotalBytes: 100, Txs: tc.expectTxs}
+		if !reflect.DeepEqual(res, expect) {
+			t.Errorf("%s: diff result, got %+v, expect %+v", testName, res, expect)
+		}
+	}
+}
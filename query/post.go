@@ -31,52 +31,21 @@ func (query *Query) GetPostInfo(ctx context.Context, author, postID string) (*mo
 // Range query
 //
 
-// // GetUserAllPosts returns all posts that a user has created.
-// func (query *Query) GetUserAllPosts(ctx context.Context, username string) (map[string]*model.Post, error) {
-// 	resKVs, err := query.transport.QuerySubspace(ctx, append(getUserPostInfoPrefix(username), PermLinkSeparator...), PostKVStoreKey)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	permlinkToPostMap := make(map[string]*model.Post)
-// 	for _, KV := range resKVs {
-// 		postInfo := new(model.PostInfo)
-// 		if err := query.transport.Cdc.UnmarshalJSON(KV.Value, postInfo); err != nil {
-// 			return nil, err
-// 		}
-
-// 		pm, err := query.GetPostMeta(ctx, postInfo.Author, postInfo.PostID)
-// 		if err != nil {
-// 			return nil, err
-// 		}
-
-// 		post := &model.Post{
-// 			PostID:                  postInfo.PostID,
-// 			Title:                   postInfo.Title,
-// 			Content:                 postInfo.Content,
-// 			Author:                  postInfo.Author,
-// 			ParentAuthor:            postInfo.ParentAuthor,
-// 			ParentPostID:            postInfo.ParentPostID,
-// 			SourceAuthor:            postInfo.SourceAuthor,
-// 			SourcePostID:            postInfo.SourcePostID,
-// 			Links:                   postInfo.Links,
-// 			CreatedAt:               pm.CreatedAt,
-// 			LastUpdatedAt:           pm.LastUpdatedAt,
-// 			LastActivityAt:          pm.LastActivityAt,
-// 			AllowReplies:            pm.AllowReplies,
-// 			IsDeleted:               pm.IsDeleted,
-// 			TotalDonateCount:        pm.TotalDonateCount,
-// 			TotalReportCoinDay:      pm.TotalReportCoinDay,
-// 			TotalUpvoteCoinDay:      pm.TotalUpvoteCoinDay,
-// 			TotalViewCount:          pm.TotalViewCount,
-// 			TotalReward:             pm.TotalReward,
-// 			RedistributionSplitRate: pm.RedistributionSplitRate,
-// 		}
-// 		permlinkToPostMap[getSubstringAfterSubstore(KV.Key)] = post
-// 	}
-
-// 	return permlinkToPostMap, nil
-// }
+// GetUserAllPosts returns all posts that a user has created, keyed by permlink.
+func (query *Query) GetUserAllPosts(ctx context.Context, username string) (map[string]*model.Post, error) {
+	prefix := append(model.GetAuthorPrefix(linotypes.AccountKey(username)), PermLinkSeparator...)
+	iter := query.NewSubspaceIterator(PostKVStoreKey, prefix, SubspaceOptions{})
+
+	permlinkToPostMap := make(map[string]*model.Post)
+	err := iter.ForEach(ctx, func() interface{} { return new(model.Post) }, func(key []byte, value interface{}) error {
+		permlinkToPostMap[getSubstringAfterSubstore(key)] = value.(*model.Post)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return permlinkToPostMap, nil
+}
 
 // // GetPostAllComments returns all comments that a post has.
 // func (query *Query) GetPostAllComments(ctx context.Context, author, postID string) (map[string]*model.Comment, error) {
@@ -1,36 +1,13 @@
 package query
 
-// import (
-// 	"context"
-
-// 	"github.com/lino-network/lino/x/proposal"
-// 	"github.com/lino-network/lino/x/proposal/model"
-// )
-
-// // GetOngoingProposal returns one ongoing proposal.
-// func (query *Query) GetOngoingProposal(ctx context.Context, proposalID string) (*model.Proposal, error) {
-// 	resp, err := query.transport.Query(ctx, ProposalKVStoreKey, proposal.QueryOngoingProposal, []string{proposalID})
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	proposal := new(model.Proposal)
-// 	if err := query.transport.Cdc.UnmarshalJSON(resp, proposal); err != nil {
-// 		return nil, err
-// 	}
-// 	return proposal, nil
-// }
-
-// // GetExpiredProposal returns one expired past proposal.
-// func (query *Query) GetExpiredProposal(ctx context.Context, proposalID string) (*model.Proposal, error) {
-// 	resp, err := query.transport.Query(ctx, ProposalKVStoreKey, proposal.QueryExpiredProposal, []string{proposalID})
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	proposal := new(model.Proposal)
-// 	if err := query.transport.Cdc.UnmarshalJSON(resp, proposal); err != nil {
-// 		return nil, err
-// 	}
-// 	return proposal, nil
-// }
+// Governance is not available on the chain version this library targets.
+//
+// lino v0.6.x no longer ships the x/proposal module: there is no proposal
+// querier (ongoing/expired proposals, proposal lists) and none of
+// VoteProposalMsg, UpgradeProtocolMsg, DeletePostContentMsg or the
+// ChangeXParamMsg family is registered in the codec, so any transaction built
+// from them would be rejected by CheckTx. The commented-out proposal
+// builders in api and broadcast are kept for reference only.
+//
+// Current parameter values can still be read with the getters in param.go,
+// e.g. GetProposalParam.
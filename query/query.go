@@ -6,7 +6,6 @@ import (
 	"context"
 	"strings"
 
-	auth "github.com/cosmos/cosmos-sdk/x/auth"
 	"github.com/lino-network/lino-go/errors"
 	"github.com/lino-network/lino-go/model"
 	"github.com/lino-network/lino-go/transport"
@@ -36,6 +35,16 @@ func (query *Query) GetBlock(ctx context.Context, height int64) (*ttypes.Block,
 	return resp.Block, nil
 }
 
+// GetBlockResults returns the DeliverTx results of a block at a certain height from blockchain.
+func (query *Query) GetBlockResults(ctx context.Context, height int64) (*ctypes.ResultBlockResults, error) {
+	resp, err := query.transport.QueryBlockResults(ctx, height)
+	if err != nil {
+		return nil, errors.QueryFailf("GetBlockResults err").AddCause(err)
+	}
+
+	return resp, nil
+}
+
 // GetBlockStatus returns the current block status from blockchain.
 func (query *Query) GetBlockStatus(ctx context.Context) (*ctypes.ResultStatus, error) {
 	resp, err := query.transport.QueryBlockStatus(ctx)
@@ -56,17 +65,5 @@ func (query *Query) GetTx(ctx context.Context, hash []byte) (*model.BlockTx, err
 		return nil, errors.QueryFailf("GetTx err").AddCause(err)
 	}
 
-	var tx auth.StdTx
-	if err := query.transport.Cdc.UnmarshalJSON(resp.Tx, &tx); err != nil {
-		return nil, errors.QueryFailf("Unmarshal tx err").AddCause(err)
-	}
-
-	bt := &model.BlockTx{
-		Height: resp.Height,
-		Tx:     tx,
-		Code:   resp.TxResult.Code,
-		Log:    resp.TxResult.Log,
-	}
-
-	return bt, nil
+	return query.decodeTx(resp)
 }
@@ -0,0 +1,158 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+)
+
+// UsernameChunkBytes are the bytes a username can start with, for splitting
+// subspaces keyed by username.
+var UsernameChunkBytes = []byte("-.0123456789abcdefghijklmnopqrstuvwxyz")
+
+// SubspaceOptions configures a SubspaceIterator.
+type SubspaceOptions struct {
+	// Start is the first full key returned, inclusive. Empty means no bound.
+	Start []byte
+	// End is the full key iteration stops at, exclusive. Empty means no bound.
+	End []byte
+	// ChunkBytes are the bytes keys may continue with after the prefix. If
+	// set, every byte is queried as its own chunk, which bounds the size of
+	// each response; keys continuing with other bytes are not returned.
+	// Empty queries the whole prefix as one chunk.
+	ChunkBytes []byte
+}
+
+// SubspaceIterator iterates the KV pairs under a store prefix in key order,
+// one chunk at a time. The node only answers whole-subspace queries, so
+// chunks are the subspaces of the prefix extended by one byte, and Start and
+// End skip chunks outside of the range.
+type SubspaceIterator struct {
+	query     *Query
+	storeName string
+	prefix    []byte
+	opts      SubspaceOptions
+	next      int
+	chunk     []sdk.KVPair
+	err       error
+}
+
+// NewSubspaceIterator returns an iterator over keys of @p storeName starting
+// with @p prefix.
+func (query *Query) NewSubspaceIterator(storeName string, prefix []byte, opts SubspaceOptions) *SubspaceIterator {
+	chunkBytes := append([]byte{}, opts.ChunkBytes...)
+	sort.Slice(chunkBytes, func(i, j int) bool { return chunkBytes[i] < chunkBytes[j] })
+	opts.ChunkBytes = chunkBytes
+	return &SubspaceIterator{
+		query:     query,
+		storeName: storeName,
+		prefix:    prefix,
+		opts:      opts,
+		next:      -1,
+	}
+}
+
+// Next fetches the next non-empty chunk. It returns false when the
+// iteration is done or failed; check Err to tell them apart.
+func (it *SubspaceIterator) Next(ctx context.Context) bool {
+	it.chunk = nil
+	for it.err == nil && len(it.chunk) == 0 && it.hasNext() {
+		it.chunk, it.err = it.fetch(ctx)
+		it.next++
+	}
+	return it.err == nil && len(it.chunk) > 0
+}
+
+// Chunk returns the KV pairs fetched by the last Next.
+func (it *SubspaceIterator) Chunk() []sdk.KVPair {
+	return it.chunk
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *SubspaceIterator) Err() error {
+	return it.err
+}
+
+// ForEach decodes every remaining value into a new value from @p newValue
+// and calls @p fn with it, stopping at the first decode or @p fn error.
+func (it *SubspaceIterator) ForEach(
+	ctx context.Context, newValue func() interface{}, fn func(key []byte, value interface{}) error) error {
+	for it.Next(ctx) {
+		for _, kv := range it.chunk {
+			value := newValue()
+			if err := it.query.transport.Cdc.UnmarshalBinaryLengthPrefixed(kv.Value, value); err != nil {
+				return errors.UnmarshaFailed("failed to decode value of key " + string(kv.Key)).AddCause(err)
+			}
+			if err := fn(kv.Key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Err()
+}
+
+func (it *SubspaceIterator) hasNext() bool {
+	if len(it.opts.ChunkBytes) == 0 {
+		return it.next < 0
+	}
+	return it.next < len(it.opts.ChunkBytes)
+}
+
+func (it *SubspaceIterator) fetch(ctx context.Context) ([]sdk.KVPair, error) {
+	if len(it.opts.ChunkBytes) == 0 {
+		return it.querySubspace(ctx, it.prefix)
+	}
+
+	// the key equal to the prefix itself is not under any chunk.
+	if it.next < 0 {
+		if !it.inRange(it.prefix) {
+			return nil, nil
+		}
+		resp, err := it.query.transport.QueryAtHeight(ctx, it.prefix, it.storeName, 0)
+		if err != nil {
+			if errors.IsEmptyResponse(err) {
+				return nil, nil
+			}
+			return nil, errors.QueryFailf("query subspace key err").AddCause(err)
+		}
+		return []sdk.KVPair{{Key: it.prefix, Value: resp}}, nil
+	}
+
+	chunk := append(append([]byte{}, it.prefix...), it.opts.ChunkBytes[it.next])
+	if len(it.opts.End) > 0 && bytes.Compare(chunk, it.opts.End) >= 0 {
+		return nil, nil
+	}
+	if len(it.opts.Start) > 0 && bytes.Compare(chunk, it.opts.Start) < 0 && !bytes.HasPrefix(it.opts.Start, chunk) {
+		return nil, nil
+	}
+	return it.querySubspace(ctx, chunk)
+}
+
+func (it *SubspaceIterator) querySubspace(ctx context.Context, subspace []byte) ([]sdk.KVPair, error) {
+	kvs, err := it.query.transport.QuerySubspace(ctx, subspace, it.storeName)
+	if err != nil {
+		return nil, errors.QueryFailf("query subspace err").AddCause(err)
+	}
+
+	res := []sdk.KVPair{}
+	for _, kv := range kvs {
+		if it.inRange(kv.Key) {
+			res = append(res, kv)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return bytes.Compare(res[i].Key, res[j].Key) < 0 })
+	return res, nil
+}
+
+func (it *SubspaceIterator) inRange(key []byte) bool {
+	if len(it.opts.Start) > 0 && bytes.Compare(key, it.opts.Start) < 0 {
+		return false
+	}
+	if len(it.opts.End) > 0 && bytes.Compare(key, it.opts.End) >= 0 {
+		return false
+	}
+	return true
+}
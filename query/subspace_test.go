@@ -0,0 +1,209 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/transport"
+)
+
+// newFakeStore returns a query of a node serving the key and subspace
+// queries of store "store" from @p kvs, and the list of queried paths and
+// keys. The node fails all queries if @p fail is set.
+func newFakeStore(t *testing.T, kvs map[string][]byte, fail bool) (*Query, func() []string) {
+	query := NewQuery(nil)
+	var lock sync.Mutex
+	queried := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Params struct {
+				Path string `json:"path"`
+				Data string `json:"data"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid rpc request, got err %v", err)
+		}
+		data, err := hex.DecodeString(req.Params.Data)
+		if err != nil {
+			t.Errorf("invalid query data %v, got err %v", req.Params.Data, err)
+		}
+		lock.Lock()
+		queried = append(queried, req.Params.Path+":"+string(data))
+		lock.Unlock()
+
+		var code uint32
+		var value []byte
+		switch {
+		case fail:
+			code = 1
+		case req.Params.Path == "/store/store/key":
+			value = kvs[string(data)]
+		case req.Params.Path == "/store/store/subspace":
+			pairs := []sdk.KVPair{}
+			for key, v := range kvs {
+				if strings.HasPrefix(key, string(data)) {
+					pairs = append(pairs, sdk.KVPair{Key: []byte(key), Value: v})
+				}
+			}
+			if len(pairs) > 0 {
+				value = query.transport.Cdc.MustMarshalBinaryLengthPrefixed(pairs)
+			}
+		default:
+			code = 1
+		}
+		resp, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result": map[string]interface{}{
+				"response": map[string]interface{}{"code": code, "value": value},
+			},
+		})
+		w.Write(resp)
+	}))
+	t.Cleanup(server.Close)
+	query.transport = transport.NewTransportFromArgs("test", server.URL, 0)
+	return query, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return queried
+	}
+}
+
+func TestSubspaceIterator(t *testing.T) {
+	kvs := map[string][]byte{
+		"p":   []byte("0"),
+		"pa1": []byte("1"),
+		"pa2": []byte("2"),
+		"pb1": []byte("3"),
+		"pc1": []byte("4"),
+		"pz1": []byte("5"),
+		"q1":  []byte("6"),
+	}
+
+	testCases := map[string]struct {
+		opts          SubspaceOptions
+		expectChunks  [][]string
+		expectQueried []string
+	}{
+		"whole prefix": {
+			opts:          SubspaceOptions{},
+			expectChunks:  [][]string{{"p", "pa1", "pa2", "pb1", "pc1", "pz1"}},
+			expectQueried: []string{"/store/store/subspace:p"},
+		},
+		"whole prefix in range": {
+			opts:          SubspaceOptions{Start: []byte("pa2"), End: []byte("pc1")},
+			expectChunks:  [][]string{{"pa2", "pb1"}},
+			expectQueried: []string{"/store/store/subspace:p"},
+		},
+		"chunks": {
+			opts:         SubspaceOptions{ChunkBytes: []byte("cab")},
+			expectChunks: [][]string{{"p"}, {"pa1", "pa2"}, {"pb1"}, {"pc1"}},
+			expectQueried: []string{
+				"/store/store/key:p", "/store/store/subspace:pa",
+				"/store/store/subspace:pb", "/store/store/subspace:pc"},
+		},
+		"empty chunks are skipped": {
+			opts:         SubspaceOptions{ChunkBytes: []byte("adz")},
+			expectChunks: [][]string{{"p"}, {"pa1", "pa2"}, {"pz1"}},
+			expectQueried: []string{
+				"/store/store/key:p", "/store/store/subspace:pa",
+				"/store/store/subspace:pd", "/store/store/subspace:pz"},
+		},
+		"chunks in range": {
+			opts:         SubspaceOptions{Start: []byte("pa2"), End: []byte("pc"), ChunkBytes: []byte("abcz")},
+			expectChunks: [][]string{{"pa2"}, {"pb1"}},
+			expectQueried: []string{
+				"/store/store/subspace:pa", "/store/store/subspace:pb"},
+		},
+	}
+
+	for testName, tc := range testCases {
+		query, queried := newFakeStore(t, kvs, false)
+		it := query.NewSubspaceIterator("store", []byte("p"), tc.opts)
+		chunks := [][]string{}
+		for it.Next(context.Background()) {
+			keys := []string{}
+			for _, kv := range it.Chunk() {
+				keys = append(keys, string(kv.Key))
+				if !bytes.Equal(kv.Value, kvs[string(kv.Key)]) {
+					t.Errorf("%s: diff value of %s, got %s", testName, kv.Key, kv.Value)
+				}
+			}
+			chunks = append(chunks, keys)
+		}
+		if err := it.Err(); err != nil {
+			t.Errorf("%s: failed to iterate, got err %v", testName, err)
+		}
+		if !reflect.DeepEqual(chunks, tc.expectChunks) {
+			t.Errorf("%s: diff chunks, got %v, expect %v", testName, chunks, tc.expectChunks)
+		}
+		if !reflect.DeepEqual(queried(), tc.expectQueried) {
+			t.Errorf("%s: diff queries, got %v, expect %v", testName, queried(), tc.expectQueried)
+		}
+	}
+}
+
+func TestSubspaceIteratorForEach(t *testing.T) {
+	cdc := transport.NewTransportFromArgs("test", "", 0).Cdc
+
+	testCases := map[string]struct {
+		kvs       map[string][]byte
+		fail      bool
+		expect    map[string]string
+		expectErr bool
+	}{
+		"decode values": {
+			kvs: map[string][]byte{
+				"pa": cdc.MustMarshalBinaryLengthPrefixed("a"),
+				"pb": cdc.MustMarshalBinaryLengthPrefixed("b"),
+			},
+			expect: map[string]string{"pa": "a", "pb": "b"},
+		},
+		"empty subspace": {
+			kvs:    map[string][]byte{},
+			expect: map[string]string{},
+		},
+		"invalid value": {
+			kvs:       map[string][]byte{"pa": []byte("not amino")},
+			expectErr: true,
+		},
+		"node failure": {
+			kvs:       map[string][]byte{},
+			fail:      true,
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		query, _ := newFakeStore(t, tc.kvs, tc.fail)
+		it := query.NewSubspaceIterator("store", []byte("p"), SubspaceOptions{ChunkBytes: []byte("ab")})
+		got := map[string]string{}
+		err := it.ForEach(context.Background(), func() interface{} { return new(string) },
+			func(key []byte, value interface{}) error {
+				got[string(key)] = *value.(*string)
+				return nil
+			})
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expect err, got %v", testName, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: failed to iterate, got err %v", testName, err)
+		} else if !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("%s: diff values, got %v, expect %v", testName, got, tc.expect)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/util"
+	linotypes "github.com/lino-network/lino/types"
+	posttypes "github.com/lino-network/lino/x/post/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+const (
+	// MaxTxSearchPerPage is the largest page tendermint serves for tx_search.
+	MaxTxSearchPerPage = 100
+)
+
+// TxQuery builds a tx_search query over Lino txs.
+//
+// MsgType, Height, MinHeight, MaxHeight and Tag are matched by the node.
+// Lino handlers do not tag the accounts or posts a msg touches, so Involving,
+// OnPost and Filter are applied to the decoded txs instead; combine them with
+// a msg type or height range to keep the scan small.
+type TxQuery struct {
+	conditions []string
+	filters    []func(sdk.Msg) bool
+}
+
+// NewTxQuery returns an empty query, which matches every tx.
+func NewTxQuery() *TxQuery {
+	return &TxQuery{}
+}
+
+// MsgType matches txs carrying a msg of type @p msgType, e.g. "TransferV2Msg".
+func (q *TxQuery) MsgType(msgType string) *TxQuery {
+	return q.Tag("message.action", msgType)
+}
+
+// Height matches txs included at @p height.
+func (q *TxQuery) Height(height int64) *TxQuery {
+	q.conditions = append(q.conditions, fmt.Sprintf("tx.height=%d", height))
+	return q
+}
+
+// MinHeight matches txs included at or after @p height.
+func (q *TxQuery) MinHeight(height int64) *TxQuery {
+	q.conditions = append(q.conditions, fmt.Sprintf("tx.height>=%d", height))
+	return q
+}
+
+// MaxHeight matches txs included at or before @p height.
+func (q *TxQuery) MaxHeight(height int64) *TxQuery {
+	q.conditions = append(q.conditions, fmt.Sprintf("tx.height<=%d", height))
+	return q
+}
+
+// Tag matches txs with an event attribute @p key equal to @p value.
+func (q *TxQuery) Tag(key, value string) *TxQuery {
+	q.conditions = append(q.conditions, fmt.Sprintf("%s='%s'", key, strings.Replace(value, "'", "", -1)))
+	return q
+}
+
+// Involving keeps txs with at least one msg touching @p username.
+func (q *TxQuery) Involving(username string) *TxQuery {
+	return q.Filter(func(msg sdk.Msg) bool {
+		for _, acc := range util.MsgInvolvedAccounts(msg) {
+			if acc == username {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// OnPost keeps txs with at least one donation to the post @p author/@p postID.
+func (q *TxQuery) OnPost(author, postID string) *TxQuery {
+	return q.Filter(func(msg sdk.Msg) bool {
+		switch msg := msg.(type) {
+		case posttypes.DonateMsg:
+			return msg.Author == linotypes.AccountKey(author) && msg.PostID == postID
+		case posttypes.IDADonateMsg:
+			return msg.Author == linotypes.AccountKey(author) && msg.PostID == postID
+		}
+		return false
+	})
+}
+
+// Filter keeps txs with at least one msg for which @p filter returns true.
+func (q *TxQuery) Filter(filter func(sdk.Msg) bool) *TxQuery {
+	q.filters = append(q.filters, filter)
+	return q
+}
+
+// String returns the query in tendermint's query syntax.
+func (q *TxQuery) String() string {
+	if len(q.conditions) == 0 {
+		return "tx.height>0"
+	}
+	return strings.Join(q.conditions, " AND ")
+}
+
+// Match reports whether @p tx passes all client-side filters.
+func (q *TxQuery) Match(tx *model.BlockTx) bool {
+	for _, filter := range q.filters {
+		matched := false
+		for _, msg := range tx.Tx.Msgs {
+			if filter(msg) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchTxs returns one page of txs matching @p q, ordered by height and
+// index. Pages start at 1, @p perPage is capped at MaxTxSearchPerPage.
+// TotalCount counts node-side matches, before client-side filters.
+func (query *Query) SearchTxs(ctx context.Context, q *TxQuery, page, perPage int) (*model.TxSearchResult, error) {
+	if perPage <= 0 || perPage > MaxTxSearchPerPage {
+		perPage = MaxTxSearchPerPage
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	resp, err := query.transport.QueryTxSearch(ctx, q.String(), page, perPage)
+	if err != nil {
+		return nil, errors.QueryFailf("SearchTxs err").AddCause(err)
+	}
+
+	result := &model.TxSearchResult{
+		Txs:        []*model.BlockTx{},
+		TotalCount: resp.TotalCount,
+		Page:       page,
+		PerPage:    perPage,
+	}
+	for _, r := range resp.Txs {
+		tx, err := query.decodeTx(r)
+		if err != nil {
+			return nil, err
+		}
+		if q.Match(tx) {
+			result.Txs = append(result.Txs, tx)
+		}
+	}
+	sort.SliceStable(result.Txs, func(i, j int) bool { return txLess(result.Txs[i], result.Txs[j]) })
+	return result, nil
+}
+
+// SearchAllTxs walks every page of @p q and returns up to @p limit matching
+// txs, oldest first, or newest first if @p desc is set. A non-positive limit
+// returns all of them.
+func (query *Query) SearchAllTxs(ctx context.Context, q *TxQuery, desc bool, limit int) ([]*model.BlockTx, error) {
+	first, err := query.SearchTxs(ctx, q, 1, MaxTxSearchPerPage)
+	if err != nil {
+		return nil, err
+	}
+	numPages := (first.TotalCount + MaxTxSearchPerPage - 1) / MaxTxSearchPerPage
+
+	res := []*model.BlockTx{}
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		if desc {
+			pageNum = numPages - i
+		}
+		page := first
+		if pageNum != 1 {
+			page, err = query.SearchTxs(ctx, q, pageNum, MaxTxSearchPerPage)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		txs := page.Txs
+		if desc {
+			sort.SliceStable(txs, func(i, j int) bool { return txLess(txs[j], txs[i]) })
+		}
+		for _, tx := range txs {
+			res = append(res, tx)
+			if limit > 0 && len(res) >= limit {
+				return res, nil
+			}
+		}
+	}
+	return res, nil
+}
+
+func (query *Query) decodeTx(resp *ctypes.ResultTx) (*model.BlockTx, errors.Error) {
+	var tx auth.StdTx
+	if err := query.transport.Cdc.UnmarshalJSON(resp.Tx, &tx); err != nil {
+		return nil, errors.QueryFailf("Unmarshal tx err").AddCause(err)
+	}
+
+	return &model.BlockTx{
+		Height: resp.Height,
+		Index:  resp.Index,
+		Hash:   resp.Hash.String(),
+		Tx:     tx,
+		Code:   resp.TxResult.Code,
+		Log:    resp.TxResult.Log,
+	}, nil
+}
+
+func txLess(a, b *model.BlockTx) bool {
+	if a.Height == b.Height {
+		return a.Index < b.Index
+	}
+	return a.Height < b.Height
+}
@@ -0,0 +1,367 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/transport"
+	linotypes "github.com/lino-network/lino/types"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	posttypes "github.com/lino-network/lino/x/post/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// newFakeTxSearch returns a query of a node serving @p txs, in that order,
+// as the result of every tx_search, and the list of searched queries. Each
+// page is served in reverse to check that results are sorted.
+func newFakeTxSearch(t *testing.T, txs []*ctypes.ResultTx) (*Query, func() []string) {
+	query := NewQuery(nil)
+	var lock sync.Mutex
+	queries := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				Query   string          `json:"query"`
+				Page    json.RawMessage `json:"page"`
+				PerPage json.RawMessage `json:"per_page"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "tx_search" {
+			t.Errorf("invalid rpc request %v, got err %v", req.Method, err)
+		}
+		page, _ := strconv.Atoi(strings.Trim(string(req.Params.Page), `"`))
+		perPage, _ := strconv.Atoi(strings.Trim(string(req.Params.PerPage), `"`))
+		lock.Lock()
+		queries = append(queries, req.Params.Query)
+		lock.Unlock()
+
+		result := &ctypes.ResultTxSearch{Txs: []*ctypes.ResultTx{}, TotalCount: len(txs)}
+		for i := page * perPage; i > (page-1)*perPage; i-- {
+			if i <= len(txs) {
+				result.Txs = append(result.Txs, txs[i-1])
+			}
+		}
+		bz, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  json.RawMessage(query.transport.Cdc.MustMarshalJSON(result)),
+		})
+		w.Write(bz)
+	}))
+	t.Cleanup(server.Close)
+	query.transport = transport.NewTransportFromArgs("test", server.URL, 0)
+	return query, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return queries
+	}
+}
+
+// transferTxs returns @p n transfer txs, two per block, from user0, user1
+// and user2 in turn.
+func transferTxs(n int) []*ctypes.ResultTx {
+	cdc := transport.NewTransportFromArgs("test", "", 0).Cdc
+	txs := make([]*ctypes.ResultTx, n)
+	for i := range txs {
+		msg := acctypes.TransferMsg{
+			Sender:   linotypes.AccountKey("user" + strconv.Itoa(i%3)),
+			Receiver: "receiver",
+			Amount:   "1",
+		}
+		txs[i] = &ctypes.ResultTx{
+			Hash:     []byte{byte(i), byte(i >> 8)},
+			Height:   int64(i/2 + 1),
+			Index:    uint32(i % 2),
+			TxResult: abci.ResponseDeliverTx{Log: strconv.Itoa(i)},
+			Tx:       cdc.MustMarshalJSON(auth.StdTx{Msgs: []sdk.Msg{msg}}),
+		}
+	}
+	return txs
+}
+
+func txLogs(txs []*model.BlockTx) []string {
+	logs := make([]string, len(txs))
+	for i, tx := range txs {
+		logs[i] = tx.Log
+	}
+	return logs
+}
+
+func TestTxQueryString(t *testing.T) {
+	testCases := map[string]struct {
+		query  *TxQuery
+		expect string
+	}{
+		"empty": {
+			query:  NewTxQuery(),
+			expect: "tx.height>0",
+		},
+		"msg type": {
+			query:  NewTxQuery().MsgType("TransferV2Msg"),
+			expect: "message.action='TransferV2Msg'",
+		},
+		"height": {
+			query:  NewTxQuery().Height(10),
+			expect: "tx.height=10",
+		},
+		"height range": {
+			query:  NewTxQuery().MinHeight(10).MaxHeight(20),
+			expect: "tx.height>=10 AND tx.height<=20",
+		},
+		"tag with quotes": {
+			query:  NewTxQuery().Tag("message.sender", "a'b"),
+			expect: "message.sender='ab'",
+		},
+		"filters are not sent": {
+			query:  NewTxQuery().MsgType("DonateMsg").Involving("user").OnPost("author", "post"),
+			expect: "message.action='DonateMsg'",
+		},
+	}
+
+	for testName, tc := range testCases {
+		if got := tc.query.String(); got != tc.expect {
+			t.Errorf("%s: diff query, got %v, expect %v", testName, got, tc.expect)
+		}
+	}
+}
+
+func TestTxQueryMatch(t *testing.T) {
+	transfer := acctypes.TransferMsg{Sender: "sender", Receiver: "receiver", Amount: "1"}
+	donate := posttypes.DonateMsg{Username: "donator", Author: "author", PostID: "post", Amount: "1"}
+	idaDonate := posttypes.IDADonateMsg{Username: "donator", App: "app", Author: "author", PostID: "post", Amount: "1"}
+	otherPost := posttypes.DonateMsg{Username: "donator", Author: "author", PostID: "other", Amount: "1"}
+
+	testCases := map[string]struct {
+		query  *TxQuery
+		msgs   []sdk.Msg
+		expect bool
+	}{
+		"no filter": {
+			query:  NewTxQuery(),
+			msgs:   []sdk.Msg{transfer},
+			expect: true,
+		},
+		"involving sender": {
+			query:  NewTxQuery().Involving("sender"),
+			msgs:   []sdk.Msg{transfer},
+			expect: true,
+		},
+		"involving receiver": {
+			query:  NewTxQuery().Involving("receiver"),
+			msgs:   []sdk.Msg{transfer},
+			expect: true,
+		},
+		"not involving": {
+			query:  NewTxQuery().Involving("other"),
+			msgs:   []sdk.Msg{transfer},
+			expect: false,
+		},
+		"involving second msg": {
+			query:  NewTxQuery().Involving("donator"),
+			msgs:   []sdk.Msg{transfer, donate},
+			expect: true,
+		},
+		"on post": {
+			query:  NewTxQuery().OnPost("author", "post"),
+			msgs:   []sdk.Msg{donate},
+			expect: true,
+		},
+		"on post with ida": {
+			query:  NewTxQuery().OnPost("author", "post"),
+			msgs:   []sdk.Msg{idaDonate},
+			expect: true,
+		},
+		"on other post": {
+			query:  NewTxQuery().OnPost("author", "post"),
+			msgs:   []sdk.Msg{otherPost, transfer},
+			expect: false,
+		},
+		"all filters match": {
+			query:  NewTxQuery().Involving("sender").OnPost("author", "post"),
+			msgs:   []sdk.Msg{transfer, donate},
+			expect: true,
+		},
+		"one filter fails": {
+			query:  NewTxQuery().Involving("sender").OnPost("author", "post"),
+			msgs:   []sdk.Msg{transfer},
+			expect: false,
+		},
+		"custom filter": {
+			query:  NewTxQuery().Filter(func(msg sdk.Msg) bool { return msg.Type() == transfer.Type() }),
+			msgs:   []sdk.Msg{donate, transfer},
+			expect: true,
+		},
+		"no msgs": {
+			query:  NewTxQuery().Filter(func(msg sdk.Msg) bool { return true }),
+			msgs:   []sdk.Msg{},
+			expect: false,
+		},
+	}
+
+	for testName, tc := range testCases {
+		tx := &model.BlockTx{Tx: auth.StdTx{Msgs: tc.msgs}}
+		if got := tc.query.Match(tx); got != tc.expect {
+			t.Errorf("%s: diff match, got %v, expect %v", testName, got, tc.expect)
+		}
+	}
+}
+
+func TestSearchTxs(t *testing.T) {
+	query, queries := newFakeTxSearch(t, transferTxs(250))
+
+	testCases := map[string]struct {
+		query         *TxQuery
+		page          int
+		perPage       int
+		expectPage    int
+		expectPerPage int
+		expectLen     int
+		expectFirst   []string
+	}{
+		"first page": {
+			query:         NewTxQuery(),
+			page:          1,
+			perPage:       3,
+			expectPage:    1,
+			expectPerPage: 3,
+			expectLen:     3,
+			expectFirst:   []string{"0", "1", "2"},
+		},
+		"second page": {
+			query:         NewTxQuery(),
+			page:          2,
+			perPage:       3,
+			expectPage:    2,
+			expectPerPage: 3,
+			expectLen:     3,
+			expectFirst:   []string{"3", "4", "5"},
+		},
+		"default page": {
+			query:         NewTxQuery(),
+			page:          0,
+			perPage:       2,
+			expectPage:    1,
+			expectPerPage: 2,
+			expectLen:     2,
+			expectFirst:   []string{"0", "1"},
+		},
+		"capped per page": {
+			query:         NewTxQuery(),
+			page:          3,
+			perPage:       MaxTxSearchPerPage + 1,
+			expectPage:    3,
+			expectPerPage: MaxTxSearchPerPage,
+			expectLen:     50,
+			expectFirst:   []string{"200", "201"},
+		},
+		"filtered": {
+			query:         NewTxQuery().MsgType("TransferMsg").Involving("user1"),
+			page:          1,
+			perPage:       6,
+			expectPage:    1,
+			expectPerPage: 6,
+			expectLen:     2,
+			expectFirst:   []string{"1", "4"},
+		},
+	}
+
+	for testName, tc := range testCases {
+		res, err := query.SearchTxs(context.Background(), tc.query, tc.page, tc.perPage)
+		if err != nil {
+			t.Errorf("%s: failed to search txs, got err %v", testName, err)
+			continue
+		}
+		if res.Page != tc.expectPage || res.PerPage != tc.expectPerPage || res.TotalCount != 250 {
+			t.Errorf("%s: diff page, got %v/%v of %v", testName, res.Page, res.PerPage, res.TotalCount)
+		}
+		if len(res.Txs) != tc.expectLen {
+			t.Errorf("%s: diff number of txs, got %v, expect %v", testName, len(res.Txs), tc.expectLen)
+		} else if logs := txLogs(res.Txs)[:len(tc.expectFirst)]; !reflect.DeepEqual(logs, tc.expectFirst) {
+			t.Errorf("%s: diff first txs, got %v, expect %v", testName, logs, tc.expectFirst)
+		}
+		if got := queries()[len(queries())-1]; got != tc.query.String() {
+			t.Errorf("%s: diff searched query, got %v, expect %v", testName, got, tc.query.String())
+		}
+	}
+}
+
+func TestSearchAllTxs(t *testing.T) {
+	testCases := map[string]struct {
+		numTxs      int
+		query       *TxQuery
+		desc        bool
+		limit       int
+		expectFirst []string
+		expectLen   int
+	}{
+		"ascending": {
+			numTxs:      250,
+			query:       NewTxQuery(),
+			expectFirst: []string{"0", "1", "2"},
+			expectLen:   250,
+		},
+		"descending": {
+			numTxs:      250,
+			query:       NewTxQuery(),
+			desc:        true,
+			expectFirst: []string{"249", "248", "247"},
+			expectLen:   250,
+		},
+		"descending with limit across pages": {
+			numTxs:      101,
+			query:       NewTxQuery(),
+			desc:        true,
+			limit:       3,
+			expectFirst: []string{"100", "99", "98"},
+			expectLen:   3,
+		},
+		"filtered descending": {
+			numTxs:      250,
+			query:       NewTxQuery().Involving("user0"),
+			desc:        true,
+			expectFirst: []string{"249", "246", "243"},
+			expectLen:   84,
+		},
+		"no txs": {
+			numTxs:      0,
+			query:       NewTxQuery(),
+			expectFirst: []string{},
+			expectLen:   0,
+		},
+	}
+
+	for testName, tc := range testCases {
+		query, _ := newFakeTxSearch(t, transferTxs(tc.numTxs))
+		res, err := query.SearchAllTxs(context.Background(), tc.query, tc.desc, tc.limit)
+		if err != nil {
+			t.Errorf("%s: failed to search all txs, got err %v", testName, err)
+			continue
+		}
+		if len(res) != tc.expectLen {
+			t.Errorf("%s: diff number of txs, got %v, expect %v", testName, len(res), tc.expectLen)
+			continue
+		}
+		if logs := txLogs(res)[:len(tc.expectFirst)]; !reflect.DeepEqual(logs, tc.expectFirst) {
+			t.Errorf("%s: diff first txs, got %v, expect %v", testName, logs, tc.expectFirst)
+		}
+		for i := 1; i < len(res); i++ {
+			if txLess(res[i], res[i-1]) != tc.desc {
+				t.Errorf("%s: txs %v and %v out of order", testName, i-1, i)
+				break
+			}
+		}
+	}
+}
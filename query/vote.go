@@ -2,26 +2,19 @@ package query
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/lino-network/lino-go/errors"
+	linomodel "github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/util"
 	linotypes "github.com/lino-network/lino/types"
 	"github.com/lino-network/lino/x/vote/model"
 	vote "github.com/lino-network/lino/x/vote/types"
 )
 
-// GetDelegation returns the delegation relationship between
-// a voter and a delegator from blockchain.
-// func (query *Query) GetDelegation(ctx context.Context, voter, delegator string) (*model.Delegation, error) {
-// 	resp, err := query.transport.Query(ctx, VoteKVStoreKey, vote.QueryVoter, []string{voter, delegator})
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	delegation := new(model.Delegation)
-// 	if err := query.transport.Cdc.UnmarshalJSON(resp, delegation); err != nil {
-// 		return nil, err
-// 	}
-// 	return delegation, nil
-// }
+// Delegation was removed from the vote module in lino v0.6.x, so there is no
+// delegation record to query. StakeInFor is the only way to stake for another
+// user, and the stake then belongs to the receiver.
 
 // GetVoter returns voter info given a voter name from blockchain.
 func (query *Query) GetVoter(ctx context.Context, voterName string) (*model.Voter, error) {
@@ -39,3 +32,59 @@ func (query *Query) GetVoter(ctx context.Context, voterName string) (*model.Vote
 	}
 	return voter, nil
 }
+
+// GetStakeStats returns the global stake statistic of a certain day,
+// counted from the chain start time.
+func (query *Query) GetStakeStats(ctx context.Context, day int64) (*model.LinoStakeStat, error) {
+	resp, err := query.transport.Query(ctx, VoteKVStoreKey, vote.QueryStakeStats, []string{strconv.FormatInt(day, 10)})
+	if err != nil {
+		linoe, ok := err.(errors.Error)
+		if ok && linoe.BlockChainCode() == uint32(linotypes.CodeStakeStatNotFound) {
+			return nil, errors.EmptyResponse("stake stat is not found")
+		}
+		return nil, err
+	}
+	stat := new(model.LinoStakeStat)
+	if err := query.transport.Cdc.UnmarshalJSON(resp, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+// GetStakeSummary returns a combined view of the LINO a user has staked
+// and the LINO that is still waiting to be returned after StakeOut.
+func (query *Query) GetStakeSummary(ctx context.Context, username string) (*linomodel.StakeSummary, error) {
+	summary := &linomodel.StakeSummary{
+		Username:      username,
+		Staked:        linotypes.NewCoinFromInt64(0),
+		Frozen:        linotypes.NewCoinFromInt64(0),
+		Interest:      linotypes.NewCoinFromInt64(0),
+		PendingUnlock: linotypes.NewCoinFromInt64(0),
+	}
+
+	voter, err := query.GetVoter(ctx, username)
+	if err != nil {
+		if !errors.IsEmptyResponse(err) {
+			return nil, err
+		}
+	} else {
+		summary.Staked = voter.LinoStake
+		summary.Frozen = voter.FrozenAmount
+		summary.Interest = voter.Interest
+		summary.Duty = voter.Duty
+	}
+
+	bank, err := query.GetAccountBank(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	status, err := query.GetBlockStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := status.SyncInfo.LatestBlockTime.Unix()
+	for _, frozenMoney := range bank.FrozenMoneyList {
+		summary.PendingUnlock = summary.PendingUnlock.Plus(util.FrozenMoneyRemaining(frozenMoney, now))
+	}
+	return summary, nil
+}
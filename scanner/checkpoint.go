@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lino-network/lino-go/errors"
+)
+
+// Checkpoint persists the next height a scanner should scan, so that it can
+// resume after a restart.
+type Checkpoint interface {
+	// Load returns the saved height, or 0 if nothing is saved yet.
+	Load() (int64, error)
+	Save(height int64) error
+}
+
+// MemCheckpoint keeps the height in memory.
+type MemCheckpoint struct {
+	mtx    sync.Mutex
+	height int64
+}
+
+var _ Checkpoint = &MemCheckpoint{}
+
+// NewMemCheckpoint returns an empty in-memory checkpoint.
+func NewMemCheckpoint() *MemCheckpoint {
+	return &MemCheckpoint{}
+}
+
+// Load implements Checkpoint.
+func (c *MemCheckpoint) Load() (int64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.height, nil
+}
+
+// Save implements Checkpoint.
+func (c *MemCheckpoint) Save(height int64) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.height = height
+	return nil
+}
+
+// FileCheckpoint keeps the height in a file, replaced atomically on save.
+type FileCheckpoint struct {
+	path string
+}
+
+var _ Checkpoint = &FileCheckpoint{}
+
+// NewFileCheckpoint returns a checkpoint stored at @p path.
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{path: path}
+}
+
+// Load implements Checkpoint.
+func (c *FileCheckpoint) Load() (int64, error) {
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.InvalidArgf("failed to read checkpoint %s", c.path).AddCause(err)
+	}
+	height, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, errors.InvalidArgf("invalid checkpoint %s", c.path).AddCause(err)
+	}
+	return height, nil
+}
+
+// Save implements Checkpoint.
+func (c *FileCheckpoint) Save(height int64) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return errors.InvalidArgf("failed to save checkpoint %s", c.path).AddCause(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strconv.FormatInt(height, 10)); err != nil {
+		tmp.Close()
+		return errors.InvalidArgf("failed to save checkpoint %s", c.path).AddCause(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.InvalidArgf("failed to save checkpoint %s", c.path).AddCause(err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return errors.InvalidArgf("failed to save checkpoint %s", c.path).AddCause(err)
+	}
+	return nil
+}
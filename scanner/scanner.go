@@ -0,0 +1,202 @@
+// Package scanner walks blocks of the blockchain in height order and
+// decodes every transaction in them, so indexers and watchers don't
+// have to re-implement the block loop.
+package scanner
+
+import (
+	"context"
+	"time"
+
+	wire "github.com/cosmos/cosmos-sdk/codec"
+	auth "github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linoapp "github.com/lino-network/lino/app"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	ttypes "github.com/tendermint/tendermint/types"
+)
+
+// BlockSource provides blocks and their results. *query.Query implements it;
+// tests can serve recorded blocks instead.
+type BlockSource interface {
+	GetBlock(ctx context.Context, height int64) (*ttypes.Block, error)
+	GetBlockResults(ctx context.Context, height int64) (*ctypes.ResultBlockResults, error)
+	GetBlockStatus(ctx context.Context) (*ctypes.ResultStatus, error)
+}
+
+// Block is a block with its txs decoded and joined with their results.
+type Block struct {
+	Height int64
+	Hash   string
+	Time   time.Time
+	Txs    []*Tx
+}
+
+// Tx is a decoded tx of a block. If the tx can't be decoded by the current
+// codec, e.g. it carries a msg removed by a chain upgrade, DecodeErr is set,
+// BlockTx.Tx is empty and Raw still holds the tx bytes.
+type Tx struct {
+	model.BlockTx
+	Raw       []byte
+	DecodeErr error
+}
+
+// Handler processes a scanned block. Returning an error stops the scan
+// before the block is checkpointed, so it is delivered again on resume.
+type Handler func(ctx context.Context, block *Block) error
+
+// Options configures a Scanner.
+type Options struct {
+	// StartHeight is the first height scanned when the checkpoint is empty.
+	// Defaults to 1.
+	StartHeight int64
+	// EndHeight is the last height scanned. 0 follows the tip until the
+	// context is done.
+	EndHeight int64
+	// Confirmations is how many blocks the scanner stays behind the tip.
+	Confirmations int64
+	// PollInterval is how long to wait for new blocks when at the tip.
+	// Defaults to one second.
+	PollInterval time.Duration
+}
+
+// Scanner walks blocks in height order and hands them to a Handler.
+type Scanner struct {
+	source     BlockSource
+	checkpoint Checkpoint
+	cdc        *wire.Codec
+	opts       Options
+}
+
+// NewScanner returns an instance of Scanner reading from @p source and
+// saving its progress in @p checkpoint.
+func NewScanner(source BlockSource, checkpoint Checkpoint, opts Options) *Scanner {
+	if opts.StartHeight <= 0 {
+		opts.StartHeight = 1
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = time.Second
+	}
+	if checkpoint == nil {
+		checkpoint = NewMemCheckpoint()
+	}
+	return &Scanner{
+		source:     source,
+		checkpoint: checkpoint,
+		cdc:        linoapp.MakeCodec(),
+		opts:       opts,
+	}
+}
+
+// NextHeight returns the height the scanner will scan next.
+func (s *Scanner) NextHeight() (int64, error) {
+	height, err := s.checkpoint.Load()
+	if err != nil {
+		return 0, err
+	}
+	if height <= 0 {
+		return s.opts.StartHeight, nil
+	}
+	return height, nil
+}
+
+// Run scans blocks from the checkpoint (or StartHeight) and calls @p handler
+// on each of them, saving the checkpoint after every handled block. It
+// returns nil once EndHeight is handled, or the context error when the
+// context is done.
+func (s *Scanner) Run(ctx context.Context, handler Handler) error {
+	height, err := s.NextHeight()
+	if err != nil {
+		return err
+	}
+
+	for s.opts.EndHeight == 0 || height <= s.opts.EndHeight {
+		tip, err := s.safeTip(ctx)
+		if err != nil {
+			return err
+		}
+
+		if height > tip {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(s.opts.PollInterval):
+			}
+			continue
+		}
+
+		for ; height <= tip && (s.opts.EndHeight == 0 || height <= s.opts.EndHeight); height++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			block, err := s.ScanBlock(ctx, height)
+			if err != nil {
+				return err
+			}
+			if err := handler(ctx, block); err != nil {
+				return err
+			}
+			if err := s.checkpoint.Save(height + 1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ScanBlock fetches the block at @p height with its results and decodes its txs.
+func (s *Scanner) ScanBlock(ctx context.Context, height int64) (*Block, error) {
+	block, err := s.source.GetBlock(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.source.GetBlockResults(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil || results == nil || results.Results == nil {
+		return nil, errors.EmptyResponsef("block %v is not found", height)
+	}
+	if len(results.Results.DeliverTx) != len(block.Txs) {
+		return nil, errors.QueryFailf(
+			"block %v has %v txs but %v results", height, len(block.Txs), len(results.Results.DeliverTx))
+	}
+
+	res := &Block{
+		Height: height,
+		Hash:   block.Hash().String(),
+		Time:   block.Time,
+		Txs:    make([]*Tx, 0, len(block.Txs)),
+	}
+	for i, rawTx := range block.Txs {
+		result := results.Results.DeliverTx[i]
+		tx := &Tx{
+			BlockTx: model.BlockTx{
+				Height: height,
+				Index:  uint32(i),
+				Hash:   cmn.HexBytes(rawTx.Hash()).String(),
+				Code:   result.Code,
+				Log:    result.Log,
+			},
+			Raw: rawTx,
+		}
+		var stdTx auth.StdTx
+		if err := s.cdc.UnmarshalJSON(rawTx, &stdTx); err != nil {
+			tx.DecodeErr = errors.UnmarshaFailed("failed to decode tx").AddCause(err)
+		} else {
+			tx.Tx = stdTx
+		}
+		res.Txs = append(res.Txs, tx)
+	}
+	return res, nil
+}
+
+// safeTip returns the highest height that has enough confirmations.
+func (s *Scanner) safeTip(ctx context.Context) (int64, error) {
+	status, err := s.source.GetBlockStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return status.SyncInfo.LatestBlockHeight - s.opts.Confirmations, nil
+}
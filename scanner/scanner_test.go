@@ -0,0 +1,205 @@
+package scanner
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth"
+	linoapp "github.com/lino-network/lino/app"
+	linotypes "github.com/lino-network/lino/types"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/state"
+	ttypes "github.com/tendermint/tendermint/types"
+)
+
+type fakeSource struct {
+	blocks  map[int64]*ttypes.Block
+	results map[int64]*ctypes.ResultBlockResults
+	tip     int64
+}
+
+func (f *fakeSource) GetBlock(ctx context.Context, height int64) (*ttypes.Block, error) {
+	return f.blocks[height], nil
+}
+
+func (f *fakeSource) GetBlockResults(ctx context.Context, height int64) (*ctypes.ResultBlockResults, error) {
+	return f.results[height], nil
+}
+
+func (f *fakeSource) GetBlockStatus(ctx context.Context) (*ctypes.ResultStatus, error) {
+	return &ctypes.ResultStatus{SyncInfo: ctypes.SyncInfo{LatestBlockHeight: f.tip}}, nil
+}
+
+func (f *fakeSource) addBlock(height int64, txs []ttypes.Tx, codes []uint32) {
+	results := &state.ABCIResponses{}
+	for _, code := range codes {
+		results.DeliverTx = append(results.DeliverTx, &abci.ResponseDeliverTx{Code: code})
+	}
+	f.blocks[height] = ttypes.MakeBlock(height, txs, nil, nil)
+	f.results[height] = &ctypes.ResultBlockResults{Height: height, Results: results}
+	f.tip = height
+}
+
+func newFakeSource(t *testing.T) *fakeSource {
+	cdc := linoapp.MakeCodec()
+	msg := acctypes.NewTransferMsg("alice", "bob", "1", "memo")
+	transfer, err := cdc.MarshalJSON(auth.NewStdTx([]sdk.Msg{msg}, auth.StdFee{}, nil, ""))
+	if err != nil {
+		t.Fatalf("failed to encode tx: %v", err)
+	}
+
+	source := &fakeSource{
+		blocks:  map[int64]*ttypes.Block{},
+		results: map[int64]*ctypes.ResultBlockResults{},
+	}
+	source.addBlock(1, nil, nil)
+	source.addBlock(2, []ttypes.Tx{transfer, ttypes.Tx("garbage")}, []uint32{0, 1})
+	source.addBlock(3, []ttypes.Tx{transfer}, []uint32{0})
+	return source
+}
+
+func TestScanBlock(t *testing.T) {
+	s := NewScanner(newFakeSource(t), nil, Options{})
+	block, err := s.ScanBlock(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("failed to scan block: %v", err)
+	}
+	if len(block.Txs) != 2 {
+		t.Fatalf("diff number of txs, got %d, want 2", len(block.Txs))
+	}
+
+	transfer := block.Txs[0]
+	if transfer.DecodeErr != nil || len(transfer.Tx.Msgs) != 1 {
+		t.Errorf("failed to decode transfer, err %v", transfer.DecodeErr)
+	} else if msg, ok := transfer.Tx.Msgs[0].(acctypes.TransferMsg); !ok || msg.Receiver != linotypes.AccountKey("bob") {
+		t.Errorf("diff msg, got %+v", transfer.Tx.Msgs[0])
+	}
+	if transfer.Height != 2 || transfer.Index != 0 || transfer.Code != 0 || transfer.Hash == "" {
+		t.Errorf("diff transfer result, got %+v", transfer.BlockTx)
+	}
+
+	garbage := block.Txs[1]
+	if garbage.DecodeErr == nil || garbage.Index != 1 || garbage.Code != 1 {
+		t.Errorf("diff undecodable tx, got %+v", garbage)
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	source := newFakeSource(t)
+	checkpoint := NewMemCheckpoint()
+
+	// runs share the checkpoint, so they are executed in order.
+	testCases := []struct {
+		testName      string
+		opts          Options
+		expectHeights []int64
+	}{
+		{
+			testName:      "first run stays one block behind tip",
+			opts:          Options{EndHeight: 2, Confirmations: 1},
+			expectHeights: []int64{1, 2},
+		},
+		{
+			testName:      "second run resumes after checkpoint",
+			opts:          Options{EndHeight: 3},
+			expectHeights: []int64{3},
+		},
+	}
+
+	for _, tc := range testCases {
+		testName := tc.testName
+		heights := []int64{}
+		err := NewScanner(source, checkpoint, tc.opts).Run(context.Background(), func(ctx context.Context, block *Block) error {
+			heights = append(heights, block.Height)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: failed to run, got err %v", testName, err)
+		}
+		if !reflect.DeepEqual(heights, tc.expectHeights) {
+			t.Errorf("%s: diff heights, got %v, want %v", testName, heights, tc.expectHeights)
+		}
+	}
+}
+
+func TestFileCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	checkpoint := NewFileCheckpoint(filepath.Join(dir, "height"))
+	if height, err := checkpoint.Load(); err != nil || height != 0 {
+		t.Errorf("empty checkpoint: got %d, %v", height, err)
+	}
+	if err := checkpoint.Save(42); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	if height, err := checkpoint.Load(); err != nil || height != 42 {
+		t.Errorf("saved checkpoint: got %d, %v, want 42", height, err)
+	}
+}
+
+func newFixture(t *testing.T) *Fixture {
+	fixture, err := RecordFixture(context.Background(), newFakeSource(t), 1, 3)
+	if err != nil {
+		t.Fatalf("failed to record fixture: %v", err)
+	}
+	return fixture
+}
+
+func TestFixtureScanBlock(t *testing.T) {
+	ctx := context.Background()
+	expect, err := NewScanner(newFakeSource(t), nil, Options{}).ScanBlock(ctx, 2)
+	if err != nil {
+		t.Fatalf("failed to scan source: %v", err)
+	}
+	block, err := NewScanner(newFixture(t), nil, Options{}).ScanBlock(ctx, 2)
+	if err != nil {
+		t.Fatalf("failed to scan fixture: %v", err)
+	}
+	if len(block.Txs) != len(expect.Txs) {
+		t.Fatalf("diff number of txs, got %d, want %d", len(block.Txs), len(expect.Txs))
+	}
+	for i, tx := range block.Txs {
+		if !reflect.DeepEqual(tx.BlockTx, expect.Txs[i].BlockTx) || (tx.DecodeErr == nil) != (expect.Txs[i].DecodeErr == nil) {
+			t.Errorf("diff tx %d, got %+v, want %+v", i, tx, expect.Txs[i])
+		}
+	}
+}
+
+func TestFixtureSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "chain.json")
+	if err := newFixture(t).Save(path); err != nil {
+		t.Fatalf("failed to save fixture: %v", err)
+	}
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+
+	block, err := NewScanner(fixture, nil, Options{}).ScanBlock(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("failed to scan loaded fixture: %v", err)
+	}
+	if len(block.Txs) != 1 || block.Txs[0].DecodeErr != nil {
+		t.Errorf("diff txs of loaded fixture, got %+v", block.Txs)
+	}
+	if _, err := fixture.GetBlock(context.Background(), 4); err == nil {
+		t.Errorf("block out of fixture range should return err")
+	}
+}
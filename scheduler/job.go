@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/util"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// MsgType is the msg a template builds.
+type MsgType string
+
+// Msg types of templates.
+const (
+	// Transfer sends LINO from the signer to To.
+	Transfer MsgType = "transfer"
+	// IDATransfer grants IDA of App to To, signed by the app or one of its
+	// affiliated accounts.
+	IDATransfer MsgType = "ida_transfer"
+	// Donate donates LINO of the signer to post PostID of To, from App if
+	// set.
+	Donate MsgType = "donate"
+)
+
+// PeriodPlaceholder in a memo is replaced with the period of the run as
+// 2006-01-02T15:04Z, so that transfers of different periods are told apart.
+const PeriodPlaceholder = "{period}"
+
+// Template is the msg a job broadcasts every period.
+type Template struct {
+	Type   MsgType `json:"type"`
+	To     string  `json:"to"`
+	Amount string  `json:"amount"`
+	App    string  `json:"app,omitempty"`
+	PostID string  `json:"post_id,omitempty"`
+	Memo   string  `json:"memo,omitempty"`
+}
+
+// Validate checks the template of a job signed by @p signer.
+func (t *Template) Validate(signer string) error {
+	if !util.CheckUsername(t.To) {
+		return errors.InvalidArgf("invalid receiver %q", t.To)
+	}
+	unit := amount.LINO
+	switch t.Type {
+	case Transfer:
+		if t.To == signer {
+			return errors.InvalidArgf("%s cannot transfer to itself", signer)
+		}
+	case IDATransfer:
+		unit = amount.IDA
+		if !util.CheckUsername(t.App) || t.To == t.App {
+			return errors.InvalidArgf("invalid app %q of ida transfer to %s", t.App, t.To)
+		}
+	case Donate:
+		if t.PostID == "" || t.To == signer {
+			return errors.InvalidArgf("invalid donation of %s to %s#%s", signer, t.To, t.PostID)
+		}
+		if t.App != "" && !util.CheckUsername(t.App) {
+			return errors.InvalidArgf("invalid app %q", t.App)
+		}
+	default:
+		return errors.InvalidArgf("unknown msg type %q", t.Type)
+	}
+	amt, err := amount.Parse(t.Amount, unit)
+	if err != nil || !amt.IsPositive() {
+		return errors.InvalidArgf("amount must be positive %s, got %q", unit.Name, t.Amount)
+	}
+	if len(t.memo(time.Time{})) > linotypes.MaximumMemoLength {
+		return errors.InvalidArgf("memo must be at most %v bytes", linotypes.MaximumMemoLength)
+	}
+	return nil
+}
+
+// memo returns the memo of the run of @p period.
+func (t *Template) memo(period time.Time) string {
+	return strings.Replace(t.Memo, PeriodPlaceholder, period.UTC().Format("2006-01-02T15:04Z"), -1)
+}
+
+// build returns the tx bytes of the run of @p period, built with @p chain.
+func (t *Template) build(chain Chain, signer, privKeyHex string, period time.Time, seq uint64) ([]byte, errors.Error) {
+	amt := strings.TrimSpace(t.Amount)
+	switch t.Type {
+	case Transfer:
+		return chain.MakeTransferMsg(signer, t.To, amt, t.memo(period), privKeyHex, seq)
+	case IDATransfer:
+		return chain.MakeIDATransferMsg(t.App, amt, t.App, t.To, signer, t.memo(period), privKeyHex, seq)
+	case Donate:
+		return chain.MakeDonateMsg(signer, t.To, amt, t.PostID, t.App, t.memo(period), privKeyHex, seq)
+	}
+	return nil, errors.InvalidArgf("unknown msg type %q", t.Type)
+}
+
+// Job broadcasts its template, signed by Signer, at the times of Schedule.
+type Job struct {
+	ID       string   `json:"id"`
+	Schedule string   `json:"schedule"`
+	Signer   string   `json:"signer"`
+	Template Template `json:"template"`
+	// Next is the next period to run.
+	Next      time.Time `json:"next"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the job.
+func (job *Job) Validate() error {
+	if job.ID == "" {
+		return errors.InvalidArg("empty job id")
+	}
+	if _, err := ParseSchedule(job.Schedule); err != nil {
+		return err
+	}
+	if !util.CheckUsername(job.Signer) {
+		return errors.InvalidArgf("invalid signer %q", job.Signer)
+	}
+	return job.Template.Validate(job.Signer)
+}
+
+// RunStatus is the state of a run.
+type RunStatus string
+
+// Statuses of runs. A run is Running from before its tx is built until the
+// result is known, and Unknown if that could not be told, e.g. on a
+// timeout, until a later tick settles it. Missed periods are Skipped.
+const (
+	Running   RunStatus = "running"
+	Succeeded RunStatus = "succeeded"
+	Failed    RunStatus = "failed"
+	Unknown   RunStatus = "unknown"
+	Skipped   RunStatus = "skipped"
+)
+
+// Run is the execution of a job for a period. There is at most one per
+// job and period.
+type Run struct {
+	JobID  string    `json:"job_id"`
+	Period time.Time `json:"period"`
+	Status RunStatus `json:"status"`
+	Signer string    `json:"signer"`
+	// Seq is the sequence number of the last tx built for the run and
+	// Hashes the hashes of all of them.
+	Seq        uint64    `json:"seq,omitempty"`
+	Hashes     []string  `json:"hashes,omitempty"`
+	TxHash     string    `json:"tx_hash,omitempty"`
+	Height     int64     `json:"height,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// isSettled returns true if the result of the run is known.
+func (run *Run) isSettled() bool {
+	return run.Status != Running && run.Status != Unknown
+}
@@ -0,0 +1,291 @@
+// Package scheduler broadcasts recurring transfers, IDA grants and
+// donations on cron schedules, e.g. for subscriptions.
+//
+// Every period of a job runs at most once: its run is saved before the tx
+// is built, the hash of every tx built is saved before it may be broadcast,
+// and a period that already has a run is never run again. Runs whose result
+// is unknown, e.g. after a crash or a timeout, are settled by later ticks
+// from the saved hashes. Only one scheduler may use a store at a time.
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/lino-network/lino-go/api"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/util"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// Chain builds and broadcasts msgs. *api.API implements it.
+type Chain interface {
+	api.TxSource
+	GuaranteeBroadcast(ctx context.Context, signers []linotypes.AccOrAddr, f api.MsgBuilderFunc) (*model.BroadcastResponse, []string, errors.Error)
+	MakeTransferMsg(sender, receiver, amount, memo, privKeyHex string, seq uint64) ([]byte, errors.Error)
+	MakeIDATransferMsg(app, amount, from, to, signer, memo string, privKeyHex string, seq uint64) ([]byte, errors.Error)
+	MakeDonateMsg(username, author, amount, postID, fromApp, memo string, privKeyHex string, seq uint64) ([]byte, errors.Error)
+}
+
+// Clock tells the time of the scheduler.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to Clock.
+type ClockFunc func() time.Time
+
+// Now implements Clock.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// Options configures a Scheduler.
+type Options struct {
+	// Keys returns the private key of a signer.
+	Keys func(signer string) (privKeyHex string, err error)
+	// Clock defaults to the system clock.
+	Clock Clock
+	// PollInterval is how often Run ticks. Defaults to 30 seconds.
+	PollInterval time.Duration
+	// CatchUp runs every period missed while the scheduler was down. By
+	// default only the latest one runs and the others are Skipped.
+	CatchUp bool
+	// OnRun is called with every run once its result is known.
+	OnRun func(run *Run)
+	// OnError is called with every error of a tick.
+	OnError func(err error)
+}
+
+// Scheduler runs the jobs of a store when they are due.
+type Scheduler struct {
+	chain Chain
+	store Store
+	opts  Options
+}
+
+// NewScheduler returns an instance of Scheduler broadcasting on @p chain
+// the jobs of @p store.
+func NewScheduler(chain Chain, store Store, opts Options) (*Scheduler, error) {
+	if opts.Keys == nil {
+		return nil, errors.InvalidArg("no keys")
+	}
+	if opts.Clock == nil {
+		opts.Clock = ClockFunc(time.Now)
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 30 * time.Second
+	}
+	if store == nil {
+		store = NewMemStore()
+	}
+	return &Scheduler{chain: chain, store: store, opts: opts}, nil
+}
+
+// Add validates and saves @p job. Its first period is the first time of its
+// schedule after now, unless Next is set.
+func (s *Scheduler) Add(ctx context.Context, job Job) (*Job, error) {
+	if err := job.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetJob(ctx, job.ID); err == nil {
+		return nil, errors.InvalidArgf("job %s already exists", job.ID)
+	} else if !errors.IsEmptyResponse(err) {
+		return nil, err
+	}
+	now := s.opts.Clock.Now().UTC()
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = now
+	}
+	if job.Next.IsZero() {
+		schedule, _ := ParseSchedule(job.Schedule)
+		job.Next = schedule.Next(now)
+		if job.Next.IsZero() {
+			return nil, errors.InvalidArgf("schedule %q never runs", job.Schedule)
+		}
+	}
+	if err := s.store.PutJob(ctx, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Remove deletes the job with @p id. Its runs are kept.
+func (s *Scheduler) Remove(ctx context.Context, id string) error {
+	return s.store.DeleteJob(ctx, id)
+}
+
+// Jobs returns all jobs.
+func (s *Scheduler) Jobs(ctx context.Context) ([]*Job, error) {
+	return s.store.Jobs(ctx)
+}
+
+// History returns the runs of job @p jobID, oldest period first.
+func (s *Scheduler) History(ctx context.Context, jobID string) ([]*Run, error) {
+	return s.store.Runs(ctx, jobID)
+}
+
+// Run ticks every PollInterval until the context is done, reporting errors
+// to OnError.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		err := s.Tick(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.opts.OnError != nil && err != nil {
+			s.opts.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.opts.PollInterval):
+		}
+	}
+}
+
+// Tick settles the runs whose result is unknown and runs the periods of
+// all jobs due by now. It returns the first error and still ticks the
+// other jobs.
+func (s *Scheduler) Tick(ctx context.Context) error {
+	unsettled, err := s.store.Unsettled(ctx)
+	if err != nil {
+		return err
+	}
+	for _, run := range unsettled {
+		if err := s.settle(ctx, run); err != nil {
+			return err
+		}
+	}
+
+	jobs, err := s.store.Jobs(ctx)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, job := range jobs {
+		if err := s.runDue(ctx, job); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// runDue runs the periods of @p job due by now and advances its Next.
+func (s *Scheduler) runDue(ctx context.Context, job *Job) error {
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return err
+	}
+	now := s.opts.Clock.Now()
+	for !job.Next.IsZero() && !job.Next.After(now) {
+		period := job.Next
+		next := schedule.Next(period)
+		_, err := s.store.GetRun(ctx, job.ID, period)
+		switch {
+		case err == nil:
+			// ran before a crash or an error saving the job.
+		case !errors.IsEmptyResponse(err):
+			return err
+		case !s.opts.CatchUp && !next.IsZero() && !next.After(now):
+			skipped := &Run{JobID: job.ID, Period: period, Status: Skipped, Signer: job.Signer, StartedAt: now, FinishedAt: now}
+			if err := s.store.PutRun(ctx, skipped); err != nil {
+				return err
+			}
+		default:
+			if err := s.execute(ctx, job, period); err != nil {
+				return err
+			}
+		}
+		job.Next = next
+		if err := s.store.PutJob(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execute runs @p job for @p period.
+func (s *Scheduler) execute(ctx context.Context, job *Job, period time.Time) error {
+	run := &Run{
+		JobID:     job.ID,
+		Period:    period,
+		Status:    Running,
+		Signer:    job.Signer,
+		StartedAt: s.opts.Clock.Now(),
+	}
+	if err := s.store.PutRun(ctx, run); err != nil {
+		return err
+	}
+	privKeyHex, err := s.opts.Keys(job.Signer)
+	if err != nil {
+		run.Status, run.Error = Failed, err.Error()
+		return s.finish(ctx, run)
+	}
+
+	// save every tx before it may be broadcast.
+	record := &api.TxRecord{}
+	build := record.Recorder(func(seqs []uint64) ([]byte, errors.Error) {
+		return job.Template.build(s.chain, job.Signer, privKeyHex, period, seqs[0])
+	}, func() error {
+		run.Seq, run.Hashes = record.Seq, append([]string(nil), record.Hashes...)
+		return s.store.PutRun(ctx, run)
+	})
+	resp, _, linoErr := s.chain.GuaranteeBroadcast(ctx, util.GetSignerList(job.Signer), build)
+	switch {
+	case linoErr == nil:
+		run.Status, run.TxHash, run.Height = Succeeded, resp.CommitHash, resp.Height
+	case len(run.Hashes) == 0 || linoErr.CodeType() == errors.CodeCheckTxFail ||
+		linoErr.CodeType() == errors.CodeDeliverTxFail:
+		run.Status, run.Error = Failed, linoErr.Error()
+	default:
+		run.Status, run.Error = Unknown, linoErr.Error()
+	}
+	return s.finish(ctx, run)
+}
+
+// settle resolves @p run from the txs built for it. It stays unsettled if
+// one of them may still commit.
+func (s *Scheduler) settle(ctx context.Context, run *Run) error {
+	tx, open, err := api.SettleTxs(ctx, s.chain, run.Signer, api.TxRecord{Seq: run.Seq, Hashes: run.Hashes})
+	if err != nil {
+		return err
+	}
+	if tx != nil {
+		run.Status, run.TxHash, run.Height, run.Error = Succeeded, tx.Hash, tx.Height, ""
+		return s.finish(ctx, run)
+	}
+	if open {
+		if run.Status == Running {
+			run.Status = Unknown
+			return s.store.PutRun(ctx, run)
+		}
+		return nil
+	}
+	run.Status = Failed
+	if run.Error == "" {
+		run.Error = "not committed"
+	}
+	return s.finish(ctx, run)
+}
+
+// finish saves @p run and reports it if it is settled.
+func (s *Scheduler) finish(ctx context.Context, run *Run) error {
+	if run.isSettled() {
+		run.FinishedAt = s.opts.Clock.Now()
+	}
+	if err := s.store.PutRun(ctx, run); err != nil {
+		return err
+	}
+	if run.isSettled() && s.opts.OnRun != nil {
+		s.opts.OnRun(run)
+	}
+	return nil
+}
@@ -0,0 +1,429 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lino-network/lino-go/api"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	ttypes "github.com/tendermint/tendermint/types"
+)
+
+type fakeMsg struct {
+	Type   MsgType
+	From   string
+	To     string
+	Amount string
+	Memo   string
+	Seq    uint64
+}
+
+type fakeChain struct {
+	seq uint64
+	// txs are the committed txs by hash, built the msgs of built txs and
+	// committed the committed msgs in order.
+	txs       map[string]*accmodel.Transaction
+	built     map[string]fakeMsg
+	committed []fakeMsg
+	// reject makes the chain reject all txs.
+	reject bool
+	// timeout makes the next broadcast time out, committing the tx if
+	// commitOnTimeout is set.
+	timeout         bool
+	commitOnTimeout bool
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{seq: 3, txs: map[string]*accmodel.Transaction{}, built: map[string]fakeMsg{}}
+}
+
+func (c *fakeChain) build(msg fakeMsg) ([]byte, errors.Error) {
+	b, _ := json.Marshal(msg)
+	c.built[hex.EncodeToString(ttypes.Tx(b).Hash())] = msg
+	return b, nil
+}
+
+func (c *fakeChain) MakeTransferMsg(sender, receiver, amount, memo, privKeyHex string, seq uint64) ([]byte, errors.Error) {
+	return c.build(fakeMsg{Transfer, sender, receiver, amount, memo, seq})
+}
+
+func (c *fakeChain) MakeIDATransferMsg(app, amount, from, to, signer, memo string, privKeyHex string, seq uint64) ([]byte, errors.Error) {
+	return c.build(fakeMsg{IDATransfer, from, to, amount, memo, seq})
+}
+
+func (c *fakeChain) MakeDonateMsg(username, author, amount, postID, fromApp, memo string, privKeyHex string, seq uint64) ([]byte, errors.Error) {
+	return c.build(fakeMsg{Donate, username, author, amount, memo, seq})
+}
+
+func (c *fakeChain) GetTxAndSequenceNumberByUsername(ctx context.Context, username, hash string) (*accmodel.TxAndSequenceNumber, error) {
+	return &accmodel.TxAndSequenceNumber{Username: username, Sequence: c.seq, Tx: c.txs[hash]}, nil
+}
+
+func (c *fakeChain) commit(hash string) {
+	c.committed = append(c.committed, c.built[hash])
+	c.seq++
+	c.txs[hash] = &accmodel.Transaction{Hash: hash, Height: int64(len(c.txs) + 1)}
+}
+
+func (c *fakeChain) GuaranteeBroadcast(ctx context.Context, signers []linotypes.AccOrAddr,
+	f api.MsgBuilderFunc) (*model.BroadcastResponse, []string, errors.Error) {
+	b, err := f([]uint64{c.seq})
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := hex.EncodeToString(ttypes.Tx(b).Hash())
+	if c.timeout {
+		c.timeout = false
+		if c.commitOnTimeout {
+			c.commit(hash)
+		}
+		return nil, []string{hash}, errors.BroadcastTimeoutf("GuaranteeBroadcast timeout")
+	}
+	if c.reject {
+		return nil, []string{hash}, errors.CheckTxFail("CheckTx failed!")
+	}
+	c.commit(hash)
+	return &model.BroadcastResponse{CommitHash: hash, Height: c.txs[hash].Height}, []string{hash}, nil
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func newScheduler(t *testing.T, chain Chain, clock Clock, catchUp bool) *Scheduler {
+	scheduler, err := NewScheduler(chain, NewMemStore(), Options{
+		Keys:    func(signer string) (string, error) { return "key", nil },
+		Clock:   clock,
+		CatchUp: catchUp,
+	})
+	if err != nil {
+		t.Fatalf("failed to create scheduler, got err %v", err)
+	}
+	return scheduler
+}
+
+var hourlyJob = Job{
+	ID:       "rent",
+	Schedule: "@hourly",
+	Signer:   "alice",
+	Template: Template{Type: Transfer, To: "bob", Amount: "1.5", Memo: "rent {period}"},
+}
+
+func statuses(t *testing.T, scheduler *Scheduler, jobID string) []RunStatus {
+	runs, err := scheduler.History(context.Background(), jobID)
+	if err != nil {
+		t.Fatalf("failed to get history, got err %v", err)
+	}
+	res := []RunStatus{}
+	for _, run := range runs {
+		res = append(res, run.Status)
+	}
+	return res
+}
+
+func equalStatuses(a, b []RunStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestScheduleNext(t *testing.T) {
+	testCases := map[string]struct {
+		spec      string
+		from      string
+		expect    string
+		expectErr bool
+	}{
+		"every minute": {
+			spec:   "* * * * *",
+			from:   "2019-01-01 10:00",
+			expect: "2019-01-01 10:01",
+		},
+		"monthly": {
+			spec:   "@monthly",
+			from:   "2019-01-31 23:59",
+			expect: "2019-02-01 00:00",
+		},
+		"steps": {
+			spec:   "*/15 9-17/4 * * *",
+			from:   "2019-01-01 13:50",
+			expect: "2019-01-01 17:00",
+		},
+		"list": {
+			spec:   "0 0 1,15 * *",
+			from:   "2019-01-02 00:00",
+			expect: "2019-01-15 00:00",
+		},
+		"day of month or week": {
+			spec:   "0 12 13 * 5",
+			from:   "2019-09-01 00:00",
+			expect: "2019-09-06 12:00",
+		},
+		"sunday as 7": {
+			spec:   "30 8 * * 7",
+			from:   "2019-09-02 00:00",
+			expect: "2019-09-08 08:30",
+		},
+		"leap day": {
+			spec:   "0 0 29 2 *",
+			from:   "2019-03-01 00:00",
+			expect: "2020-02-29 00:00",
+		},
+		"never": {
+			spec: "0 0 30 2 *",
+			from: "2019-01-01 00:00",
+		},
+		"too few fields": {
+			spec:      "0 0 * *",
+			expectErr: true,
+		},
+		"out of range": {
+			spec:      "60 * * * *",
+			expectErr: true,
+		},
+		"zero step": {
+			spec:      "*/0 * * * *",
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		schedule, err := ParseSchedule(tc.spec)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		next := schedule.Next(at(tc.from))
+		if tc.expect == "" {
+			if !next.IsZero() {
+				t.Errorf("%s: diff next, got %v, expect none", testName, next)
+			}
+			continue
+		}
+		if !next.Equal(at(tc.expect)) {
+			t.Errorf("%s: diff next, got %v, expect %v", testName, next, tc.expect)
+		}
+	}
+}
+
+func TestJobValidate(t *testing.T) {
+	testCases := map[string]struct {
+		job       Job
+		expectErr bool
+	}{
+		"transfer": {
+			job: hourlyJob,
+		},
+		"ida transfer": {
+			job: Job{ID: "grant", Schedule: "@daily", Signer: "app",
+				Template: Template{Type: IDATransfer, To: "bob", Amount: "10", App: "app"}},
+		},
+		"donate": {
+			job: Job{ID: "support", Schedule: "@weekly", Signer: "alice",
+				Template: Template{Type: Donate, To: "bob", Amount: "1", PostID: "post"}},
+		},
+		"transfer to itself": {
+			job: Job{ID: "self", Schedule: "@daily", Signer: "alice",
+				Template: Template{Type: Transfer, To: "alice", Amount: "1"}},
+			expectErr: true,
+		},
+		"donate without post": {
+			job: Job{ID: "support", Schedule: "@weekly", Signer: "alice",
+				Template: Template{Type: Donate, To: "bob", Amount: "1"}},
+			expectErr: true,
+		},
+		"ida transfer without app": {
+			job: Job{ID: "grant", Schedule: "@daily", Signer: "app",
+				Template: Template{Type: IDATransfer, To: "bob", Amount: "10"}},
+			expectErr: true,
+		},
+		"zero amount": {
+			job: Job{ID: "rent", Schedule: "@daily", Signer: "alice",
+				Template: Template{Type: Transfer, To: "bob", Amount: "0"}},
+			expectErr: true,
+		},
+		"invalid schedule": {
+			job: Job{ID: "rent", Schedule: "@often", Signer: "alice",
+				Template: Template{Type: Transfer, To: "bob", Amount: "1"}},
+			expectErr: true,
+		},
+		"unknown type": {
+			job: Job{ID: "rent", Schedule: "@daily", Signer: "alice",
+				Template: Template{Type: "stake", To: "bob", Amount: "1"}},
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		err := tc.job.Validate()
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+		}
+	}
+}
+
+func TestTick(t *testing.T) {
+	ctx := context.Background()
+	testCases := map[string]struct {
+		catchUp       bool
+		expectRuns    []RunStatus
+		expectPeriods []string
+	}{
+		"skips missed periods": {
+			expectRuns:    []RunStatus{Succeeded, Skipped, Skipped, Succeeded},
+			expectPeriods: []string{"2019-01-01T11:00Z", "2019-01-01T14:00Z"},
+		},
+		"catches up": {
+			catchUp:       true,
+			expectRuns:    []RunStatus{Succeeded, Succeeded, Succeeded, Succeeded},
+			expectPeriods: []string{"2019-01-01T11:00Z", "2019-01-01T12:00Z", "2019-01-01T13:00Z", "2019-01-01T14:00Z"},
+		},
+	}
+
+	for testName, tc := range testCases {
+		chain := newFakeChain()
+		clock := &fakeClock{now: at("2019-01-01 10:30")}
+		scheduler := newScheduler(t, chain, clock, tc.catchUp)
+		job, err := scheduler.Add(ctx, hourlyJob)
+		if err != nil {
+			t.Fatalf("%s: failed to add job, got err %v", testName, err)
+		}
+		if !job.Next.Equal(at("2019-01-01 11:00")) {
+			t.Errorf("%s: diff next, got %v", testName, job.Next)
+		}
+		if _, err := scheduler.Add(ctx, hourlyJob); err == nil {
+			t.Errorf("%s: added job twice", testName)
+		}
+
+		for _, now := range []string{"2019-01-01 10:59", "2019-01-01 11:00", "2019-01-01 11:30", "2019-01-01 14:05", "2019-01-01 14:10"} {
+			clock.now = at(now)
+			if err := scheduler.Tick(ctx); err != nil {
+				t.Errorf("%s: failed to tick at %s, got err %v", testName, now, err)
+			}
+		}
+
+		if got := statuses(t, scheduler, job.ID); !equalStatuses(got, tc.expectRuns) {
+			t.Errorf("%s: diff runs, got %v, expect %v", testName, got, tc.expectRuns)
+		}
+		if len(chain.committed) != len(tc.expectPeriods) {
+			t.Fatalf("%s: diff committed, got %v, expect %d", testName, chain.committed, len(tc.expectPeriods))
+		}
+		for i, period := range tc.expectPeriods {
+			if msg := chain.committed[i]; msg.Memo != "rent "+period || msg.To != "bob" || msg.Amount != "1.5" {
+				t.Errorf("%s: diff msg %d, got %+v, expect period %s", testName, i, msg, period)
+			}
+		}
+		jobs, _ := scheduler.Jobs(ctx)
+		if len(jobs) != 1 || !jobs[0].Next.Equal(at("2019-01-01 15:00")) {
+			t.Errorf("%s: diff jobs, got %+v", testName, jobs)
+		}
+	}
+}
+
+func TestTickSettles(t *testing.T) {
+	ctx := context.Background()
+	testCases := map[string]struct {
+		reject          bool
+		timeout         bool
+		commitOnTimeout bool
+		// advance commits another tx of the signer before the next tick.
+		advance     bool
+		expectFirst RunStatus
+		expectAfter RunStatus
+	}{
+		"rejected": {
+			reject:      true,
+			expectFirst: Failed,
+			expectAfter: Failed,
+		},
+		"committed after timeout": {
+			timeout:         true,
+			commitOnTimeout: true,
+			expectFirst:     Unknown,
+			expectAfter:     Succeeded,
+		},
+		"pending after timeout": {
+			timeout:     true,
+			expectFirst: Unknown,
+			expectAfter: Unknown,
+		},
+		"dropped after timeout": {
+			timeout:     true,
+			advance:     true,
+			expectFirst: Unknown,
+			expectAfter: Failed,
+		},
+	}
+
+	for testName, tc := range testCases {
+		chain := newFakeChain()
+		chain.reject, chain.timeout, chain.commitOnTimeout = tc.reject, tc.timeout, tc.commitOnTimeout
+		clock := &fakeClock{now: at("2019-01-01 10:30")}
+		scheduler := newScheduler(t, chain, clock, false)
+		settled := 0
+		scheduler.opts.OnRun = func(run *Run) { settled++ }
+		if _, err := scheduler.Add(ctx, hourlyJob); err != nil {
+			t.Fatalf("%s: failed to add job, got err %v", testName, err)
+		}
+
+		clock.now = at("2019-01-01 11:00")
+		if err := scheduler.Tick(ctx); err != nil {
+			t.Errorf("%s: failed to tick, got err %v", testName, err)
+		}
+		run, err := scheduler.store.GetRun(ctx, hourlyJob.ID, at("2019-01-01 11:00"))
+		if err != nil || run.Status != tc.expectFirst {
+			t.Errorf("%s: diff first run, got %+v, err %v, expect %v", testName, run, err, tc.expectFirst)
+		}
+
+		chain.reject = false
+		if tc.advance {
+			chain.seq++
+		}
+		clock.now = at("2019-01-01 11:10")
+		if err := scheduler.Tick(ctx); err != nil {
+			t.Errorf("%s: failed to tick, got err %v", testName, err)
+		}
+		run, err = scheduler.store.GetRun(ctx, hourlyJob.ID, at("2019-01-01 11:00"))
+		if err != nil || run.Status != tc.expectAfter {
+			t.Errorf("%s: diff settled run, got %+v, err %v, expect %v", testName, run, err, tc.expectAfter)
+		}
+		// the period is never run again.
+		if len(chain.built) != 1 {
+			t.Errorf("%s: diff built txs, got %d, expect 1", testName, len(chain.built))
+		}
+		expectSettled := 0
+		if run.isSettled() {
+			expectSettled = 1
+		}
+		if settled != expectSettled {
+			t.Errorf("%s: diff settled runs reported, got %d, expect %d", testName, settled, expectSettled)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lino-network/lino-go/errors"
+)
+
+// Store persists jobs and their runs.
+type Store interface {
+	// PutJob adds or replaces the job with the ID of @p job.
+	PutJob(ctx context.Context, job *Job) error
+	// GetJob returns the job with @p id, or an errors.EmptyResponse error
+	// if there is none.
+	GetJob(ctx context.Context, id string) (*Job, error)
+	DeleteJob(ctx context.Context, id string) error
+	// Jobs returns all jobs ordered by ID.
+	Jobs(ctx context.Context) ([]*Job, error)
+	// PutRun adds or replaces the run of the job and period of @p run.
+	PutRun(ctx context.Context, run *Run) error
+	// GetRun returns the run of job @p jobID for @p period, or an
+	// errors.EmptyResponse error if there is none.
+	GetRun(ctx context.Context, jobID string, period time.Time) (*Run, error)
+	// Runs returns the runs of job @p jobID, oldest period first.
+	Runs(ctx context.Context, jobID string) ([]*Run, error)
+	// Unsettled returns the runs that are Running or Unknown.
+	Unsettled(ctx context.Context) ([]*Run, error)
+}
+
+// MemStore keeps jobs and runs in memory.
+type MemStore struct {
+	mtx  sync.Mutex
+	jobs map[string][]byte
+	// runs are by job ID and period.
+	runs map[string]map[int64][]byte
+}
+
+var _ Store = &MemStore{}
+
+// NewMemStore returns an empty in-memory store.
+func NewMemStore() *MemStore {
+	return &MemStore{jobs: map[string][]byte{}, runs: map[string]map[int64][]byte{}}
+}
+
+// PutJob implements Store.
+func (s *MemStore) PutJob(ctx context.Context, job *Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return errors.InvalidArg("failed to encode job").AddCause(err)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.jobs[job.ID] = b
+	return nil
+}
+
+// GetJob implements Store.
+func (s *MemStore) GetJob(ctx context.Context, id string) (*Job, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	b, ok := s.jobs[id]
+	if !ok {
+		return nil, errors.EmptyResponsef("job %s is not found", id)
+	}
+	job := new(Job)
+	return job, decode(b, job)
+}
+
+// DeleteJob implements Store. The runs of the job are kept.
+func (s *MemStore) DeleteJob(ctx context.Context, id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+// Jobs implements Store.
+func (s *MemStore) Jobs(ctx context.Context) ([]*Job, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	res := []*Job{}
+	for _, b := range s.jobs {
+		job := new(Job)
+		if err := decode(b, job); err != nil {
+			return nil, err
+		}
+		res = append(res, job)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+// PutRun implements Store.
+func (s *MemStore) PutRun(ctx context.Context, run *Run) error {
+	b, err := json.Marshal(run)
+	if err != nil {
+		return errors.InvalidArg("failed to encode run").AddCause(err)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.runs[run.JobID] == nil {
+		s.runs[run.JobID] = map[int64][]byte{}
+	}
+	s.runs[run.JobID][run.Period.Unix()] = b
+	return nil
+}
+
+// GetRun implements Store.
+func (s *MemStore) GetRun(ctx context.Context, jobID string, period time.Time) (*Run, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	b, ok := s.runs[jobID][period.Unix()]
+	if !ok {
+		return nil, errors.EmptyResponsef("run of job %s at %v is not found", jobID, period)
+	}
+	run := new(Run)
+	return run, decode(b, run)
+}
+
+// Runs implements Store.
+func (s *MemStore) Runs(ctx context.Context, jobID string) ([]*Run, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.runsOf(jobID, func(run *Run) bool { return true })
+}
+
+// Unsettled implements Store.
+func (s *MemStore) Unsettled(ctx context.Context) ([]*Run, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	res := []*Run{}
+	for jobID := range s.runs {
+		runs, err := s.runsOf(jobID, func(run *Run) bool { return !run.isSettled() })
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, runs...)
+	}
+	return res, nil
+}
+
+func (s *MemStore) runsOf(jobID string, filter func(run *Run) bool) ([]*Run, error) {
+	res := []*Run{}
+	for _, b := range s.runs[jobID] {
+		run := new(Run)
+		if err := decode(b, run); err != nil {
+			return nil, err
+		}
+		if filter(run) {
+			res = append(res, run)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Period.Before(res[j].Period) })
+	return res, nil
+}
+
+func decode(b []byte, v interface{}) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return errors.UnmarshaFailed("invalid scheduler record").AddCause(err)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+// Package staking derives the interest and unlock schedule of LINO staked in
+// the vote module, and compounds interest for a set of accounts.
+//
+// Interest is the consumption friction of each day, shared among the stake
+// that has not claimed it yet. It is read from the daily stake stats, not
+// from the GlobalAllocationParam, whose inflation goes to content creators,
+// apps and validators.
+package staking
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/util"
+	"github.com/lino-network/lino/param"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	globalmodel "github.com/lino-network/lino/x/global/model"
+	votemodel "github.com/lino-network/lino/x/vote/model"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+const (
+	secondsPerDay = 24 * 3600
+	daysPerYear   = 365
+	// maxConcurrentDays is how many stake stats are fetched at once.
+	maxConcurrentDays = 8
+)
+
+// Source provides the stake of a voter and the stake stats. *query.Query
+// implements it.
+type Source interface {
+	GetVoter(ctx context.Context, username string) (*votemodel.Voter, error)
+	GetStakeStats(ctx context.Context, day int64) (*votemodel.LinoStakeStat, error)
+	GetGlobalTime(ctx context.Context) (*globalmodel.GlobalTime, error)
+	GetAccountBank(ctx context.Context, username string) (*accmodel.AccountBank, error)
+	GetVoteParam(ctx context.Context) (*param.VoteParam, error)
+	GetBlockStatus(ctx context.Context) (*ctypes.ResultStatus, error)
+}
+
+// Unlock is an amount returned to the saving at a time.
+type Unlock struct {
+	At     time.Time      `json:"at"`
+	Amount linotypes.Coin `json:"amount"`
+}
+
+// Position is the stake of a user at the time of the last block.
+type Position struct {
+	Username string         `json:"username"`
+	At       time.Time      `json:"at"`
+	Staked   linotypes.Coin `json:"staked"`
+	// Frozen is the part of Staked locked by a validator or app duty.
+	Frozen linotypes.Coin `json:"frozen"`
+	// Interest is the interest ClaimInterest would pay now.
+	Interest linotypes.Coin `json:"interest"`
+	Saving   linotypes.Coin `json:"saving"`
+	// Unlocks are the pieces of staked-out (or revoked) coins not returned
+	// yet, earliest first, and PendingUnlock their sum.
+	Unlocks       []Unlock       `json:"unlocks"`
+	PendingUnlock linotypes.Coin `json:"pending_unlock"`
+}
+
+// Planner answers staking questions against the chain.
+type Planner struct {
+	source Source
+}
+
+// NewPlanner returns an instance of Planner reading from @p source.
+func NewPlanner(source Source) *Planner {
+	return &Planner{source: source}
+}
+
+// Position returns the stake, interest and unlocks of @p username. A user
+// that never staked has a zero stake.
+func (p *Planner) Position(ctx context.Context, username string) (*Position, error) {
+	globalTime, err := p.source.GetGlobalTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	position := &Position{
+		Username:      username,
+		At:            time.Unix(globalTime.LastBlockTime, 0),
+		Staked:        linotypes.NewCoinFromInt64(0),
+		Frozen:        linotypes.NewCoinFromInt64(0),
+		Interest:      linotypes.NewCoinFromInt64(0),
+		PendingUnlock: linotypes.NewCoinFromInt64(0),
+	}
+
+	voter, err := p.source.GetVoter(ctx, username)
+	if err != nil {
+		if !errors.IsEmptyResponse(err) {
+			return nil, err
+		}
+	} else {
+		position.Staked = voter.LinoStake
+		position.Frozen = voter.FrozenAmount
+		position.Interest, err = p.pendingInterest(ctx, voter, globalTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bank, err := p.source.GetAccountBank(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	position.Saving = bank.Saving
+	position.Unlocks = Unlocks(bank.FrozenMoneyList, globalTime.LastBlockTime)
+	for _, unlock := range position.Unlocks {
+		position.PendingUnlock = position.PendingUnlock.Plus(unlock.Amount)
+	}
+	return position, nil
+}
+
+// PendingInterest returns the interest ClaimInterest would pay @p username now.
+func (p *Planner) PendingInterest(ctx context.Context, username string) (linotypes.Coin, error) {
+	voter, err := p.source.GetVoter(ctx, username)
+	if err != nil {
+		return linotypes.NewCoinFromInt64(0), err
+	}
+	globalTime, err := p.source.GetGlobalTime(ctx)
+	if err != nil {
+		return linotypes.NewCoinFromInt64(0), err
+	}
+	return p.pendingInterest(ctx, voter, globalTime)
+}
+
+// StakeOutReturns returns when @p amount staked out now would be returned.
+func (p *Planner) StakeOutReturns(ctx context.Context, amount linotypes.Coin) ([]Unlock, error) {
+	voteParam, err := p.source.GetVoteParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := p.source.GetBlockStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return StakeOutReturns(amount, status.SyncInfo.LatestBlockTime, status.SyncInfo.LatestBlockHeight, voteParam), nil
+}
+
+// InterestRate returns the yearly interest rate of stake over the last
+// @p days complete days, assuming interest is claimed daily. Days without
+// stake are skipped.
+func (p *Planner) InterestRate(ctx context.Context, days int64) (sdk.Dec, error) {
+	if days <= 0 {
+		return sdk.ZeroDec(), errors.InvalidArgf("invalid number of days %v", days)
+	}
+	globalTime, err := p.source.GetGlobalTime(ctx)
+	if err != nil {
+		return sdk.ZeroDec(), err
+	}
+	end := pastDay(globalTime.LastBlockTime, globalTime.ChainStartTime)
+	start := end - days
+	if start < 0 {
+		start = 0
+	}
+	stats, err := p.stakeStats(ctx, start, end)
+	if err != nil {
+		return sdk.ZeroDec(), err
+	}
+
+	total := sdk.ZeroDec()
+	counted := int64(0)
+	for _, stat := range stats {
+		if !stat.TotalLinoStake.IsPositive() {
+			continue
+		}
+		total = total.Add(stat.TotalConsumptionFriction.ToDec().Quo(stat.TotalLinoStake.ToDec()))
+		counted++
+	}
+	if counted == 0 {
+		return sdk.ZeroDec(), nil
+	}
+	return total.MulInt64(daysPerYear).QuoInt64(counted), nil
+}
+
+// pendingInterest computes the interest of @p voter the way the chain does
+// on ClaimInterest: the settled interest plus its share of the unclaimed
+// friction of every day since its last stake change.
+func (p *Planner) pendingInterest(
+	ctx context.Context, voter *votemodel.Voter, globalTime *globalmodel.GlobalTime) (linotypes.Coin, error) {
+	start := pastDay(voter.LastPowerChangeAt, globalTime.ChainStartTime)
+	end := pastDay(globalTime.LastBlockTime, globalTime.ChainStartTime)
+	stats, err := p.stakeStats(ctx, start, end)
+	if err != nil {
+		return linotypes.NewCoinFromInt64(0), err
+	}
+	return Interest(voter, stats), nil
+}
+
+// stakeStats returns the stake stats of days [@p start, @p end).
+func (p *Planner) stakeStats(ctx context.Context, start, end int64) ([]*votemodel.LinoStakeStat, error) {
+	if end <= start {
+		return nil, nil
+	}
+	stats := make([]*votemodel.LinoStakeStat, end-start)
+	errs := make([]error, end-start)
+	sem := make(chan struct{}, maxConcurrentDays)
+	var wg sync.WaitGroup
+	for day := start; day < end; day++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(day int64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			stats[day-start], errs[day-start] = p.source.GetStakeStats(ctx, day)
+		}(day)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// Interest returns the interest of @p voter given the stake stats of every
+// day since its last stake change.
+func Interest(voter *votemodel.Voter, stats []*votemodel.LinoStakeStat) linotypes.Coin {
+	interest := voter.Interest
+	for _, stat := range stats {
+		if stat.UnclaimedLinoStake.IsZero() || !stat.UnclaimedLinoStake.IsGTE(voter.LinoStake) {
+			continue
+		}
+		interest = interest.Plus(linotypes.DecToCoin(stat.UnclaimedFriction.ToDec().Mul(
+			voter.LinoStake.ToDec().Quo(stat.UnclaimedLinoStake.ToDec()))))
+	}
+	return interest
+}
+
+// Unlocks returns the pieces of @p frozenMoneyList returned after unix time
+// @p now, earliest first.
+func Unlocks(frozenMoneyList []accmodel.FrozenMoney, now int64) []Unlock {
+	unlocks := []Unlock{}
+	for _, frozenMoney := range frozenMoneyList {
+		for _, piece := range util.FrozenMoneyReturns(frozenMoney) {
+			if piece.At > now {
+				unlocks = append(unlocks, Unlock{At: time.Unix(piece.At, 0), Amount: piece.Amount})
+			}
+		}
+	}
+	sort.SliceStable(unlocks, func(i, j int) bool { return unlocks[i].At.Before(unlocks[j].At) })
+	return unlocks
+}
+
+// StakeOutReturns returns when @p amount staked out at @p now in a block at
+// @p height is returned. Outside of the upgrade5 window the chain ignores
+// the vote param and returns it in one piece after a day.
+func StakeOutReturns(amount linotypes.Coin, now time.Time, height int64, voteParam *param.VoteParam) []Unlock {
+	interval, times := voteParam.VoterCoinReturnIntervalSec, voteParam.VoterCoinReturnTimes
+	if height < linotypes.Upgrade5Update1 || height >= linotypes.Upgrade5Update2 {
+		interval, times = secondsPerDay+1, 1
+	}
+	return Unlocks([]accmodel.FrozenMoney{{
+		Amount:   amount,
+		StartAt:  now.Unix(),
+		Times:    times,
+		Interval: interval,
+	}}, now.Unix())
+}
+
+// pastDay returns the day of @p unixTime counted from @p chainStartTime.
+func pastDay(unixTime, chainStartTime int64) int64 {
+	day := (unixTime - chainStartTime) / secondsPerDay
+	if day < 0 {
+		return 0
+	}
+	return day
+}
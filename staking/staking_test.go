@@ -0,0 +1,240 @@
+package staking
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino/param"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	globalmodel "github.com/lino-network/lino/x/global/model"
+	votemodel "github.com/lino-network/lino/x/vote/model"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+const (
+	day = int64(secondsPerDay)
+	now = 10*day + 100
+)
+
+type fakeChain struct {
+	voters  map[string]*votemodel.Voter
+	stats   map[int64]*votemodel.LinoStakeStat
+	banks   map[string]*accmodel.AccountBank
+	param   *param.VoteParam
+	height  int64
+	claimed []string
+	staked  map[string]string
+}
+
+func (c *fakeChain) GetVoter(ctx context.Context, username string) (*votemodel.Voter, error) {
+	voter, ok := c.voters[username]
+	if !ok {
+		return nil, errors.EmptyResponse("voter is not found")
+	}
+	return voter, nil
+}
+
+func (c *fakeChain) GetStakeStats(ctx context.Context, day int64) (*votemodel.LinoStakeStat, error) {
+	stat, ok := c.stats[day]
+	if !ok {
+		return nil, errors.EmptyResponse("stake stat is not found")
+	}
+	return stat, nil
+}
+
+func (c *fakeChain) GetGlobalTime(ctx context.Context) (*globalmodel.GlobalTime, error) {
+	return &globalmodel.GlobalTime{ChainStartTime: 0, LastBlockTime: now}, nil
+}
+
+func (c *fakeChain) GetAccountBank(ctx context.Context, username string) (*accmodel.AccountBank, error) {
+	bank, ok := c.banks[username]
+	if !ok {
+		return nil, errors.EmptyResponse("account bank is not found")
+	}
+	return bank, nil
+}
+
+func (c *fakeChain) GetVoteParam(ctx context.Context) (*param.VoteParam, error) {
+	return c.param, nil
+}
+
+func (c *fakeChain) GetBlockStatus(ctx context.Context) (*ctypes.ResultStatus, error) {
+	return &ctypes.ResultStatus{SyncInfo: ctypes.SyncInfo{
+		LatestBlockHeight: c.height,
+		LatestBlockTime:   time.Unix(now, 0),
+	}}, nil
+}
+
+func (c *fakeChain) ClaimInterest(ctx context.Context, username, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	c.claimed = append(c.claimed, username)
+	return &model.BroadcastResponse{CommitHash: "claim"}, nil
+}
+
+func (c *fakeChain) StakeIn(ctx context.Context, username, deposit, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	c.staked[username] = deposit
+	return &model.BroadcastResponse{CommitHash: "stake"}, nil
+}
+
+func coin(n int64) linotypes.Coin {
+	return linotypes.NewCoinFromInt64(n)
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		voters: map[string]*votemodel.Voter{
+			"alice": {Username: "alice", LinoStake: coin(1000), FrozenAmount: coin(0), Interest: coin(50), LastPowerChangeAt: 7*day + 10},
+			"bob":   {Username: "bob", LinoStake: coin(1000), FrozenAmount: coin(0), Interest: coin(50), LastPowerChangeAt: 7*day + 10},
+		},
+		stats: map[int64]*votemodel.LinoStakeStat{
+			7: {UnclaimedFriction: coin(1000), UnclaimedLinoStake: coin(10000)},
+			8: {UnclaimedFriction: coin(500), UnclaimedLinoStake: coin(5000),
+				TotalConsumptionFriction: coin(500), TotalLinoStake: coin(50000)},
+			9: {UnclaimedFriction: coin(500), UnclaimedLinoStake: coin(500),
+				TotalConsumptionFriction: coin(500), TotalLinoStake: coin(0)},
+		},
+		banks: map[string]*accmodel.AccountBank{
+			"alice": {Saving: coin(5000), FrozenMoneyList: []accmodel.FrozenMoney{
+				{Amount: coin(100), StartAt: now - 15, Interval: 10, Times: 3},
+				{Amount: coin(10), StartAt: now, Interval: 1, Times: 1},
+			}},
+			"bob":   {Saving: coin(5000)},
+			"carol": {Saving: coin(1500)},
+		},
+		param:  &param.VoteParam{MinStakeIn: coin(1000), VoterCoinReturnIntervalSec: 3600, VoterCoinReturnTimes: 2},
+		height: linotypes.Upgrade5Update2,
+		staked: map[string]string{},
+	}
+}
+
+func TestPosition(t *testing.T) {
+	planner := NewPlanner(newFakeChain())
+	testCases := map[string]struct {
+		username       string
+		expectPosition *Position
+	}{
+		"voter with unlocks": {
+			username: "alice",
+			expectPosition: &Position{
+				Username: "alice", At: time.Unix(now, 0), Staked: coin(1000), Frozen: coin(0),
+				Interest: coin(250), Saving: coin(5000), PendingUnlock: coin(77),
+				Unlocks: []Unlock{
+					{At: time.Unix(now+1, 0), Amount: coin(10)},
+					{At: time.Unix(now+5, 0), Amount: coin(34)},
+					{At: time.Unix(now+15, 0), Amount: coin(33)},
+				},
+			},
+		},
+		"not a voter": {
+			username: "carol",
+			expectPosition: &Position{
+				Username: "carol", At: time.Unix(now, 0), Staked: coin(0), Frozen: coin(0),
+				Interest: coin(0), Saving: coin(1500), PendingUnlock: coin(0), Unlocks: []Unlock{},
+			},
+		},
+	}
+
+	for testName, tc := range testCases {
+		position, err := planner.Position(context.Background(), tc.username)
+		if err != nil {
+			t.Errorf("%s: failed to get position: %v", testName, err)
+			continue
+		}
+		if !reflect.DeepEqual(position, tc.expectPosition) {
+			t.Errorf("%s: diff position, got %+v, want %+v", testName, position, tc.expectPosition)
+		}
+	}
+}
+
+func TestStakeOutReturns(t *testing.T) {
+	chain := newFakeChain()
+	planner := NewPlanner(chain)
+	testCases := map[string]struct {
+		height        int64
+		expectUnlocks []Unlock
+	}{
+		"after upgrade5 window": {
+			height:        linotypes.Upgrade5Update2,
+			expectUnlocks: []Unlock{{At: time.Unix(now+day+1, 0), Amount: coin(1001)}},
+		},
+		"in upgrade5 window": {
+			height: linotypes.Upgrade5Update1,
+			expectUnlocks: []Unlock{
+				{At: time.Unix(now+3600, 0), Amount: coin(500)},
+				{At: time.Unix(now+7200, 0), Amount: coin(501)},
+			},
+		},
+	}
+
+	for testName, tc := range testCases {
+		chain.height = tc.height
+		unlocks, err := planner.StakeOutReturns(context.Background(), coin(1001))
+		if err != nil {
+			t.Errorf("%s: failed to get returns: %v", testName, err)
+			continue
+		}
+		if !reflect.DeepEqual(unlocks, tc.expectUnlocks) {
+			t.Errorf("%s: diff unlocks, got %+v, want %+v", testName, unlocks, tc.expectUnlocks)
+		}
+	}
+}
+
+func TestInterestRate(t *testing.T) {
+	rate, err := NewPlanner(newFakeChain()).InterestRate(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("failed to get interest rate: %v", err)
+	}
+	if !rate.Equal(sdk.MustNewDecFromStr("3.65")) {
+		t.Errorf("diff interest rate, got %v, want 3.65", rate)
+	}
+}
+
+func TestWorker(t *testing.T) {
+	chain := newFakeChain()
+	chain.param.MinStakeIn = coin(100)
+	// dave has interest but no bank, and erin less than MinInterest.
+	for _, username := range []string{"dave", "erin"} {
+		chain.voters[username] = &votemodel.Voter{Username: linotypes.AccountKey(username), LinoStake: coin(1000),
+			FrozenAmount: coin(0), Interest: coin(50), LastPowerChangeAt: 7*day + 10}
+	}
+	chain.banks["erin"] = &accmodel.AccountBank{Saving: coin(5000)}
+	failed := map[string]error{}
+	worker, err := NewWorker(chain, WorkerOptions{
+		Accounts: []Account{
+			{Username: "alice", PrivKeyHex: "key", MinInterest: coin(100), Restake: true, Reserve: coin(1000)},
+			{Username: "bob", PrivKeyHex: "key", Restake: true, Reserve: coin(4900)},
+			{Username: "carol", PrivKeyHex: "key", Restake: true},
+			{Username: "dave", PrivKeyHex: "key", Restake: true},
+			{Username: "erin", PrivKeyHex: "key", MinInterest: coin(1000), Restake: true},
+		},
+		OnError: func(username string, err error) { failed[username] = err },
+	})
+	if err != nil {
+		t.Fatalf("failed to create worker: %v", err)
+	}
+
+	actions := worker.Round(context.Background())
+	if len(actions) != 5 {
+		t.Errorf("diff number of actions, got %v, want 5", len(actions))
+	}
+	if !reflect.DeepEqual(chain.claimed, []string{"alice", "bob", "dave"}) {
+		t.Errorf("diff claimed, got %v, want [alice bob dave]", chain.claimed)
+	}
+	// only the claimed interest is staked, within the saving above Reserve.
+	expectStaked := map[string]string{"alice": "0.0025", "bob": "0.001"}
+	if !reflect.DeepEqual(chain.staked, expectStaked) {
+		t.Errorf("diff staked, got %v, want %v", chain.staked, expectStaked)
+	}
+	if _, ok := failed["dave"]; !ok || len(failed) != 1 {
+		t.Errorf("diff failed accounts, got %v, want dave", failed)
+	}
+
+	if _, err := NewWorker(chain, WorkerOptions{Accounts: []Account{{Username: "alice"}}}); err == nil {
+		t.Errorf("account without private key should fail")
+	}
+}
@@ -0,0 +1,170 @@
+package staking
+
+import (
+	"context"
+	"time"
+
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linotypes "github.com/lino-network/lino/types"
+)
+
+// Action kinds.
+const (
+	ActionClaimInterest = "claim_interest"
+	ActionStakeIn       = "stake_in"
+)
+
+// Chain queries stakes and broadcasts claims and stake-ins. *api.API
+// implements it.
+type Chain interface {
+	Source
+	ClaimInterest(ctx context.Context, username, privKeyHex string) (*model.BroadcastResponse, errors.Error)
+	StakeIn(ctx context.Context, username, deposit, privKeyHex string) (*model.BroadcastResponse, errors.Error)
+}
+
+// Account is an account the worker compounds interest for.
+type Account struct {
+	Username   string
+	PrivKeyHex string
+	// MinInterest is the pending interest below which interest is not
+	// claimed. Zero claims any positive interest.
+	MinInterest linotypes.Coin
+	// Restake stakes in the interest just claimed, if it is at least the
+	// min_stake_in vote param, leaving at least Reserve of the saving.
+	Restake bool
+	Reserve linotypes.Coin
+}
+
+// WorkerOptions configures a Worker.
+type WorkerOptions struct {
+	Accounts []Account
+	// Interval is the time between rounds of Run. Defaults to a week.
+	Interval time.Duration
+	// OnAction is called with every broadcast action.
+	OnAction func(action *Action)
+	// OnError is called with the error of every account failing in a round.
+	OnError func(username string, err error)
+}
+
+// Action is a claim or a stake-in broadcast for an account.
+type Action struct {
+	Username string                   `json:"username"`
+	Kind     string                   `json:"kind"`
+	Amount   linotypes.Coin           `json:"amount"`
+	Response *model.BroadcastResponse `json:"response"`
+}
+
+// Worker claims and restakes interest of its accounts periodically.
+type Worker struct {
+	chain   Chain
+	planner *Planner
+	opts    WorkerOptions
+}
+
+// NewWorker returns an instance of Worker compounding on @p chain.
+func NewWorker(chain Chain, opts WorkerOptions) (*Worker, error) {
+	if len(opts.Accounts) == 0 {
+		return nil, errors.InvalidArg("no account to compound")
+	}
+	for i, account := range opts.Accounts {
+		if account.Username == "" || account.PrivKeyHex == "" {
+			return nil, errors.InvalidArgf("account %v: username and private key are required", i)
+		}
+		if account.MinInterest == (linotypes.Coin{}) {
+			opts.Accounts[i].MinInterest = linotypes.NewCoinFromInt64(0)
+		}
+		if account.Reserve == (linotypes.Coin{}) {
+			opts.Accounts[i].Reserve = linotypes.NewCoinFromInt64(0)
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 7 * 24 * time.Hour
+	}
+	return &Worker{chain: chain, planner: NewPlanner(chain), opts: opts}, nil
+}
+
+// Run plays a round every Interval until the context is done.
+func (w *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		w.Round(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Round compounds every account once and returns the broadcast actions.
+// Failed accounts are reported to OnError and do not stop the round.
+func (w *Worker) Round(ctx context.Context) []*Action {
+	actions := []*Action{}
+	for _, account := range w.opts.Accounts {
+		done, err := w.Compound(ctx, account)
+		actions = append(actions, done...)
+		if err != nil && w.opts.OnError != nil {
+			w.opts.OnError(account.Username, err)
+		}
+	}
+	return actions
+}
+
+// Compound claims the interest of @p account if it reached MinInterest and
+// restakes the claimed interest if Restake is set.
+func (w *Worker) Compound(ctx context.Context, account Account) ([]*Action, error) {
+	actions := []*Action{}
+	claimed := linotypes.NewCoinFromInt64(0)
+	interest, err := w.planner.PendingInterest(ctx, account.Username)
+	if err != nil {
+		if !errors.IsEmptyResponse(err) {
+			return actions, err
+		}
+		interest = linotypes.NewCoinFromInt64(0)
+	}
+	if interest.IsPositive() && interest.IsGTE(account.MinInterest) {
+		resp, err := w.chain.ClaimInterest(ctx, account.Username, account.PrivKeyHex)
+		if err != nil {
+			return actions, err
+		}
+		claimed = interest
+		actions = append(actions, w.done(&Action{
+			Username: account.Username, Kind: ActionClaimInterest, Amount: interest, Response: resp}))
+	}
+	if !account.Restake || !claimed.IsPositive() {
+		return actions, nil
+	}
+
+	bank, err := w.chain.GetAccountBank(ctx, account.Username)
+	if err != nil {
+		return actions, err
+	}
+	voteParam, err := w.chain.GetVoteParam(ctx)
+	if err != nil {
+		return actions, err
+	}
+	deposit := bank.Saving.Minus(account.Reserve)
+	if deposit.IsGT(claimed) {
+		deposit = claimed
+	}
+	if !deposit.IsPositive() || voteParam.MinStakeIn.IsGT(deposit) {
+		return actions, nil
+	}
+	resp, err := w.chain.StakeIn(ctx, account.Username, amount.NewFromCoin(deposit).String(), account.PrivKeyHex)
+	if err != nil {
+		return actions, err
+	}
+	actions = append(actions, w.done(&Action{
+		Username: account.Username, Kind: ActionStakeIn, Amount: deposit, Response: resp}))
+	return actions, nil
+}
+
+func (w *Worker) done(action *Action) *Action {
+	if w.opts.OnAction != nil {
+		w.opts.OnAction(action)
+	}
+	return action
+}
@@ -0,0 +1,161 @@
+package tipping
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/transport"
+	posttypes "github.com/lino-network/lino/x/post/types"
+)
+
+// Receipt is the proof of a donation, signed by the tipping service. The
+// signature covers all other fields.
+type Receipt struct {
+	ID     string `json:"id"`
+	Payer  string `json:"payer"`
+	Author string `json:"author"`
+	PostID string `json:"post_id"`
+	App    string `json:"app"`
+	// Amount is a plain decimal of LINO, or of IDA of App if IsIDA.
+	Amount string `json:"amount"`
+	IsIDA  bool   `json:"is_ida"`
+	Memo   string `json:"memo"`
+	TxHash string `json:"tx_hash"`
+	Height int64  `json:"height"`
+	// IssuedAt is when the service confirmed the donation.
+	IssuedAt time.Time `json:"issued_at"`
+	// PubKey is the hex public key of the service and Signature the hex
+	// signature of the receipt by it.
+	PubKey    string `json:"pub_key"`
+	Signature string `json:"signature"`
+}
+
+// signBytes returns the bytes the signature of the receipt covers.
+func (r *Receipt) signBytes() []byte {
+	unsigned := *r
+	unsigned.Signature = ""
+	b, _ := json.Marshal(unsigned)
+	return b
+}
+
+// Sign signs the receipt with @p privKeyHex and sets PubKey and Signature.
+func (r *Receipt) Sign(privKeyHex string) error {
+	privKey, err := transport.GetPrivKeyFromHex(privKeyHex)
+	if err != nil {
+		return errors.FailedToGetPrivKeyFromHex("invalid receipt key").AddCause(err)
+	}
+	r.PubKey = hex.EncodeToString(privKey.PubKey().Bytes())
+	sig, err := privKey.Sign(r.signBytes())
+	if err != nil {
+		return errors.InvalidSignature("failed to sign receipt").AddCause(err)
+	}
+	r.Signature = hex.EncodeToString(sig)
+	return nil
+}
+
+// VerifySignature checks that the receipt is signed by @p pubKeyHex.
+func (r *Receipt) VerifySignature(pubKeyHex string) error {
+	if r.PubKey != pubKeyHex {
+		return errors.InvalidSignature("receipt is not signed by the expected key")
+	}
+	pubKey, err := transport.GetPubKeyFromHex(pubKeyHex)
+	if err != nil {
+		return errors.FailedToGetPubKeyFromHex("invalid receipt public key").AddCause(err)
+	}
+	sig, err := hex.DecodeString(r.Signature)
+	if err != nil || !pubKey.VerifyBytes(r.signBytes(), sig) {
+		return errors.InvalidSignature("invalid receipt signature")
+	}
+	return nil
+}
+
+// TxSource provides committed transactions. *query.Query implements it.
+type TxSource interface {
+	GetTx(ctx context.Context, hash []byte) (*model.BlockTx, errors.Error)
+}
+
+// Verify checks that @p receipt is signed by @p pubKeyHex and that its
+// transaction succeeded at its height with a donation matching it.
+func Verify(ctx context.Context, source TxSource, receipt *Receipt, pubKeyHex string) error {
+	if err := receipt.VerifySignature(pubKeyHex); err != nil {
+		return err
+	}
+	hash, err := hex.DecodeString(receipt.TxHash)
+	if err != nil {
+		return errors.InvalidArgf("invalid tx hash %q", receipt.TxHash)
+	}
+	tx, linoErr := source.GetTx(ctx, hash)
+	if linoErr != nil {
+		return linoErr
+	}
+	if tx.Code != 0 {
+		return errors.InvalidArgf("tx %s failed with code %v", receipt.TxHash, tx.Code)
+	}
+	if tx.Height != receipt.Height {
+		return errors.InvalidArgf("tx %s is at height %v, not %v", receipt.TxHash, tx.Height, receipt.Height)
+	}
+	for _, msg := range tx.Tx.Msgs {
+		if matches(receipt, msg) {
+			return nil
+		}
+	}
+	return errors.InvalidArgf("tx %s has no donation matching the receipt", receipt.TxHash)
+}
+
+// matches returns true if @p msg is the donation of @p receipt.
+func matches(receipt *Receipt, msg interface{}) bool {
+	var payer, author, postID, app, memo string
+	var amt amount.Amount
+	var err error
+	switch msg := msg.(type) {
+	case posttypes.DonateMsg:
+		if receipt.IsIDA {
+			return false
+		}
+		payer, author, postID, app, memo = string(msg.Username), string(msg.Author), msg.PostID, string(msg.FromApp), msg.Memo
+		amt, err = amount.ParseLNO(msg.Amount)
+	case posttypes.IDADonateMsg:
+		if !receipt.IsIDA {
+			return false
+		}
+		payer, author, postID, app, memo = string(msg.Username), string(msg.Author), msg.PostID, string(msg.App), msg.Memo
+		amt, err = amount.ParseIDA(msg.Amount)
+	default:
+		return false
+	}
+	if err != nil {
+		return false
+	}
+	unit := amount.LINO
+	if receipt.IsIDA {
+		unit = amount.IDA
+	}
+	expect, err := amount.Parse(receipt.Amount, unit)
+	if err != nil {
+		return false
+	}
+	return payer == receipt.Payer && author == receipt.Author && postID == receipt.PostID &&
+		app == receipt.App && memo == receipt.Memo && amt.Equal(expect)
+}
+
+// receiptOf returns the unsigned receipt of @p intent donated in @p resp.
+func receiptOf(intent *Intent, resp *model.BroadcastResponse, issuedAt time.Time) *Receipt {
+	return &Receipt{
+		ID:       intent.ID,
+		Payer:    intent.Payer,
+		Author:   intent.Author,
+		PostID:   intent.PostID,
+		App:      intent.App,
+		Amount:   intent.Amount.String(),
+		IsIDA:    intent.Amount.Unit() == amount.IDA,
+		Memo:     intent.Memo,
+		TxHash:   resp.CommitHash,
+		Height:   resp.Height,
+		IssuedAt: issuedAt.UTC().Truncate(time.Second),
+	}
+}
@@ -0,0 +1,238 @@
+// Package tipping donates to posts on behalf of users and issues signed
+// receipts of the donations, which creators can verify against the chain
+// later, e.g. before payouts.
+//
+// LINO is donated with DonateMsg, signed by the payer. IDA of an app is
+// donated with IDADonateMsg, signed by the app or one of its affiliated
+// accounts.
+package tipping
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/api"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/transport"
+	"github.com/lino-network/lino-go/util"
+	linotypes "github.com/lino-network/lino/types"
+	postmodel "github.com/lino-network/lino/x/post/model"
+)
+
+// Chain reads posts and transactions and broadcasts donations. *api.API
+// implements it.
+type Chain interface {
+	TxSource
+	GetPostInfo(ctx context.Context, author, postID string) (*postmodel.Post, error)
+	DonateAmount(ctx context.Context, username, author string, amt amount.Amount,
+		postID, fromApp, memo string, privKeyHex string) (*model.BroadcastResponse, errors.Error)
+	IDADonateAmount(ctx context.Context, username, author, app string, amt amount.Amount,
+		postID, signer, memo string, privKeyHex string) (*model.BroadcastResponse, errors.Error)
+}
+
+// Intent is a donation to make.
+type Intent struct {
+	// ID identifies the intent to the caller and is copied to the receipt.
+	ID     string
+	Payer  string
+	Author string
+	PostID string
+	// Amount is LINO or IDA of App.
+	Amount amount.Amount
+	// App is the app a LINO donation is made from, optional, or the app of
+	// the IDA donated.
+	App string
+	// Signer signs IDA donations. Empty is App.
+	Signer string
+	Memo   string
+}
+
+// signer returns the account signing the donation of the intent.
+func (intent *Intent) signer() string {
+	if intent.Amount.Unit() != amount.IDA {
+		return intent.Payer
+	}
+	if intent.Signer == "" {
+		return intent.App
+	}
+	return intent.Signer
+}
+
+// Validate checks the intent as the chain does, except for balances and the
+// existence of the post.
+func (intent *Intent) Validate() error {
+	if !util.CheckUsername(intent.Payer) || !util.CheckUsername(intent.Author) {
+		return errors.InvalidArgf("invalid payer %q or author %q", intent.Payer, intent.Author)
+	}
+	if intent.Payer == intent.Author {
+		return errors.InvalidArgf("%s cannot donate to itself", intent.Payer)
+	}
+	if intent.PostID == "" {
+		return errors.InvalidArg("empty post id")
+	}
+	if intent.Amount == (amount.Amount{}) || !intent.Amount.IsPositive() {
+		return errors.InvalidArg("amount must be positive")
+	}
+	switch intent.Amount.Unit() {
+	case amount.LINO:
+		if intent.App != "" && !util.CheckUsername(intent.App) {
+			return errors.InvalidArgf("invalid app %q", intent.App)
+		}
+	case amount.IDA:
+		if !util.CheckUsername(intent.App) || !util.CheckUsername(intent.signer()) {
+			return errors.InvalidArgf("invalid app %q or signer %q of ida donation", intent.App, intent.Signer)
+		}
+	default:
+		return errors.InvalidArgf("cannot donate %s", intent.Amount.Unit().Name)
+	}
+	if utf8.RuneCountInString(intent.Memo) > linotypes.MaximumMemoLength {
+		return errors.InvalidArgf("memo must be at most %v characters", linotypes.MaximumMemoLength)
+	}
+	return nil
+}
+
+// Options configures a Tipper.
+type Options struct {
+	// Keys returns the private key of a signer.
+	Keys func(signer string) (privKeyHex string, err error)
+	// ReceiptKeyHex is the private key receipts are signed with.
+	ReceiptKeyHex string
+	// Admission, if set, is consulted before every donation, so that
+	// donations are paced under the bandwidth of the app. Leave it nil if
+	// the same admission control is set on the api.API already.
+	Admission api.AdmissionControl
+	// Concurrency is the number of signers donating at once in TipAll.
+	// Defaults to 4.
+	Concurrency int
+}
+
+// Result is the outcome of an intent in TipAll.
+type Result struct {
+	Intent  Intent   `json:"intent"`
+	Receipt *Receipt `json:"receipt"`
+	Err     error    `json:"-"`
+}
+
+// Tipper donates intents and issues receipts.
+type Tipper struct {
+	chain  Chain
+	opts   Options
+	pubKey string
+	now    func() time.Time
+}
+
+// NewTipper returns an instance of Tipper donating on @p chain.
+func NewTipper(chain Chain, opts Options) (*Tipper, error) {
+	if opts.Keys == nil {
+		return nil, errors.InvalidArg("no keys")
+	}
+	privKey, err := transport.GetPrivKeyFromHex(opts.ReceiptKeyHex)
+	if err != nil {
+		return nil, errors.FailedToGetPrivKeyFromHex("invalid receipt key").AddCause(err)
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	return &Tipper{
+		chain:  chain,
+		opts:   opts,
+		pubKey: hex.EncodeToString(privKey.PubKey().Bytes()),
+		now:    time.Now,
+	}, nil
+}
+
+// PubKey returns the hex public key receipts are signed with.
+func (t *Tipper) PubKey() string {
+	return t.pubKey
+}
+
+// Tip checks that the post of @p intent exists, donates and returns the
+// signed receipt of the donation.
+func (t *Tipper) Tip(ctx context.Context, intent Intent) (*Receipt, error) {
+	if err := intent.Validate(); err != nil {
+		return nil, err
+	}
+	post, err := t.chain.GetPostInfo(ctx, intent.Author, intent.PostID)
+	if err != nil {
+		return nil, err
+	}
+	if post.IsDeleted {
+		return nil, errors.InvalidArgf("post %s#%s is deleted", intent.Author, intent.PostID)
+	}
+	signer := intent.signer()
+	privKeyHex, err := t.opts.Keys(signer)
+	if err != nil {
+		return nil, err
+	}
+	if t.opts.Admission != nil {
+		if err := t.opts.Admission.Admit(ctx, util.GetSignerList(signer)); err != nil {
+			return nil, err
+		}
+	}
+
+	var resp *model.BroadcastResponse
+	var linoErr errors.Error
+	if intent.Amount.Unit() == amount.IDA {
+		resp, linoErr = t.chain.IDADonateAmount(ctx, intent.Payer, intent.Author, intent.App, intent.Amount,
+			intent.PostID, signer, intent.Memo, privKeyHex)
+	} else {
+		resp, linoErr = t.chain.DonateAmount(ctx, intent.Payer, intent.Author, intent.Amount,
+			intent.PostID, intent.App, intent.Memo, privKeyHex)
+	}
+	if linoErr != nil {
+		return nil, linoErr
+	}
+
+	receipt := receiptOf(&intent, resp, t.now())
+	if err := receipt.Sign(t.opts.ReceiptKeyHex); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// TipAll tips @p intents and returns their results in the same order.
+// Intents of a signer are donated one by one in order, as they share its
+// sequence number; different signers donate concurrently.
+func (t *Tipper) TipAll(ctx context.Context, intents []Intent) []*Result {
+	results := make([]*Result, len(intents))
+	bySigner := map[string][]int{}
+	signers := []string{}
+	for i, intent := range intents {
+		results[i] = &Result{Intent: intent}
+		signer := ""
+		if intent.Amount != (amount.Amount{}) {
+			signer = intent.signer()
+		}
+		if _, ok := bySigner[signer]; !ok {
+			signers = append(signers, signer)
+		}
+		bySigner[signer] = append(bySigner[signer], i)
+	}
+
+	sem := make(chan struct{}, t.opts.Concurrency)
+	var wg sync.WaitGroup
+	for _, signer := range signers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(indexes []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			for _, i := range indexes {
+				results[i].Receipt, results[i].Err = t.Tip(ctx, intents[i])
+			}
+		}(bySigner[signer])
+	}
+	wg.Wait()
+	return results
+}
+
+// Verify checks that @p receipt was issued by the tipper and matches a
+// successful donation on chain.
+func (t *Tipper) Verify(ctx context.Context, receipt *Receipt) error {
+	return Verify(ctx, t.chain, receipt, t.pubKey)
+}
@@ -0,0 +1,278 @@
+package tipping
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linotypes "github.com/lino-network/lino/types"
+	postmodel "github.com/lino-network/lino/x/post/model"
+	posttypes "github.com/lino-network/lino/x/post/types"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+)
+
+type fakeChain struct {
+	mu    sync.Mutex
+	posts map[string]*postmodel.Post
+	txs   map[string]*model.BlockTx
+	keys  []string
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		posts: map[string]*postmodel.Post{
+			"bob#p1":     {Author: "bob", PostID: "p1"},
+			"bob#gone":   {Author: "bob", PostID: "gone", IsDeleted: true},
+			"carol#note": {Author: "carol", PostID: "note"},
+		},
+		txs: map[string]*model.BlockTx{},
+	}
+}
+
+func (c *fakeChain) GetTx(ctx context.Context, hash []byte) (*model.BlockTx, errors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tx, ok := c.txs[strings.ToUpper(hex.EncodeToString(hash))]
+	if !ok {
+		return nil, errors.QueryTxNotFound()
+	}
+	return tx, nil
+}
+
+func (c *fakeChain) GetPostInfo(ctx context.Context, author, postID string) (*postmodel.Post, error) {
+	post, ok := c.posts[author+"#"+postID]
+	if !ok {
+		return nil, errors.EmptyResponse("post is not found")
+	}
+	return post, nil
+}
+
+func (c *fakeChain) commit(msg sdk.Msg, privKeyHex string) *model.BroadcastResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = append(c.keys, privKeyHex)
+	hash := fmt.Sprintf("%064X", len(c.txs)+1)
+	c.txs[hash] = &model.BlockTx{Height: int64(100 + len(c.txs)), Hash: hash, Tx: auth.StdTx{Msgs: []sdk.Msg{msg}}}
+	return &model.BroadcastResponse{CommitHash: hash, Height: c.txs[hash].Height}
+}
+
+func (c *fakeChain) DonateAmount(ctx context.Context, username, author string, amt amount.Amount,
+	postID, fromApp, memo string, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	return c.commit(posttypes.DonateMsg{
+		Username: linotypes.AccountKey(username),
+		Amount:   linotypes.LNO(amt.String()),
+		Author:   linotypes.AccountKey(author),
+		PostID:   postID,
+		FromApp:  linotypes.AccountKey(fromApp),
+		Memo:     memo,
+	}, privKeyHex), nil
+}
+
+func (c *fakeChain) IDADonateAmount(ctx context.Context, username, author, app string, amt amount.Amount,
+	postID, signer, memo string, privKeyHex string) (*model.BroadcastResponse, errors.Error) {
+	return c.commit(posttypes.IDADonateMsg{
+		Username: linotypes.AccountKey(username),
+		App:      linotypes.AccountKey(app),
+		Amount:   linotypes.IDAStr(amt.String()),
+		Author:   linotypes.AccountKey(author),
+		PostID:   postID,
+		Memo:     memo,
+		Signer:   linotypes.AccountKey(signer),
+	}, privKeyHex), nil
+}
+
+func newTipper(t *testing.T, chain Chain) *Tipper {
+	tipper, err := NewTipper(chain, Options{
+		Keys:          func(signer string) (string, error) { return "key-of-" + signer, nil },
+		ReceiptKeyHex: hex.EncodeToString(secp256k1.GenPrivKey().Bytes()),
+	})
+	if err != nil {
+		t.Fatalf("failed to create tipper, got err %v", err)
+	}
+	tipper.now = func() time.Time { return time.Unix(1500000000, 0) }
+	return tipper
+}
+
+func TestTip(t *testing.T) {
+	testCases := map[string]struct {
+		intent       Intent
+		expectKey    string
+		expectIsIDA  bool
+		expectAmount string
+		expectErr    bool
+	}{
+		"lino": {
+			intent:       Intent{ID: "1", Payer: "alice", Author: "bob", PostID: "p1", Amount: amount.MustParse("1.50", amount.LINO), App: "app", Memo: "thanks"},
+			expectKey:    "key-of-alice",
+			expectAmount: "1.5",
+		},
+		"ida signed by app": {
+			intent:       Intent{ID: "2", Payer: "alice", Author: "bob", PostID: "p1", Amount: amount.MustParse("20", amount.IDA), App: "app"},
+			expectKey:    "key-of-app",
+			expectIsIDA:  true,
+			expectAmount: "20",
+		},
+		"ida signed by affiliated": {
+			intent:       Intent{ID: "3", Payer: "alice", Author: "bob", PostID: "p1", Amount: amount.MustParse("2", amount.IDA), App: "app", Signer: "app-op"},
+			expectKey:    "key-of-app-op",
+			expectIsIDA:  true,
+			expectAmount: "2",
+		},
+		"post not found": {
+			intent:    Intent{Payer: "alice", Author: "bob", PostID: "p2", Amount: amount.MustParse("1", amount.LINO)},
+			expectErr: true,
+		},
+		"post deleted": {
+			intent:    Intent{Payer: "alice", Author: "bob", PostID: "gone", Amount: amount.MustParse("1", amount.LINO)},
+			expectErr: true,
+		},
+		"self donation": {
+			intent:    Intent{Payer: "bob", Author: "bob", PostID: "p1", Amount: amount.MustParse("1", amount.LINO)},
+			expectErr: true,
+		},
+		"ida without app": {
+			intent:    Intent{Payer: "alice", Author: "bob", PostID: "p1", Amount: amount.MustParse("1", amount.IDA)},
+			expectErr: true,
+		},
+		"usd": {
+			intent:    Intent{Payer: "alice", Author: "bob", PostID: "p1", Amount: amount.MustParse("1", amount.USD)},
+			expectErr: true,
+		},
+		"zero": {
+			intent:    Intent{Payer: "alice", Author: "bob", PostID: "p1", Amount: amount.Zero(amount.LINO)},
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		chain := newFakeChain()
+		tipper := newTipper(t, chain)
+		receipt, err := tipper.Tip(context.Background(), tc.intent)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			if len(chain.txs) != 0 {
+				t.Errorf("%s: donated despite err", testName)
+			}
+			continue
+		}
+		if chain.keys[0] != tc.expectKey {
+			t.Errorf("%s: diff key, got %v, want %v", testName, chain.keys[0], tc.expectKey)
+		}
+		if receipt.ID != tc.intent.ID || receipt.IsIDA != tc.expectIsIDA || receipt.Amount != tc.expectAmount {
+			t.Errorf("%s: diff receipt, got %+v", testName, receipt)
+		}
+		if err := tipper.Verify(context.Background(), receipt); err != nil {
+			t.Errorf("%s: failed to verify, got err %v", testName, err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	chain := newFakeChain()
+	tipper := newTipper(t, chain)
+	receipt, err := tipper.Tip(context.Background(), Intent{
+		ID: "1", Payer: "alice", Author: "bob", PostID: "p1", Amount: amount.MustParse("3", amount.LINO)})
+	if err != nil {
+		t.Fatalf("failed to tip, got err %v", err)
+	}
+	b, _ := json.Marshal(receipt)
+	stored := new(Receipt)
+	if err := json.Unmarshal(b, stored); err != nil {
+		t.Fatalf("failed to unmarshal receipt, got err %v", err)
+	}
+
+	testCases := map[string]struct {
+		tamper    func(r *Receipt)
+		pubKey    string
+		expectErr bool
+	}{
+		"stored receipt": {
+			tamper: func(r *Receipt) {},
+		},
+		"other issuer": {
+			tamper:    func(r *Receipt) {},
+			pubKey:    hex.EncodeToString(secp256k1.GenPrivKey().PubKey().Bytes()),
+			expectErr: true,
+		},
+		"amount changed": {
+			tamper:    func(r *Receipt) { r.Amount = "30" },
+			expectErr: true,
+		},
+		"resigned with other amount": {
+			tamper: func(r *Receipt) {
+				r.Amount = "30"
+				r.Sign(tipper.opts.ReceiptKeyHex)
+			},
+			expectErr: true,
+		},
+		"resigned with other height": {
+			tamper: func(r *Receipt) {
+				r.Height++
+				r.Sign(tipper.opts.ReceiptKeyHex)
+			},
+			expectErr: true,
+		},
+		"unknown tx": {
+			tamper: func(r *Receipt) {
+				r.TxHash = fmt.Sprintf("%064X", 99)
+				r.Sign(tipper.opts.ReceiptKeyHex)
+			},
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		r := *stored
+		tc.tamper(&r)
+		pubKey := tc.pubKey
+		if pubKey == "" {
+			pubKey = tipper.PubKey()
+		}
+		err := Verify(context.Background(), chain, &r, pubKey)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+		}
+	}
+}
+
+func TestTipAll(t *testing.T) {
+	chain := newFakeChain()
+	tipper := newTipper(t, chain)
+	intents := []Intent{
+		{ID: "1", Payer: "alice", Author: "bob", PostID: "p1", Amount: amount.MustParse("1", amount.LINO)},
+		{ID: "2", Payer: "dave", Author: "carol", PostID: "note", Amount: amount.MustParse("5", amount.IDA), App: "app"},
+		{ID: "3", Payer: "alice", Author: "bob", PostID: "missing", Amount: amount.MustParse("1", amount.LINO)},
+		{ID: "4", Payer: "alice", Author: "carol", PostID: "note", Amount: amount.MustParse("2", amount.LINO)},
+	}
+	results := tipper.TipAll(context.Background(), intents)
+	if len(results) != len(intents) {
+		t.Fatalf("diff results, got %v, want %v", len(results), len(intents))
+	}
+	for i, result := range results {
+		if result.Intent.ID != intents[i].ID {
+			t.Errorf("result %v: diff order, got %v", i, result.Intent.ID)
+		}
+		if expectErr := intents[i].ID == "3"; (result.Err != nil) != expectErr {
+			t.Errorf("result %v: diff err, got %v, expect err %v", i, result.Err, expectErr)
+		}
+		if result.Err == nil && result.Receipt.ID != intents[i].ID {
+			t.Errorf("result %v: diff receipt, got %+v", i, result.Receipt)
+		}
+	}
+	if len(chain.txs) != 3 {
+		t.Errorf("diff donations, got %v, want 3", len(chain.txs))
+	}
+}
@@ -100,12 +100,14 @@ func (t Transport) QueryAtHeight(ctx context.Context, key cmn.HexBytes, storeNam
 	return res, err
 }
 
-// Query from Tendermint with the provided subspace and storename
+// QuerySubspace returns all KV pairs under @p subspace of a store. The node
+// answers with every pair at once, so keep the subspace narrow or use
+// query.SubspaceIterator to split it.
 func (t Transport) QuerySubspace(ctx context.Context, subspace []byte, storeName string) (res []sdk.KVPair, err error) {
 	var resRaw []byte
 	finishChan := make(chan bool)
 	go func() {
-		resRaw, err = t.query([]string{string(subspace)}, storeName, "subspace", 0)
+		resRaw, err = t.queryByKey(subspace, storeName, "subspace", 0)
 		finishChan <- true
 	}()
 
@@ -116,15 +118,22 @@ func (t Transport) QuerySubspace(ctx context.Context, subspace []byte, storeName
 		return nil, errors.Timeout("query subspace timeout").AddCause(ctx.Err())
 	}
 
+	// an empty subspace is answered with an empty value
+	res = []sdk.KVPair{}
+	if errors.IsEmptyResponse(err) {
+		return res, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-
-	t.Cdc.UnmarshalJSON(resRaw, &res)
-	return
+	if err := t.Cdc.UnmarshalBinaryLengthPrefixed(resRaw, &res); err != nil {
+		return nil, errors.UnmarshaFailed("failed to decode subspace").AddCause(err)
+	}
+	return res, nil
 }
+
 func (t Transport) queryByKey(key cmn.HexBytes, storeName, substore string, height int64) (res []byte, err error) {
-	path := fmt.Sprintf("/store/%s/key", storeName)
+	path := fmt.Sprintf("/store/%s/%s", storeName, substore)
 	node, err := t.GetNode()
 	if err != nil {
 		return res, err
@@ -205,6 +214,29 @@ func (t Transport) QueryBlock(ctx context.Context, height int64) (res *ctypes.Re
 	return res, err
 }
 
+// QueryBlockResults queries the ABCI results of a block with a certain height from blockchain.
+func (t Transport) QueryBlockResults(ctx context.Context, height int64) (res *ctypes.ResultBlockResults, err error) {
+	node, err := t.GetNode()
+	if err != nil {
+		return res, err
+	}
+
+	finishChan := make(chan bool)
+	go func() {
+		res, err = node.BlockResults(&height)
+		finishChan <- true
+	}()
+
+	select {
+	case <-finishChan:
+		break
+	case <-ctx.Done():
+		return nil, errors.Timeout("query block results timeout").AddCause(ctx.Err())
+	}
+
+	return res, err
+}
+
 // QueryBlockStatus queries block status from blockchain.
 func (t Transport) QueryBlockStatus(ctx context.Context) (res *ctypes.ResultStatus, err error) {
 	node, err := t.GetNode()
@@ -251,6 +283,168 @@ func (t Transport) QueryTx(ctx context.Context, hash []byte) (res *ctypes.Result
 	return res, err
 }
 
+// QueryTxSearch searches txs matching a tendermint tx_search query, returning
+// one page of results.
+func (t Transport) QueryTxSearch(ctx context.Context, query string, page, perPage int) (res *ctypes.ResultTxSearch, err error) {
+	node, err := t.GetNode()
+	if err != nil {
+		return res, err
+	}
+
+	finishChan := make(chan bool)
+	go func() {
+		res, err = node.TxSearch(query, false, page, perPage)
+		finishChan <- true
+	}()
+
+	select {
+	case <-finishChan:
+		break
+	case <-ctx.Done():
+		return nil, errors.Timeout("query tx search timeout").AddCause(ctx.Err())
+	}
+
+	return res, err
+}
+
+// QueryValidators queries the tendermint validator set at a certain height, or the latest one if @p height is nil.
+func (t Transport) QueryValidators(ctx context.Context, height *int64) (*ctypes.ResultValidators, error) {
+	var res *ctypes.ResultValidators
+	err := t.queryNode(ctx, "validators", func(node rpcclient.Client) (err error) {
+		res, err = node.Validators(height)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryCommit queries the commit of a block at a certain height, or the latest one if @p height is nil.
+func (t Transport) QueryCommit(ctx context.Context, height *int64) (*ctypes.ResultCommit, error) {
+	var res *ctypes.ResultCommit
+	err := t.queryNode(ctx, "commit", func(node rpcclient.Client) (err error) {
+		res, err = node.Commit(height)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryNetInfo queries the network info of the node.
+func (t Transport) QueryNetInfo(ctx context.Context) (*ctypes.ResultNetInfo, error) {
+	var res *ctypes.ResultNetInfo
+	err := t.queryNode(ctx, "net info", func(node rpcclient.Client) (err error) {
+		res, err = node.NetInfo()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryGenesis queries the genesis document of the chain.
+func (t Transport) QueryGenesis(ctx context.Context) (*ctypes.ResultGenesis, error) {
+	var res *ctypes.ResultGenesis
+	err := t.queryNode(ctx, "genesis", func(node rpcclient.Client) (err error) {
+		res, err = node.Genesis()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryConsensusState queries the consensus round state of the node.
+func (t Transport) QueryConsensusState(ctx context.Context) (*ctypes.ResultConsensusState, error) {
+	var res *ctypes.ResultConsensusState
+	err := t.queryNode(ctx, "consensus state", func(node rpcclient.Client) (err error) {
+		res, err = node.ConsensusState()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryDumpConsensusState queries the consensus state of the node and its peers.
+func (t Transport) QueryDumpConsensusState(ctx context.Context) (*ctypes.ResultDumpConsensusState, error) {
+	var res *ctypes.ResultDumpConsensusState
+	err := t.queryNode(ctx, "dump consensus state", func(node rpcclient.Client) (err error) {
+		res, err = node.DumpConsensusState()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryUnconfirmedTxs queries at most @p limit txs in the mempool of the node.
+func (t Transport) QueryUnconfirmedTxs(ctx context.Context, limit int) (*ctypes.ResultUnconfirmedTxs, error) {
+	var res *ctypes.ResultUnconfirmedTxs
+	err := t.queryMempool(ctx, "unconfirmed txs", func(mempool rpcclient.MempoolClient) (err error) {
+		res, err = mempool.UnconfirmedTxs(limit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryNumUnconfirmedTxs queries the number of txs in the mempool of the node.
+func (t Transport) QueryNumUnconfirmedTxs(ctx context.Context) (*ctypes.ResultUnconfirmedTxs, error) {
+	var res *ctypes.ResultUnconfirmedTxs
+	err := t.queryMempool(ctx, "num unconfirmed txs", func(mempool rpcclient.MempoolClient) (err error) {
+		res, err = mempool.NumUnconfirmedTxs()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// queryMempool is queryNode for the mempool RPCs, which not every client serves.
+func (t Transport) queryMempool(ctx context.Context, name string, call func(rpcclient.MempoolClient) error) error {
+	return t.queryNode(ctx, name, func(node rpcclient.Client) error {
+		mempool, ok := node.(rpcclient.MempoolClient)
+		if !ok {
+			return errors.InvalidNodeURL("node client has no mempool access")
+		}
+		return call(mempool)
+	})
+}
+
+// queryNode runs an RPC @p call against the node, giving up with a timeout
+// named after @p name when @p ctx is done first. The call keeps running in
+// the background after a timeout, so its results must only be read when
+// queryNode returns no error.
+func (t Transport) queryNode(ctx context.Context, name string, call func(rpcclient.Client) error) error {
+	node, err := t.GetNode()
+	if err != nil {
+		return err
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- call(node)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return errors.Timeoutf("query %s timeout", name).AddCause(ctx.Err())
+	}
+}
+
 // BroadcastTx broadcasts a transcation to blockchain.
 func (t Transport) BroadcastTx(tx []byte, checkTxOnly bool) (interface{}, error) {
 	node, err := t.GetNode()
@@ -363,8 +557,13 @@ func (t Transport) SignAndBuild(msg sdk.Msg, privKeyHex string, seq uint64, memo
 
 // SignAndBuildMultiSig signs msg with multiple private key and return tx bytes
 func (t Transport) SignAndBuildMultiSig(msg sdk.Msg, privKeyHexs []string, seqs []uint64, memo string) ([]byte, errors.Error) {
-	msgs := []sdk.Msg{msg}
+	return t.SignAndBuildMsgs([]sdk.Msg{msg}, privKeyHexs, seqs, memo)
+}
 
+// SignAndBuildMsgs signs msgs with multiple private key and return tx bytes.
+// The signatures are for the signers of all msgs in order, and signatures of
+// the same account take consecutive sequence numbers.
+func (t Transport) SignAndBuildMsgs(msgs []sdk.Msg, privKeyHexs []string, seqs []uint64, memo string) ([]byte, errors.Error) {
 	pubKeys := []crypto.PubKey{}
 	sigs := [][]byte{}
 	for i, privKeyHex := range privKeyHexs {
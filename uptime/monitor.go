@@ -0,0 +1,376 @@
+// Package uptime follows the commits of the blockchain and tracks how many
+// blocks every validator signed or missed, raising alerts when a validator
+// misses too many blocks, is jailed or drops out of the oncall validators.
+package uptime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lino-network/lino-go/errors"
+	valmodel "github.com/lino-network/lino/x/validator/model"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// Source provides commits, validator sets and the Lino validator list.
+// *query.Query implements it; tests can replay a Recording instead.
+type Source interface {
+	GetBlockStatus(ctx context.Context) (*ctypes.ResultStatus, error)
+	GetCommit(ctx context.Context, height int64) (*ctypes.ResultCommit, error)
+	GetValidatorSet(ctx context.Context, height int64) (*ctypes.ResultValidators, error)
+	GetAllValidators(ctx context.Context) (*valmodel.ValidatorList, error)
+	GetValidatorUsernames(ctx context.Context) (map[string]string, error)
+}
+
+// AlertKind is the reason of an alert.
+type AlertKind string
+
+// Alert kinds.
+const (
+	// AlertMissedConsecutive is raised when a validator misses
+	// MaxConsecutiveMissed blocks in a row.
+	AlertMissedConsecutive AlertKind = "missed_consecutive"
+	// AlertMissedWindow is raised when a validator misses more than
+	// MaxMissed blocks of a window.
+	AlertMissedWindow AlertKind = "missed_window"
+	// AlertRecovered is raised when a validator signs again after an
+	// AlertMissedConsecutive.
+	AlertRecovered AlertKind = "recovered"
+	// AlertJailed is raised when a validator is in jail.
+	AlertJailed AlertKind = "jailed"
+	// AlertDropped is raised when a validator leaves the oncall validators
+	// without being jailed.
+	AlertDropped AlertKind = "dropped"
+	// AlertOncall is raised when a jailed or dropped validator is oncall again.
+	AlertOncall AlertKind = "oncall"
+)
+
+// Alert is a change in the signing performance or the status of a validator.
+type Alert struct {
+	Kind     AlertKind `json:"kind"`
+	Height   int64     `json:"height"`
+	Username string    `json:"username"`
+	Address  string    `json:"address"`
+	Message  string    `json:"message"`
+	// Stats is a snapshot of the validator's stats, nil if the monitor has
+	// not seen the validator in a validator set.
+	Stats *Stats `json:"stats"`
+}
+
+// Notifier receives alerts in height order.
+type Notifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+// NotifierFunc adapts a function to Notifier.
+type NotifierFunc func(ctx context.Context, alert *Alert) error
+
+// Notify implements Notifier.
+func (f NotifierFunc) Notify(ctx context.Context, alert *Alert) error {
+	return f(ctx, alert)
+}
+
+// Window is a sliding window of the latest Size blocks, raising
+// AlertMissedWindow when more than MaxMissed of them are missed.
+type Window struct {
+	Size      int64 `json:"size"`
+	MaxMissed int64 `json:"max_missed"`
+}
+
+// Options configures a Monitor.
+type Options struct {
+	// Validators are the watched usernames. Empty watches all validators.
+	Validators []string
+	// StartHeight is the first height checked. 0 starts at the tip.
+	StartHeight int64
+	// EndHeight is the last height checked. 0 follows the tip until the
+	// context is done.
+	EndHeight int64
+	// Windows are the sliding windows stats are kept for. Defaults to one
+	// window of 100 blocks alerting after 5 missed blocks.
+	Windows []Window
+	// MaxConsecutiveMissed is how many blocks in a row a validator misses
+	// before AlertMissedConsecutive. 0 disables the alert.
+	MaxConsecutiveMissed int64
+	// ValidatorCheckInterval is how many blocks pass between checks of the
+	// validator list for jailed and dropped validators. Defaults to 10.
+	ValidatorCheckInterval int64
+	// PollInterval is how long to wait for new blocks when at the tip.
+	// Defaults to one second.
+	PollInterval time.Duration
+	// OnError is called with every error returned by the notifier.
+	OnError func(err error)
+}
+
+// Monitor follows commits and keeps the signing stats of validators in memory.
+type Monitor struct {
+	source   Source
+	notifier Notifier
+	opts     Options
+	watched  map[string]bool
+
+	mtx       sync.Mutex
+	next      int64
+	checked   int64
+	usernames map[string]string
+	roles     map[string]string
+	trackers  map[string]*tracker
+}
+
+// NewMonitor returns an instance of Monitor reading from @p source and
+// raising alerts to @p notifier.
+func NewMonitor(source Source, notifier Notifier, opts Options) (*Monitor, error) {
+	if len(opts.Windows) == 0 {
+		opts.Windows = []Window{{Size: 100, MaxMissed: 5}}
+	}
+	for _, window := range opts.Windows {
+		if window.Size <= 0 || window.MaxMissed < 0 {
+			return nil, errors.InvalidArgf("invalid window size %v max missed %v", window.Size, window.MaxMissed)
+		}
+	}
+	if opts.MaxConsecutiveMissed < 0 {
+		return nil, errors.InvalidArgf("invalid max consecutive missed %v", opts.MaxConsecutiveMissed)
+	}
+	if opts.ValidatorCheckInterval <= 0 {
+		opts.ValidatorCheckInterval = 10
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = time.Second
+	}
+	watched := map[string]bool{}
+	for _, username := range opts.Validators {
+		watched[username] = true
+	}
+	return &Monitor{
+		source:    source,
+		notifier:  notifier,
+		opts:      opts,
+		watched:   watched,
+		next:      opts.StartHeight,
+		usernames: map[string]string{},
+		roles:     map[string]string{},
+		trackers:  map[string]*tracker{},
+	}, nil
+}
+
+// Run checks commits from StartHeight, or the height after the last checked
+// one, until EndHeight is checked or the context is done. Only canonical
+// commits are checked, so it stays one block behind the tip. A failed query
+// stops Run before the height is applied; calling Run again resumes with
+// the stats kept.
+func (m *Monitor) Run(ctx context.Context) error {
+	for m.opts.EndHeight == 0 || m.nextHeight() <= m.opts.EndHeight {
+		status, err := m.source.GetBlockStatus(ctx)
+		if err != nil {
+			return err
+		}
+		tip := status.SyncInfo.LatestBlockHeight - 1
+		if m.nextHeight() <= 0 {
+			m.setNextHeight(tip)
+		}
+
+		height := m.nextHeight()
+		if height <= 0 || height > tip {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(m.opts.PollInterval):
+			}
+			continue
+		}
+
+		for ; height <= tip && (m.opts.EndHeight == 0 || height <= m.opts.EndHeight); height++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := m.CheckHeight(ctx, height); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckHeight records who signed the commit at @p height and raises the
+// resulting alerts. Heights must be checked in order.
+func (m *Monitor) CheckHeight(ctx context.Context, height int64) error {
+	commit, err := m.source.GetCommit(ctx, height)
+	if err != nil {
+		return err
+	}
+	set, err := m.source.GetValidatorSet(ctx, height)
+	if err != nil {
+		return err
+	}
+	if commit == nil || commit.Commit == nil || set == nil {
+		return errors.EmptyResponsef("commit %v is not found", height)
+	}
+
+	m.mtx.Lock()
+	checkDue := m.checked == 0 || height-m.checked >= m.opts.ValidatorCheckInterval
+	m.mtx.Unlock()
+	var list *valmodel.ValidatorList
+	var usernames map[string]string
+	if checkDue {
+		if list, err = m.source.GetAllValidators(ctx); err != nil {
+			return err
+		}
+		if usernames, err = m.source.GetValidatorUsernames(ctx); err != nil {
+			return err
+		}
+	}
+
+	m.mtx.Lock()
+	alerts := []*Alert{}
+	if checkDue {
+		m.usernames = usernames
+		alerts = append(alerts, m.checkValidatorList(height, list)...)
+		m.checked = height
+	}
+	alerts = append(alerts, m.applyCommit(height, commit, set)...)
+	m.next = height + 1
+	m.mtx.Unlock()
+
+	for _, alert := range alerts {
+		if err := m.notifier.Notify(ctx, alert); err != nil && m.opts.OnError != nil {
+			m.opts.OnError(err)
+		}
+	}
+	return nil
+}
+
+// Stats returns the stats of all watched validators seen in a validator
+// set, ordered by username and address.
+func (m *Monitor) Stats() []*Stats {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	res := make([]*Stats, 0, len(m.trackers))
+	for _, t := range m.trackers {
+		res = append(res, t.snapshot(m.opts.Windows))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Username == res[j].Username {
+			return res[i].Address < res[j].Address
+		}
+		return res[i].Username < res[j].Username
+	})
+	return res
+}
+
+// nextHeight returns the height to check next, 0 if not started at the tip yet.
+func (m *Monitor) nextHeight() int64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.next
+}
+
+func (m *Monitor) setNextHeight(height int64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.next = height
+}
+
+func (m *Monitor) isWatched(username string) bool {
+	return len(m.watched) == 0 || m.watched[username]
+}
+
+func (m *Monitor) applyCommit(height int64, commit *ctypes.ResultCommit, set *ctypes.ResultValidators) []*Alert {
+	alerts := []*Alert{}
+	precommits := commit.Commit.Precommits
+	for i, val := range set.Validators {
+		addr := val.Address.String()
+		username := m.usernames[addr]
+		if !m.isWatched(username) {
+			continue
+		}
+		t, ok := m.trackers[addr]
+		if !ok {
+			t = newTracker(addr, m.opts.Windows)
+			m.trackers[addr] = t
+		}
+		t.stats.Username = username
+
+		signed := false
+		if i < len(precommits) && precommits[i] != nil {
+			sig := precommits[i]
+			signed = bytes.Equal(sig.ValidatorAddress, val.Address) && sig.BlockID.Equals(commit.Commit.BlockID)
+		}
+		alerts = append(alerts, t.record(height, signed, m.opts)...)
+	}
+	return alerts
+}
+
+// checkValidatorList compares the roles of watched validators with the last
+// check. A validator jailed at the first check is reported as well.
+func (m *Monitor) checkValidatorList(height int64, list *valmodel.ValidatorList) []*Alert {
+	roles := map[string]string{}
+	for role, usernames := range map[string][]string{
+		roleOncall:    toStrings(list.Oncall),
+		roleStandby:   toStrings(list.Standby),
+		roleCandidate: toStrings(list.Candidates),
+		roleJail:      toStrings(list.Jail),
+	} {
+		for _, username := range usernames {
+			roles[username] = role
+		}
+	}
+
+	addrs := map[string]string{}
+	for addr, username := range m.usernames {
+		addrs[username] = addr
+	}
+	names := map[string]bool{}
+	for username := range roles {
+		names[username] = true
+	}
+	for username := range m.roles {
+		names[username] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for username := range names {
+		if m.isWatched(username) {
+			sorted = append(sorted, username)
+		}
+	}
+	sort.Strings(sorted)
+
+	alerts := []*Alert{}
+	firstCheck := m.checked == 0
+	for _, username := range sorted {
+		prev, now := m.roles[username], roles[username]
+		var kind AlertKind
+		switch {
+		case now == prev:
+			continue
+		case now == roleJail:
+			kind = AlertJailed
+		case firstCheck:
+			continue
+		case prev == roleOncall:
+			kind = AlertDropped
+		case now == roleOncall:
+			kind = AlertOncall
+		default:
+			continue
+		}
+
+		alert := &Alert{
+			Kind:     kind,
+			Height:   height,
+			Username: username,
+			Address:  addrs[username],
+			Message:  fmt.Sprintf("validator %s is %s, was %s", username, roleName(now), roleName(prev)),
+		}
+		if t, ok := m.trackers[alert.Address]; ok {
+			alert.Stats = t.snapshot(m.opts.Windows)
+		}
+		alerts = append(alerts, alert)
+	}
+	m.roles = roles
+	return alerts
+}
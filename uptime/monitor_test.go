@@ -0,0 +1,201 @@
+package uptime
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	linotypes "github.com/lino-network/lino/types"
+	valmodel "github.com/lino-network/lino/x/validator/model"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	ttypes "github.com/tendermint/tendermint/types"
+)
+
+type testAlert struct {
+	kind     AlertKind
+	height   int64
+	username string
+}
+
+// newRecording records 10 blocks signed by alice, bob and carol. Bob misses
+// heights 3 to 6, carol votes for another block at height 2. Bob is jailed
+// at height 5 and back oncall at height 9, when carol drops to standby.
+func newRecording() *Recording {
+	names := []string{"alice", "bob", "carol"}
+	vals := make([]*ttypes.Validator, len(names))
+	recording := &Recording{}
+	for i, name := range names {
+		vals[i] = ttypes.NewValidator(ed25519.GenPrivKeyFromSecret([]byte(name)).PubKey(), 100)
+		recording.Usernames = append(recording.Usernames, &ValidatorName{
+			Address:  vals[i].Address.String(),
+			Username: name,
+		})
+	}
+
+	for height := int64(1); height <= 10; height++ {
+		blockID := ttypes.BlockID{Hash: []byte{byte(height)}}
+		commit := &ttypes.Commit{BlockID: blockID}
+		for i, val := range vals {
+			switch {
+			case names[i] == "bob" && height >= 3 && height <= 6:
+				commit.Precommits = append(commit.Precommits, nil)
+			case names[i] == "carol" && height == 2:
+				commit.Precommits = append(commit.Precommits, &ttypes.CommitSig{
+					Height:           height,
+					ValidatorAddress: val.Address,
+					ValidatorIndex:   i,
+				})
+			default:
+				commit.Precommits = append(commit.Precommits, &ttypes.CommitSig{
+					Height:           height,
+					BlockID:          blockID,
+					ValidatorAddress: val.Address,
+					ValidatorIndex:   i,
+				})
+			}
+		}
+		recording.Add(
+			ctypes.NewResultCommit(&ttypes.Header{Height: height}, commit, true),
+			&ctypes.ResultValidators{BlockHeight: height, Validators: vals})
+	}
+
+	recording.ValidatorLists = []*ValidatorListAt{
+		{Height: 1, List: &valmodel.ValidatorList{Oncall: []linotypes.AccountKey{"alice", "bob", "carol"}}},
+		{Height: 5, List: &valmodel.ValidatorList{
+			Oncall: []linotypes.AccountKey{"alice", "carol"},
+			Jail:   []linotypes.AccountKey{"bob"},
+		}},
+		{Height: 9, List: &valmodel.ValidatorList{
+			Oncall:  []linotypes.AccountKey{"alice", "bob"},
+			Standby: []linotypes.AccountKey{"carol"},
+		}},
+	}
+	return recording
+}
+
+func runMonitor(t *testing.T, source Source, opts Options) (*Monitor, []testAlert) {
+	alerts := []testAlert{}
+	notifier := NotifierFunc(func(ctx context.Context, alert *Alert) error {
+		alerts = append(alerts, testAlert{kind: alert.Kind, height: alert.Height, username: alert.Username})
+		return nil
+	})
+	m, err := NewMonitor(source, notifier, opts)
+	if err != nil {
+		t.Fatalf("failed to create monitor: %v", err)
+	}
+	if err := m.Run(context.Background()); err != nil {
+		t.Fatalf("failed to run monitor: %v", err)
+	}
+	return m, alerts
+}
+
+func TestMonitor(t *testing.T) {
+	testCases := map[string]struct {
+		validators   []string
+		expectAlerts []testAlert
+		expectStats  map[string][2]int64
+	}{
+		"all validators": {
+			expectAlerts: []testAlert{
+				{AlertJailed, 5, "bob"},
+				{AlertMissedConsecutive, 5, "bob"},
+				{AlertMissedWindow, 5, "bob"},
+				{AlertRecovered, 7, "bob"},
+				{AlertOncall, 9, "bob"},
+				{AlertDropped, 9, "carol"},
+			},
+			expectStats: map[string][2]int64{"alice": {10, 0}, "bob": {6, 4}, "carol": {9, 1}},
+		},
+		"watched validator": {
+			validators:   []string{"carol"},
+			expectAlerts: []testAlert{{AlertDropped, 9, "carol"}},
+			expectStats:  map[string][2]int64{"carol": {9, 1}},
+		},
+	}
+
+	for testName, tc := range testCases {
+		m, alerts := runMonitor(t, newRecording(), Options{
+			Validators:             tc.validators,
+			StartHeight:            1,
+			EndHeight:              10,
+			Windows:                []Window{{Size: 4, MaxMissed: 2}},
+			MaxConsecutiveMissed:   3,
+			ValidatorCheckInterval: 2,
+		})
+		if !reflect.DeepEqual(alerts, tc.expectAlerts) {
+			t.Errorf("%s: diff alerts, got %v, want %v", testName, alerts, tc.expectAlerts)
+		}
+
+		stats := map[string][2]int64{}
+		for _, s := range m.Stats() {
+			stats[s.Username] = [2]int64{s.Signed, s.Missed}
+		}
+		if !reflect.DeepEqual(stats, tc.expectStats) {
+			t.Errorf("%s: diff stats, got %v, want %v", testName, stats, tc.expectStats)
+		}
+	}
+}
+
+func TestWindowStats(t *testing.T) {
+	m, _ := runMonitor(t, newRecording(), Options{
+		StartHeight: 1,
+		EndHeight:   8,
+		Windows:     []Window{{Size: 4, MaxMissed: 4}, {Size: 20, MaxMissed: 20}},
+	})
+
+	var bob *Stats
+	for _, s := range m.Stats() {
+		if s.Username == "bob" {
+			bob = s
+		}
+	}
+	if bob == nil {
+		t.Fatalf("bob has no stats")
+	}
+	expectWindows := []WindowStats{
+		{Size: 4, Blocks: 4, Signed: 2, Missed: 2},
+		{Size: 20, Blocks: 8, Signed: 4, Missed: 4},
+	}
+	if !reflect.DeepEqual(bob.Windows, expectWindows) {
+		t.Errorf("diff windows, got %+v, want %+v", bob.Windows, expectWindows)
+	}
+	if bob.ConsecutiveMissed != 0 || bob.LastSignedHeight != 8 {
+		t.Errorf("diff consecutive missed %v or last signed height %v", bob.ConsecutiveMissed, bob.LastSignedHeight)
+	}
+}
+
+func TestRecordingSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recording")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "commits.json")
+	if err := newRecording().Save(path); err != nil {
+		t.Fatalf("failed to save recording: %v", err)
+	}
+	recording, err := LoadRecording(path)
+	if err != nil {
+		t.Fatalf("failed to load recording: %v", err)
+	}
+
+	_, alerts := runMonitor(t, recording, Options{
+		StartHeight:          1,
+		EndHeight:            10,
+		Windows:              []Window{{Size: 4, MaxMissed: 2}},
+		MaxConsecutiveMissed: 3,
+	})
+	expectAlerts := []testAlert{
+		{AlertMissedConsecutive, 5, "bob"},
+		{AlertMissedWindow, 5, "bob"},
+		{AlertRecovered, 7, "bob"},
+	}
+	if !reflect.DeepEqual(alerts, expectAlerts) {
+		t.Errorf("diff alerts, got %v, want %v", alerts, expectAlerts)
+	}
+}
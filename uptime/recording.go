@@ -0,0 +1,178 @@
+package uptime
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+
+	"github.com/lino-network/lino-go/errors"
+	linoapp "github.com/lino-network/lino/app"
+	valmodel "github.com/lino-network/lino/x/validator/model"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// Recording is a recorded range of commits with their validator sets. It
+// implements Source, so monitors can replay it offline.
+type Recording struct {
+	Commits       []*ctypes.ResultCommit     `json:"commits"`
+	ValidatorSets []*ctypes.ResultValidators `json:"validator_sets"`
+	// ValidatorLists are the validator lists seen from a height on.
+	ValidatorLists []*ValidatorListAt `json:"validator_lists"`
+	// Usernames map validator key addresses to usernames.
+	Usernames []*ValidatorName `json:"usernames"`
+
+	mtx    sync.Mutex
+	cursor int64
+}
+
+// ValidatorListAt is the validator list from Height on.
+type ValidatorListAt struct {
+	Height int64                   `json:"height"`
+	List   *valmodel.ValidatorList `json:"list"`
+}
+
+// ValidatorName is the username a validator key address belongs to.
+type ValidatorName struct {
+	Address  string `json:"address"`
+	Username string `json:"username"`
+}
+
+var _ Source = &Recording{}
+
+// RecordCommits records commits and validator sets from @p startHeight to
+// @p endHeight of @p source, with the current validator list and usernames
+// as seen from @p startHeight on.
+func RecordCommits(ctx context.Context, source Source, startHeight, endHeight int64) (*Recording, error) {
+	if startHeight <= 0 || endHeight < startHeight {
+		return nil, errors.InvalidArgf("invalid recording range [%v, %v]", startHeight, endHeight)
+	}
+
+	recording := &Recording{}
+	for height := startHeight; height <= endHeight; height++ {
+		commit, err := source.GetCommit(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		set, err := source.GetValidatorSet(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		recording.Add(commit, set)
+	}
+
+	list, err := source.GetAllValidators(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recording.ValidatorLists = []*ValidatorListAt{{Height: startHeight, List: list}}
+	usernames, err := source.GetValidatorUsernames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for addr, username := range usernames {
+		recording.Usernames = append(recording.Usernames, &ValidatorName{Address: addr, Username: username})
+	}
+	return recording, nil
+}
+
+// LoadRecording reads a recording saved by Save.
+func LoadRecording(path string) (*Recording, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.InvalidArgf("failed to read recording %s", path).AddCause(err)
+	}
+	recording := &Recording{}
+	if err := linoapp.MakeCodec().UnmarshalJSON(data, recording); err != nil {
+		return nil, errors.UnmarshaFailed("failed to decode recording").AddCause(err)
+	}
+	return recording, nil
+}
+
+// Save writes the recording to @p path.
+func (r *Recording) Save(path string) error {
+	data, err := linoapp.MakeCodec().MarshalJSONIndent(r, "", "  ")
+	if err != nil {
+		return errors.InvalidArg("failed to encode recording").AddCause(err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return errors.InvalidArgf("failed to write recording %s", path).AddCause(err)
+	}
+	return nil
+}
+
+// Add appends a commit and its validator set, which must be the next height.
+func (r *Recording) Add(commit *ctypes.ResultCommit, set *ctypes.ResultValidators) {
+	r.Commits = append(r.Commits, commit)
+	r.ValidatorSets = append(r.ValidatorSets, set)
+}
+
+// GetBlockStatus implements Source, reporting the block after the last
+// recorded commit as the tip, so all recorded commits are canonical.
+func (r *Recording) GetBlockStatus(ctx context.Context) (*ctypes.ResultStatus, error) {
+	status := &ctypes.ResultStatus{}
+	if len(r.Commits) > 0 {
+		status.SyncInfo.LatestBlockHeight = r.Commits[len(r.Commits)-1].Height + 1
+	}
+	return status, nil
+}
+
+// GetCommit implements Source. It also moves the replay to @p height, which
+// selects the validator list returned by GetAllValidators.
+func (r *Recording) GetCommit(ctx context.Context, height int64) (*ctypes.ResultCommit, error) {
+	i, err := r.index(height)
+	if err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	r.cursor = height
+	r.mtx.Unlock()
+	return r.Commits[i], nil
+}
+
+// GetValidatorSet implements Source.
+func (r *Recording) GetValidatorSet(ctx context.Context, height int64) (*ctypes.ResultValidators, error) {
+	i, err := r.index(height)
+	if err != nil {
+		return nil, err
+	}
+	return r.ValidatorSets[i], nil
+}
+
+// GetAllValidators implements Source, returning the latest validator list
+// recorded at or before the height of the last GetCommit.
+func (r *Recording) GetAllValidators(ctx context.Context) (*valmodel.ValidatorList, error) {
+	r.mtx.Lock()
+	cursor := r.cursor
+	r.mtx.Unlock()
+
+	var res *valmodel.ValidatorList
+	for _, list := range r.ValidatorLists {
+		if list.Height <= cursor || res == nil {
+			res = list.List
+		}
+	}
+	if res == nil {
+		return &valmodel.ValidatorList{}, nil
+	}
+	return res, nil
+}
+
+// GetValidatorUsernames implements Source.
+func (r *Recording) GetValidatorUsernames(ctx context.Context) (map[string]string, error) {
+	res := make(map[string]string, len(r.Usernames))
+	for _, name := range r.Usernames {
+		res[name.Address] = name.Username
+	}
+	return res, nil
+}
+
+func (r *Recording) index(height int64) (int, error) {
+	if len(r.Commits) == 0 {
+		return 0, errors.EmptyResponsef("commit %v is not found", height)
+	}
+	i := int(height - r.Commits[0].Height)
+	if i < 0 || i >= len(r.Commits) {
+		return 0, errors.EmptyResponsef("commit %v is not found", height)
+	}
+	return i, nil
+}
@@ -0,0 +1,165 @@
+package uptime
+
+import (
+	"fmt"
+
+	linotypes "github.com/lino-network/lino/types"
+)
+
+const (
+	roleOncall    = "oncall"
+	roleStandby   = "standby"
+	roleCandidate = "candidate"
+	roleJail      = "jail"
+)
+
+// Stats are the signing stats of a validator since the monitor started.
+type Stats struct {
+	Username          string        `json:"username"`
+	Address           string        `json:"address"`
+	Signed            int64         `json:"signed"`
+	Missed            int64         `json:"missed"`
+	ConsecutiveMissed int64         `json:"consecutive_missed"`
+	LastSignedHeight  int64         `json:"last_signed_height"`
+	Windows           []WindowStats `json:"windows"`
+}
+
+// WindowStats are the signing stats of a validator in a sliding window.
+// Blocks is less than Size until the validator has been seen Size times.
+type WindowStats struct {
+	Size   int64 `json:"size"`
+	Blocks int64 `json:"blocks"`
+	Signed int64 `json:"signed"`
+	Missed int64 `json:"missed"`
+}
+
+// tracker keeps the latest blocks of a validator in a ring as large as the
+// largest window, so the missed count of every window can be updated as
+// blocks enter and leave it.
+type tracker struct {
+	stats         Stats
+	ring          []bool
+	pos           int
+	seen          int64
+	windowMissed  []int64
+	windowAlerted []bool
+	alerted       bool
+}
+
+func newTracker(addr string, windows []Window) *tracker {
+	maxSize := int64(0)
+	for _, window := range windows {
+		if window.Size > maxSize {
+			maxSize = window.Size
+		}
+	}
+	return &tracker{
+		stats:         Stats{Address: addr},
+		ring:          make([]bool, maxSize),
+		windowMissed:  make([]int64, len(windows)),
+		windowAlerted: make([]bool, len(windows)),
+	}
+}
+
+// record adds a block to the stats and returns the alerts it raises.
+func (t *tracker) record(height int64, signed bool, opts Options) []*Alert {
+	missed := !signed
+	for i, window := range opts.Windows {
+		if t.seen >= window.Size && t.ring[t.index(window.Size)] {
+			t.windowMissed[i]--
+		}
+		if missed {
+			t.windowMissed[i]++
+		}
+	}
+	t.ring[t.pos] = missed
+	t.pos = (t.pos + 1) % len(t.ring)
+	t.seen++
+
+	alerts := []*Alert{}
+	if signed {
+		missedInRow := t.stats.ConsecutiveMissed
+		t.stats.Signed++
+		t.stats.LastSignedHeight = height
+		t.stats.ConsecutiveMissed = 0
+		if t.alerted {
+			t.alerted = false
+			alerts = append(alerts, t.alert(AlertRecovered, height, opts.Windows,
+				fmt.Sprintf("validator %s signed again after %v missed blocks", t.name(), missedInRow)))
+		}
+	} else {
+		t.stats.Missed++
+		t.stats.ConsecutiveMissed++
+		if opts.MaxConsecutiveMissed > 0 && t.stats.ConsecutiveMissed == opts.MaxConsecutiveMissed {
+			t.alerted = true
+			alerts = append(alerts, t.alert(AlertMissedConsecutive, height, opts.Windows,
+				fmt.Sprintf("validator %s missed %v blocks in a row", t.name(), t.stats.ConsecutiveMissed)))
+		}
+	}
+
+	for i, window := range opts.Windows {
+		over := t.windowMissed[i] > window.MaxMissed
+		if over && !t.windowAlerted[i] {
+			alerts = append(alerts, t.alert(AlertMissedWindow, height, opts.Windows,
+				fmt.Sprintf("validator %s missed %v of the last %v blocks", t.name(), t.windowMissed[i], window.Size)))
+		}
+		t.windowAlerted[i] = over
+	}
+	return alerts
+}
+
+// index returns the ring position of the block recorded @p age blocks ago.
+func (t *tracker) index(age int64) int {
+	return (t.pos - int(age) + len(t.ring)) % len(t.ring)
+}
+
+func (t *tracker) name() string {
+	if t.stats.Username == "" {
+		return t.stats.Address
+	}
+	return t.stats.Username
+}
+
+func (t *tracker) alert(kind AlertKind, height int64, windows []Window, msg string) *Alert {
+	return &Alert{
+		Kind:     kind,
+		Height:   height,
+		Username: t.stats.Username,
+		Address:  t.stats.Address,
+		Message:  msg,
+		Stats:    t.snapshot(windows),
+	}
+}
+
+func (t *tracker) snapshot(windows []Window) *Stats {
+	stats := t.stats
+	stats.Windows = make([]WindowStats, len(windows))
+	for i, window := range windows {
+		blocks := window.Size
+		if t.seen < blocks {
+			blocks = t.seen
+		}
+		stats.Windows[i] = WindowStats{
+			Size:   window.Size,
+			Blocks: blocks,
+			Signed: blocks - t.windowMissed[i],
+			Missed: t.windowMissed[i],
+		}
+	}
+	return &stats
+}
+
+func roleName(role string) string {
+	if role == "" {
+		return "not a validator"
+	}
+	return role
+}
+
+func toStrings(keys []linotypes.AccountKey) []string {
+	res := make([]string, len(keys))
+	for i, key := range keys {
+		res[i] = string(key)
+	}
+	return res
+}
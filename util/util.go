@@ -2,11 +2,18 @@ package util
 
 // nolint
 import (
-	"fmt"
 	"regexp"
-	"strings"
 
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/amount"
 	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	devtypes "github.com/lino-network/lino/x/developer/types"
+	posttypes "github.com/lino-network/lino/x/post/types"
+	pricetypes "github.com/lino-network/lino/x/price/types"
+	valtypes "github.com/lino-network/lino/x/validator/types"
+	votetypes "github.com/lino-network/lino/x/vote/types"
 )
 
 const (
@@ -28,17 +35,7 @@ func CheckUsername(username string) bool {
 }
 
 func CoinToLNO(c linotypes.Coin) string {
-	amountStr := "00000" + c.Amount.String()
-	length := len(amountStr)
-	converted := fmt.Sprintf("%s.%s", amountStr[:length-5], amountStr[length-5:])
-	converted = strings.Trim(converted, "0")
-	if converted[0] == '.' {
-		converted = "0" + converted
-	}
-	if converted[len(converted)-1] == '.' {
-		converted = converted[:len(converted)-1]
-	}
-	return converted
+	return amount.NewFromCoin(c).String()
 }
 
 func Min(a, b int64) int64 {
@@ -51,3 +48,112 @@ func Min(a, b int64) int64 {
 func GetSignerList(signer string) []linotypes.AccOrAddr {
 	return []linotypes.AccOrAddr{linotypes.NewAccOrAddrFromAcc(linotypes.AccountKey(signer))}
 }
+
+// FrozenMoneyReturn is a piece of frozen money returned at unix time At.
+type FrozenMoneyReturn struct {
+	At     int64
+	Amount linotypes.Coin
+}
+
+// FrozenMoneyReturns returns the pieces frozen money is returned in. The
+// chain returns it in Times pieces, one every Interval seconds after StartAt,
+// splitting the amount the same way as below.
+func FrozenMoneyReturns(frozenMoney accmodel.FrozenMoney) []FrozenMoneyReturn {
+	returns := make([]FrozenMoneyReturn, 0, frozenMoney.Times)
+	coin := frozenMoney.Amount
+	for i := int64(0); i < frozenMoney.Times; i++ {
+		piece := linotypes.DecToCoin(coin.ToDec().Quo(sdk.NewDec(frozenMoney.Times - i)))
+		coin = coin.Minus(piece)
+		returns = append(returns, FrozenMoneyReturn{At: frozenMoney.StartAt + (i+1)*frozenMoney.Interval, Amount: piece})
+	}
+	return returns
+}
+
+// FrozenMoneyRemaining returns the part of frozen money that is not returned
+// at unix time @p now.
+func FrozenMoneyRemaining(frozenMoney accmodel.FrozenMoney, now int64) linotypes.Coin {
+	remaining := linotypes.NewCoinFromInt64(0)
+	for _, piece := range FrozenMoneyReturns(frozenMoney) {
+		if piece.At > now {
+			remaining = remaining.Plus(piece.Amount)
+		}
+	}
+	return remaining
+}
+
+// MsgInvolvedAccounts returns the accounts a msg touches, in the order they
+// appear in the msg and without duplicates. Address parties of AccOrAddr
+// fields are returned as bech32 addresses.
+func MsgInvolvedAccounts(msg sdk.Msg) []string {
+	var accs []linotypes.AccountKey
+	switch msg := msg.(type) {
+	case acctypes.RegisterV2Msg:
+		accs = []linotypes.AccountKey{linotypes.AccountKey(msg.Referrer.String()), msg.NewUser}
+	case acctypes.TransferMsg:
+		accs = []linotypes.AccountKey{msg.Sender, msg.Receiver}
+	case acctypes.TransferV2Msg:
+		accs = []linotypes.AccountKey{
+			linotypes.AccountKey(msg.Sender.String()), linotypes.AccountKey(msg.Receiver.String())}
+	case acctypes.RecoverMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case acctypes.UpdateAccountMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case devtypes.DeveloperRegisterMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case devtypes.DeveloperUpdateMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case devtypes.DeveloperRevokeMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case devtypes.IDAIssueMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case devtypes.IDAMintMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case devtypes.IDAConvertFromLinoMsg:
+		accs = []linotypes.AccountKey{msg.Username, msg.App}
+	case devtypes.IDATransferMsg:
+		accs = []linotypes.AccountKey{msg.From, msg.To, msg.App, msg.Signer}
+	case devtypes.IDAAuthorizeMsg:
+		accs = []linotypes.AccountKey{msg.Username, msg.App}
+	case devtypes.UpdateAffiliatedMsg:
+		accs = []linotypes.AccountKey{msg.App, msg.Username}
+	case posttypes.CreatePostMsg:
+		accs = []linotypes.AccountKey{msg.Author, msg.CreatedBy}
+	case posttypes.UpdatePostMsg:
+		accs = []linotypes.AccountKey{msg.Author}
+	case posttypes.DeletePostMsg:
+		accs = []linotypes.AccountKey{msg.Author}
+	case posttypes.DonateMsg:
+		accs = []linotypes.AccountKey{msg.Username, msg.Author, msg.FromApp}
+	case posttypes.IDADonateMsg:
+		accs = []linotypes.AccountKey{msg.Username, msg.Author, msg.App, msg.Signer}
+	case pricetypes.FeedPriceMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case valtypes.ValidatorRegisterMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case valtypes.ValidatorRevokeMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case valtypes.ValidatorUpdateMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case valtypes.VoteValidatorMsg:
+		accs = append([]linotypes.AccountKey{msg.Username}, msg.VotedValidators...)
+	case votetypes.StakeInMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case votetypes.StakeOutMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case votetypes.ClaimInterestMsg:
+		accs = []linotypes.AccountKey{msg.Username}
+	case votetypes.StakeInForMsg:
+		accs = []linotypes.AccountKey{msg.Sender, msg.Receiver}
+	}
+
+	res := []string{}
+	seen := map[string]bool{}
+	for _, acc := range accs {
+		if acc == "" || seen[string(acc)] {
+			continue
+		}
+		seen[string(acc)] = true
+		res = append(res, string(acc))
+	}
+	return res
+}
@@ -1,9 +1,15 @@
 package util
 
 import (
+	"reflect"
 	"testing"
 
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	posttypes "github.com/lino-network/lino/x/post/types"
+	valtypes "github.com/lino-network/lino/x/validator/types"
 )
 
 const (
@@ -69,3 +75,83 @@ func TestCoinToLNO(t *testing.T) {
 		}
 	}
 }
+
+func TestFrozenMoneyRemaining(t *testing.T) {
+	frozenMoney := accmodel.FrozenMoney{
+		Amount:   linotypes.NewCoinFromInt64(100),
+		StartAt:  1000,
+		Interval: 10,
+		Times:    3,
+	}
+	testCases := map[string]struct {
+		now          int64
+		expectRemain linotypes.Coin
+	}{
+		"before first return": {
+			now:          1005,
+			expectRemain: linotypes.NewCoinFromInt64(100),
+		},
+		"after first return": {
+			now:          1010,
+			expectRemain: linotypes.NewCoinFromInt64(67),
+		},
+		"after second return": {
+			now:          1025,
+			expectRemain: linotypes.NewCoinFromInt64(33),
+		},
+		"all returned": {
+			now:          1030,
+			expectRemain: linotypes.NewCoinFromInt64(0),
+		},
+	}
+
+	for testName, tc := range testCases {
+		got := FrozenMoneyRemaining(frozenMoney, tc.now)
+		if !got.IsEqual(tc.expectRemain) {
+			t.Errorf("%s: diff remaining, got %v, want %v", testName, got, tc.expectRemain)
+		}
+	}
+}
+
+func TestMsgInvolvedAccounts(t *testing.T) {
+	testCases := map[string]struct {
+		msg            sdk.Msg
+		expectAccounts []string
+	}{
+		"transfer": {
+			msg:            acctypes.TransferMsg{Sender: "alice", Receiver: "bob", Amount: "1"},
+			expectAccounts: []string{"alice", "bob"},
+		},
+		"transfer v2 between accounts": {
+			msg: acctypes.TransferV2Msg{
+				Sender:   linotypes.NewAccOrAddrFromAcc("alice"),
+				Receiver: linotypes.NewAccOrAddrFromAcc("bob"),
+			},
+			expectAccounts: []string{"alice", "bob"},
+		},
+		"donate without app": {
+			msg:            posttypes.DonateMsg{Username: "alice", Author: "bob", PostID: "p1"},
+			expectAccounts: []string{"alice", "bob"},
+		},
+		"ida donate signed by app": {
+			msg:            posttypes.IDADonateMsg{Username: "alice", Author: "bob", App: "app", Signer: "app"},
+			expectAccounts: []string{"alice", "bob", "app"},
+		},
+		"vote validators": {
+			msg: valtypes.VoteValidatorMsg{
+				Username: "alice", VotedValidators: []linotypes.AccountKey{"val1", "val2"}},
+			expectAccounts: []string{"alice", "val1", "val2"},
+		},
+		"unknown msg": {
+			msg:            sdk.NewTestMsg(),
+			expectAccounts: []string{},
+		},
+	}
+
+	for testName, tc := range testCases {
+		accounts := MsgInvolvedAccounts(tc.msg)
+		if !reflect.DeepEqual(accounts, tc.expectAccounts) {
+			t.Errorf("%s: diff accounts, got %v, want %v", testName, accounts, tc.expectAccounts)
+		}
+	}
+}
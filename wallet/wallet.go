@@ -0,0 +1,406 @@
+// Package wallet holds the keys of many accounts, e.g. an app, its
+// affiliated accounts and hot wallets, caches their state and signs every
+// msg with the transaction keys of its signers.
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/amount"
+	"github.com/lino-network/lino-go/api"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	"github.com/lino-network/lino-go/transport"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// Chain queries accounts and broadcasts txs. *api.API implements it.
+type Chain interface {
+	GetAccountInfo(ctx context.Context, username string) (*accmodel.AccountInfo, error)
+	GetAccountBank(ctx context.Context, username string) (*accmodel.AccountBank, error)
+	GetAccountBankByAddress(ctx context.Context, address string) (*accmodel.AccountBank, error)
+	GuaranteeBroadcast(ctx context.Context, signers []linotypes.AccOrAddr, f api.MsgBuilderFunc) (*model.BroadcastResponse, []string, errors.Error)
+	MakeSignedMsgs(msgs []sdk.Msg, privKeyHexs []string, seqs []uint64, memo string) ([]byte, errors.Error)
+}
+
+// State is the cached state of an account of the wallet.
+type State struct {
+	// Username is empty for a key whose account is not registered yet.
+	Username string `json:"username,omitempty"`
+	// Address is the hex address of the transaction key.
+	Address string                `json:"address"`
+	Info    *accmodel.AccountInfo `json:"info,omitempty"`
+	// Bank is nil if the account has none yet.
+	Bank        *accmodel.AccountBank `json:"bank,omitempty"`
+	RefreshedAt time.Time             `json:"refreshed_at"`
+}
+
+// Balance returns the saving of the account, zero if it has no bank.
+func (s *State) Balance() amount.Amount {
+	if s.Bank == nil {
+		return amount.Zero(amount.LINO)
+	}
+	return amount.NewFromCoin(s.Bank.Saving)
+}
+
+// Sequence returns the next sequence number of the account.
+func (s *State) Sequence() uint64 {
+	if s.Bank == nil {
+		return 0
+	}
+	return s.Bank.Sequence
+}
+
+type account struct {
+	state      State
+	txKey      string
+	signingKey string
+	signingPub crypto.PubKey
+	txPub      crypto.PubKey
+}
+
+// Wallet holds accounts and their keys. It is safe for concurrent use.
+type Wallet struct {
+	chain Chain
+	now   func() time.Time
+
+	mtx sync.Mutex
+	// accounts are by username, or by address until registered.
+	accounts map[string]*account
+	byAddr   map[string]*account
+}
+
+// NewWallet returns an empty wallet on @p chain.
+func NewWallet(chain Chain) *Wallet {
+	return &Wallet{
+		chain:    chain,
+		now:      time.Now,
+		accounts: map[string]*account{},
+		byAddr:   map[string]*account{},
+	}
+}
+
+// Add adds account @p username with its keys, replacing the keys it had,
+// and checks them against the chain. The signing key is optional. An empty
+// username adds a transaction key whose account is not registered yet, e.g.
+// the new account of a RegisterV2Msg; it is known by its address until
+// a refresh finds its account.
+func (w *Wallet) Add(ctx context.Context, username, txPrivKeyHex, signingPrivKeyHex string) (*State, error) {
+	acc := &account{txKey: txPrivKeyHex, signingKey: signingPrivKeyHex}
+	txPriv, err := transport.GetPrivKeyFromHex(txPrivKeyHex)
+	if err != nil {
+		return nil, errors.FailedToGetPrivKeyFromHex("invalid transaction key").AddCause(err)
+	}
+	acc.txPub = txPriv.PubKey()
+	if signingPrivKeyHex != "" {
+		signingPriv, err := transport.GetPrivKeyFromHex(signingPrivKeyHex)
+		if err != nil {
+			return nil, errors.FailedToGetPrivKeyFromHex("invalid signing key").AddCause(err)
+		}
+		acc.signingPub = signingPriv.PubKey()
+	}
+	acc.state = State{Username: username, Address: hex.EncodeToString(acc.txPub.Address())}
+	if err := w.refresh(ctx, acc); err != nil {
+		return nil, err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if old := w.byAddr[acc.state.Address]; old != nil {
+		w.remove(old)
+	}
+	if old := w.accounts[acc.name()]; old != nil {
+		w.remove(old)
+	}
+	w.accounts[acc.name()] = acc
+	w.byAddr[acc.state.Address] = acc
+	return acc.copyState(), nil
+}
+
+// Remove removes the account with username or address @p name.
+func (w *Wallet) Remove(name string) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if acc := w.lookup(name); acc != nil {
+		w.remove(acc)
+	}
+}
+
+// Accounts returns the cached states of all accounts ordered by name.
+func (w *Wallet) Accounts() []*State {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	res := []*State{}
+	for _, acc := range w.accounts {
+		res = append(res, acc.copyState())
+	}
+	sort.Slice(res, func(i, j int) bool { return name(res[i]) < name(res[j]) })
+	return res
+}
+
+// State returns the cached state of the account with username or address
+// @p name.
+func (w *Wallet) State(name string) (*State, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	acc := w.lookup(name)
+	if acc == nil {
+		return nil, errors.EmptyResponsef("account %s is not in the wallet", name)
+	}
+	return acc.copyState(), nil
+}
+
+// Refresh reloads the state of the account with username or address
+// @p name from the chain.
+func (w *Wallet) Refresh(ctx context.Context, name string) (*State, error) {
+	w.mtx.Lock()
+	acc := w.lookup(name)
+	w.mtx.Unlock()
+	if acc == nil {
+		return nil, errors.EmptyResponsef("account %s is not in the wallet", name)
+	}
+
+	fresh := &account{state: *acc.copyState(), txPub: acc.txPub, signingPub: acc.signingPub}
+	if err := w.refresh(ctx, fresh); err != nil {
+		return nil, err
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	// the account may have been removed or replaced meanwhile.
+	if w.byAddr[acc.state.Address] != acc {
+		return fresh.copyState(), nil
+	}
+	if fresh.name() != acc.name() {
+		delete(w.accounts, acc.name())
+		w.accounts[fresh.name()] = acc
+	}
+	acc.state = fresh.state
+	return acc.copyState(), nil
+}
+
+// RefreshAll reloads the states of all accounts. It returns the first error
+// and still refreshes the other accounts.
+func (w *Wallet) RefreshAll(ctx context.Context) error {
+	var firstErr error
+	for _, state := range w.Accounts() {
+		if _, err := w.Refresh(ctx, name(state)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// refresh loads the state of @p acc and checks its keys.
+func (w *Wallet) refresh(ctx context.Context, acc *account) error {
+	state := &acc.state
+	if state.Username == "" {
+		bank, err := w.chain.GetAccountBankByAddress(ctx, state.Address)
+		if err != nil && !errors.IsEmptyResponse(err) {
+			return err
+		}
+		state.Bank = bank
+		if bank == nil || bank.Username == "" {
+			state.RefreshedAt = w.now()
+			*state = *acc.copyState()
+			return nil
+		}
+		// registered since.
+		state.Username = string(bank.Username)
+	}
+
+	info, err := w.chain.GetAccountInfo(ctx, state.Username)
+	if err != nil {
+		return err
+	}
+	if info.TransactionKey == nil || !info.TransactionKey.Equals(acc.txPub) {
+		return errors.InvalidArgf("transaction key does not match account %s", state.Username)
+	}
+	if acc.signingPub != nil && (info.SigningKey == nil || !info.SigningKey.Equals(acc.signingPub)) {
+		return errors.InvalidArgf("signing key does not match account %s", state.Username)
+	}
+	bank, err := w.chain.GetAccountBank(ctx, state.Username)
+	if err != nil {
+		return err
+	}
+	state.Info, state.Bank, state.RefreshedAt = info, bank, w.now()
+	// keep copies, the cache is updated by sends.
+	*state = *acc.copyState()
+	return nil
+}
+
+// SignPayload signs @p payload with the signing key of account @p username,
+// returning the hex signature that query.VerifyUserSignatureUsingSigningKey
+// verifies.
+func (w *Wallet) SignPayload(username, payload string) (string, error) {
+	w.mtx.Lock()
+	acc := w.lookup(username)
+	w.mtx.Unlock()
+	if acc == nil || acc.signingKey == "" {
+		return "", errors.InvalidArgf("no signing key of %s", username)
+	}
+	privKey, err := transport.GetPrivKeyFromHex(acc.signingKey)
+	if err != nil {
+		return "", errors.FailedToGetPrivKeyFromHex("invalid signing key").AddCause(err)
+	}
+	sig, err := privKey.Sign([]byte(payload))
+	if err != nil {
+		return "", errors.InvalidSignature("failed to sign payload").AddCause(err)
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// Builder returns the signers of @p msgs and a builder signing them with
+// the keys of the wallet, for api.GuaranteeBroadcast. Signers repeated in
+// the msgs, e.g. the sender of two transfers, appear once and sign with
+// consecutive sequence numbers.
+func (w *Wallet) Builder(memo string, msgs ...sdk.Msg) ([]linotypes.AccOrAddr, api.MsgBuilderFunc, errors.Error) {
+	if len(msgs) == 0 {
+		return nil, nil, errors.InvalidArg("no msgs")
+	}
+	sigs := []linotypes.AccOrAddr{}
+	for _, msg := range msgs {
+		if err := msg.ValidateBasic(); err != nil {
+			return nil, nil, errors.InvalidArgf("invalid %s msg: %s", msg.Type(), err.Error())
+		}
+		sigs = append(sigs, signersOf(msg)...)
+	}
+	if len(sigs) > linotypes.TxSigLimit {
+		return nil, nil, errors.InvalidArgf("a tx has at most %d signatures, got %d", linotypes.TxSigLimit, len(sigs))
+	}
+
+	signers := []linotypes.AccOrAddr{}
+	keys := make([]string, len(sigs))
+	// signer of each signature and how many earlier ones the signer has.
+	signerOf := make([]int, len(sigs))
+	offset := make([]uint64, len(sigs))
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	for i, sig := range sigs {
+		acc := w.lookupSigner(sig)
+		if acc == nil {
+			return nil, nil, errors.InvalidArgf("no key of signer %s", sig.String())
+		}
+		keys[i] = acc.txKey
+		signerOf[i] = -1
+		for j := 0; j < i; j++ {
+			if sigs[j].String() == sig.String() {
+				signerOf[i], offset[i] = signerOf[j], offset[i]+1
+			}
+		}
+		if signerOf[i] < 0 {
+			signerOf[i] = len(signers)
+			signers = append(signers, sig)
+		}
+	}
+
+	f := func(seqs []uint64) ([]byte, errors.Error) {
+		if len(seqs) < len(signers) {
+			return nil, errors.SequenceNumberNotEnoughf("sequence number is not enough. got %d, expect %d", len(seqs), len(signers))
+		}
+		sigSeqs := make([]uint64, len(sigs))
+		for i := range sigs {
+			sigSeqs[i] = seqs[signerOf[i]] + offset[i]
+		}
+		return w.chain.MakeSignedMsgs(msgs, keys, sigSeqs, memo)
+	}
+	return signers, f, nil
+}
+
+// Send signs @p msgs with the keys of their signers and broadcasts them in
+// a tx with GuaranteeBroadcast. The cached sequence numbers of the signers
+// are advanced on success; balances are as of the last refresh.
+func (w *Wallet) Send(ctx context.Context, memo string, msgs ...sdk.Msg) (*model.BroadcastResponse, errors.Error) {
+	signers, build, err := w.Builder(memo, msgs...)
+	if err != nil {
+		return nil, err
+	}
+	var used []uint64
+	resp, _, err := w.chain.GuaranteeBroadcast(ctx, signers, func(seqs []uint64) ([]byte, errors.Error) {
+		used = seqs
+		return build(seqs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	next := map[string]uint64{}
+	for _, msg := range msgs {
+		for _, sig := range signersOf(msg) {
+			next[sig.String()]++
+		}
+	}
+	for i, signer := range signers {
+		acc := w.lookupSigner(signer)
+		if acc != nil && acc.state.Bank != nil && i < len(used) {
+			acc.state.Bank.Sequence = used[i] + next[signer.String()]
+		}
+	}
+	return resp, nil
+}
+
+// signersOf returns the signers of @p msg in the order of its signatures.
+func signersOf(msg sdk.Msg) []linotypes.AccOrAddr {
+	if addrMsg, ok := msg.(linotypes.AddrMsg); ok {
+		return addrMsg.GetAccOrAddrSigners()
+	}
+	// lino msgs sign with usernames as addresses.
+	signers := []linotypes.AccOrAddr{}
+	for _, addr := range msg.GetSigners() {
+		signers = append(signers, linotypes.NewAccOrAddrFromAcc(linotypes.AccountKey(addr)))
+	}
+	return signers
+}
+
+func (w *Wallet) lookup(name string) *account {
+	if acc := w.accounts[name]; acc != nil {
+		return acc
+	}
+	return w.byAddr[strings.ToLower(name)]
+}
+
+func (w *Wallet) lookupSigner(signer linotypes.AccOrAddr) *account {
+	if signer.IsAddr {
+		return w.byAddr[hex.EncodeToString(signer.Addr)]
+	}
+	return w.accounts[string(signer.AccountKey)]
+}
+
+func (w *Wallet) remove(acc *account) {
+	delete(w.accounts, acc.name())
+	delete(w.byAddr, acc.state.Address)
+}
+
+// name returns the username of the account, or its address if it has none.
+func (acc *account) name() string {
+	return name(&acc.state)
+}
+
+func name(state *State) string {
+	if state.Username != "" {
+		return state.Username
+	}
+	return state.Address
+}
+
+func (acc *account) copyState() *State {
+	state := acc.state
+	if state.Info != nil {
+		info := *state.Info
+		state.Info = &info
+	}
+	if state.Bank != nil {
+		bank := *state.Bank
+		state.Bank = &bank
+	}
+	return &state
+}
@@ -0,0 +1,372 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/lino-network/lino-go/api"
+	"github.com/lino-network/lino-go/errors"
+	"github.com/lino-network/lino-go/model"
+	linotypes "github.com/lino-network/lino/types"
+	accmodel "github.com/lino-network/lino/x/account/model"
+	acctypes "github.com/lino-network/lino/x/account/types"
+	devtypes "github.com/lino-network/lino/x/developer/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+)
+
+type key struct {
+	priv crypto.PrivKey
+	hex  string
+}
+
+func newKey() key {
+	priv := secp256k1.GenPrivKey()
+	return key{priv: priv, hex: hex.EncodeToString(priv.Bytes())}
+}
+
+type signed struct {
+	keys []string
+	seqs []uint64
+}
+
+type fakeChain struct {
+	infos map[string]*accmodel.AccountInfo
+	banks map[string]*accmodel.AccountBank
+	// byAddr are banks by hex address.
+	byAddr map[string]*accmodel.AccountBank
+	built  []signed
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		infos:  map[string]*accmodel.AccountInfo{},
+		banks:  map[string]*accmodel.AccountBank{},
+		byAddr: map[string]*accmodel.AccountBank{},
+	}
+}
+
+func (c *fakeChain) register(username string, txKey, signingKey key, seq uint64) {
+	addr := txKey.priv.PubKey().Address()
+	c.infos[username] = &accmodel.AccountInfo{
+		Username:       linotypes.AccountKey(username),
+		TransactionKey: txKey.priv.PubKey(),
+		SigningKey:     signingKey.priv.PubKey(),
+		Address:        sdk.AccAddress(addr),
+	}
+	bank := &accmodel.AccountBank{
+		Saving:   linotypes.NewCoinFromInt64(10 * linotypes.Decimals),
+		Sequence: seq,
+		Username: linotypes.AccountKey(username),
+	}
+	c.banks[username] = bank
+	c.byAddr[hex.EncodeToString(addr)] = bank
+}
+
+func (c *fakeChain) GetAccountInfo(ctx context.Context, username string) (*accmodel.AccountInfo, error) {
+	info, ok := c.infos[username]
+	if !ok {
+		return nil, errors.EmptyResponse("account info is not found")
+	}
+	return info, nil
+}
+
+func (c *fakeChain) GetAccountBank(ctx context.Context, username string) (*accmodel.AccountBank, error) {
+	bank, ok := c.banks[username]
+	if !ok {
+		return nil, errors.EmptyResponse("account bank is not found")
+	}
+	return bank, nil
+}
+
+func (c *fakeChain) GetAccountBankByAddress(ctx context.Context, address string) (*accmodel.AccountBank, error) {
+	bank, ok := c.byAddr[address]
+	if !ok {
+		return nil, errors.EmptyResponse("account bank is not found")
+	}
+	return bank, nil
+}
+
+func (c *fakeChain) MakeSignedMsgs(msgs []sdk.Msg, privKeyHexs []string, seqs []uint64, memo string) ([]byte, errors.Error) {
+	c.built = append(c.built, signed{keys: privKeyHexs, seqs: seqs})
+	b, _ := json.Marshal(seqs)
+	return b, nil
+}
+
+func (c *fakeChain) GuaranteeBroadcast(ctx context.Context, signers []linotypes.AccOrAddr,
+	f api.MsgBuilderFunc) (*model.BroadcastResponse, []string, errors.Error) {
+	seqs := []uint64{}
+	for _, signer := range signers {
+		var bank *accmodel.AccountBank
+		if signer.IsAddr {
+			bank = c.byAddr[hex.EncodeToString(signer.Addr)]
+		} else {
+			bank = c.banks[string(signer.AccountKey)]
+		}
+		seq := uint64(0)
+		if bank != nil {
+			seq = bank.Sequence
+		}
+		seqs = append(seqs, seq)
+	}
+	if _, err := f(seqs); err != nil {
+		return nil, nil, err
+	}
+	return &model.BroadcastResponse{CommitHash: "hash", Height: 1}, []string{"hash"}, nil
+}
+
+func transfer(sender, receiver string) sdk.Msg {
+	return acctypes.TransferMsg{
+		Sender:   linotypes.AccountKey(sender),
+		Receiver: linotypes.AccountKey(receiver),
+		Amount:   "1",
+	}
+}
+
+func TestAdd(t *testing.T) {
+	ctx := context.Background()
+	aliceTx, aliceSigning, other := newKey(), newKey(), newKey()
+	chain := newFakeChain()
+	chain.register("alice", aliceTx, aliceSigning, 5)
+
+	testCases := map[string]struct {
+		username   string
+		txKey      string
+		signingKey string
+		expectErr  bool
+		expectName string
+	}{
+		"tx and signing keys": {
+			username:   "alice",
+			txKey:      aliceTx.hex,
+			signingKey: aliceSigning.hex,
+			expectName: "alice",
+		},
+		"tx key only": {
+			username:   "alice",
+			txKey:      aliceTx.hex,
+			expectName: "alice",
+		},
+		"wrong tx key": {
+			username:  "alice",
+			txKey:     other.hex,
+			expectErr: true,
+		},
+		"wrong signing key": {
+			username:   "alice",
+			txKey:      aliceTx.hex,
+			signingKey: other.hex,
+			expectErr:  true,
+		},
+		"unknown account": {
+			username:  "bob",
+			txKey:     other.hex,
+			expectErr: true,
+		},
+		"invalid key": {
+			username:  "alice",
+			txKey:     "xyz",
+			expectErr: true,
+		},
+		"unregistered key": {
+			txKey:      other.hex,
+			expectName: hex.EncodeToString(other.priv.PubKey().Address()),
+		},
+	}
+
+	for testName, tc := range testCases {
+		w := NewWallet(chain)
+		state, err := w.Add(ctx, tc.username, tc.txKey, tc.signingKey)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if name(state) != tc.expectName {
+			t.Errorf("%s: diff name, got %s, expect %s", testName, name(state), tc.expectName)
+		}
+		if _, err := w.State(tc.expectName); err != nil {
+			t.Errorf("%s: failed to get state, got err %v", testName, err)
+		}
+	}
+}
+
+func TestRefreshRegistered(t *testing.T) {
+	ctx := context.Background()
+	newTx := newKey()
+	chain := newFakeChain()
+	w := NewWallet(chain)
+	w.now = func() time.Time { return time.Unix(100, 0) }
+	state, err := w.Add(ctx, "", newTx.hex, "")
+	if err != nil {
+		t.Fatalf("failed to add key, got err %v", err)
+	}
+	if state.Username != "" || state.Sequence() != 0 || !state.Balance().IsZero() {
+		t.Errorf("diff unregistered state, got %+v", state)
+	}
+
+	chain.register("carol", newTx, newKey(), 1)
+	if _, err := w.Refresh(ctx, state.Address); err != nil {
+		t.Fatalf("failed to refresh, got err %v", err)
+	}
+	got, err := w.State("carol")
+	if err != nil {
+		t.Fatalf("failed to get registered account, got err %v", err)
+	}
+	if got.Address != state.Address || got.Sequence() != 1 || got.Balance().String() != "10" || got.Info == nil {
+		t.Errorf("diff registered state, got %+v", got)
+	}
+	if accounts := w.Accounts(); len(accounts) != 1 {
+		t.Errorf("diff accounts, got %d, expect 1", len(accounts))
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	alice, app, affiliated, newUser := newKey(), newKey(), newKey(), newKey()
+	chain := newFakeChain()
+	chain.register("alice", alice, newKey(), 5)
+	chain.register("myapp", app, newKey(), 8)
+	chain.register("myappbot", affiliated, newKey(), 2)
+	w := NewWallet(chain)
+	for username, k := range map[string]key{"alice": alice, "myapp": app, "myappbot": affiliated, "": newUser} {
+		if _, err := w.Add(context.Background(), username, k.hex, ""); err != nil {
+			t.Fatalf("failed to add %s, got err %v", username, err)
+		}
+	}
+
+	testCases := map[string]struct {
+		msgs          []sdk.Msg
+		expectSigners int
+		expectKeys    []string
+		expectSeqs    []uint64
+		expectErr     bool
+	}{
+		"transfer": {
+			msgs:          []sdk.Msg{transfer("alice", "bob")},
+			expectSigners: 1,
+			expectKeys:    []string{alice.hex},
+			expectSeqs:    []uint64{5},
+		},
+		"two transfers of a sender": {
+			msgs:          []sdk.Msg{transfer("alice", "bob"), transfer("alice", "carol")},
+			expectSigners: 1,
+			expectKeys:    []string{alice.hex, alice.hex},
+			expectSeqs:    []uint64{5, 6},
+		},
+		"transfers of two senders": {
+			msgs:          []sdk.Msg{transfer("alice", "bob"), transfer("myapp", "carol")},
+			expectSigners: 2,
+			expectKeys:    []string{alice.hex, app.hex},
+			expectSeqs:    []uint64{5, 8},
+		},
+		"ida transfer signed by affiliated account": {
+			msgs: []sdk.Msg{devtypes.IDATransferMsg{
+				App: "myapp", Amount: "1", From: "myapp", To: "bob", Signer: "myappbot"}},
+			expectSigners: 1,
+			expectKeys:    []string{affiliated.hex},
+			expectSeqs:    []uint64{2},
+		},
+		"register with new key": {
+			msgs: []sdk.Msg{acctypes.RegisterV2Msg{
+				Referrer:             linotypes.NewAccOrAddrFromAcc("alice"),
+				RegisterFee:          "1",
+				NewUser:              "dave",
+				NewTransactionPubKey: newUser.priv.PubKey(),
+				NewSigningPubKey:     newKey().priv.PubKey(),
+			}},
+			expectSigners: 2,
+			expectKeys:    []string{alice.hex, newUser.hex},
+			expectSeqs:    []uint64{5, 0},
+		},
+		"unknown signer": {
+			msgs:      []sdk.Msg{transfer("erin", "bob")},
+			expectErr: true,
+		},
+		"too many signatures": {
+			msgs:      []sdk.Msg{transfer("alice", "bob"), transfer("alice", "carol"), transfer("alice", "dave")},
+			expectErr: true,
+		},
+		"invalid msg": {
+			msgs:      []sdk.Msg{transfer("alice", "")},
+			expectErr: true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		chain.built = nil
+		signers, build, err := w.Builder("", tc.msgs...)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: diff err, got %v, expect err %v", testName, err, tc.expectErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(signers) != tc.expectSigners {
+			t.Errorf("%s: diff signers, got %v, expect %d", testName, signers, tc.expectSigners)
+		}
+		if _, _, err := chain.GuaranteeBroadcast(context.Background(), signers, build); err != nil {
+			t.Errorf("%s: failed to build, got err %v", testName, err)
+			continue
+		}
+		got := chain.built[0]
+		if len(got.keys) != len(tc.expectKeys) {
+			t.Errorf("%s: diff keys, got %d, expect %d", testName, len(got.keys), len(tc.expectKeys))
+			continue
+		}
+		for i := range got.keys {
+			if got.keys[i] != tc.expectKeys[i] || got.seqs[i] != tc.expectSeqs[i] {
+				t.Errorf("%s: diff signature %d, got seq %d, expect seq %d", testName, i, got.seqs[i], tc.expectSeqs[i])
+			}
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	ctx := context.Background()
+	alice := newKey()
+	chain := newFakeChain()
+	chain.register("alice", alice, newKey(), 5)
+	w := NewWallet(chain)
+	if _, err := w.Add(ctx, "alice", alice.hex, ""); err != nil {
+		t.Fatalf("failed to add alice, got err %v", err)
+	}
+	if _, err := w.Send(ctx, "", transfer("alice", "bob"), transfer("alice", "carol")); err != nil {
+		t.Fatalf("failed to send, got err %v", err)
+	}
+	state, _ := w.State("alice")
+	if state.Sequence() != 7 {
+		t.Errorf("diff cached sequence, got %d, expect 7", state.Sequence())
+	}
+	// the chain state is not changed by the cache.
+	if chain.banks["alice"].Sequence != 5 {
+		t.Errorf("cache changed chain bank, got sequence %d", chain.banks["alice"].Sequence)
+	}
+}
+
+func TestSignPayload(t *testing.T) {
+	alice, aliceSigning := newKey(), newKey()
+	chain := newFakeChain()
+	chain.register("alice", alice, aliceSigning, 0)
+	w := NewWallet(chain)
+	if _, err := w.SignPayload("alice", "hello"); err == nil {
+		t.Errorf("signed without account")
+	}
+	if _, err := w.Add(context.Background(), "alice", alice.hex, aliceSigning.hex); err != nil {
+		t.Fatalf("failed to add alice, got err %v", err)
+	}
+	sigHex, err := w.SignPayload("alice", "hello")
+	if err != nil {
+		t.Fatalf("failed to sign, got err %v", err)
+	}
+	sig, _ := hex.DecodeString(sigHex)
+	if !aliceSigning.priv.PubKey().VerifyBytes([]byte("hello"), sig) {
+		t.Errorf("signature does not verify with the signing key")
+	}
+}